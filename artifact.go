@@ -0,0 +1,75 @@
+package tinycert
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidArtifact = errors.New("tinycert: invalid artifact")
+
+// Artifact selects which piece of a certificate the "what" parameter of
+// cert/get and ca/get asks for.
+type Artifact int
+
+const (
+	Cert Artifact = iota
+	Chain
+	CSR
+	KeyDecrypted
+	Key
+	PKCS12
+	CRL
+)
+
+// CertificatePart is the former name of Artifact.
+type CertificatePart = Artifact
+
+const (
+	CertificateOnly           = Cert
+	CertificateWithChain      = Chain
+	CertificateSigningRequest = CSR
+	PrivateKeyDecrypted       = KeyDecrypted
+	PrivateKeyEncrypted       = Key
+	KeyAndCertificate         = PKCS12
+)
+
+func (a Artifact) String() string {
+	switch a {
+	case Cert:
+		return "cert"
+	case Chain:
+		return "chain"
+	case CSR:
+		return "csr"
+	case KeyDecrypted:
+		return "key.dec"
+	case Key:
+		return "key.enc"
+	case PKCS12:
+		return "pkcs12"
+	case CRL:
+		return "crl"
+	}
+	return fmt.Sprintf("Artifact(%d)", int(a))
+}
+
+// valid reports whether a is a certificate artifact; CRL only exists for CAs.
+func (a Artifact) valid() bool {
+	return a >= Cert && a <= PKCS12
+}
+
+// secret reports whether a carries a private key, which is never cached.
+func (a Artifact) secret() bool {
+	return a == KeyDecrypted || a == Key || a == PKCS12
+}
+
+// ParseArtifact maps an API "what" value such as "chain" or "key.dec" to its
+// Artifact.
+func ParseArtifact(what string) (Artifact, error) {
+	for a := Cert; a <= CRL; a++ {
+		if a.String() == what {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidArtifact, what)
+}
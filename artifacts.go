@@ -0,0 +1,41 @@
+package tinycert
+
+import "context"
+
+func (c *Certificate) GetCertPEM(certId int64) (pem *string, err error) {
+	return c.GetCertPEMCtx(context.Background(), certId)
+}
+
+// GetCertPEMCtx fetches the leaf certificate alone.
+func (c *Certificate) GetCertPEMCtx(ctx context.Context, certId int64) (pem *string, err error) {
+	return c.GetCtx(ctx, certId, Cert)
+}
+
+func (c *Certificate) GetChainPEM(certId int64) (pem *string, err error) {
+	return c.GetChainPEMCtx(context.Background(), certId)
+}
+
+// GetChainPEMCtx fetches the certificate followed by its issuing CA; see
+// FullChain for an ordered bundle.
+func (c *Certificate) GetChainPEMCtx(ctx context.Context, certId int64) (pem *string, err error) {
+	return c.GetCtx(ctx, certId, Chain)
+}
+
+func (c *Certificate) GetPrivateKeyPEM(certId int64, decrypted bool) (pem SecureString, err error) {
+	return c.GetPrivateKeyPEMCtx(context.Background(), certId, decrypted)
+}
+
+// GetPrivateKeyPEMCtx fetches the private key, either decrypted or encrypted
+// with the account passphrase.
+func (c *Certificate) GetPrivateKeyPEMCtx(ctx context.Context, certId int64, decrypted bool) (pem SecureString, err error) {
+	what := Key
+	if decrypted {
+		what = KeyDecrypted
+	}
+
+	key, err := c.GetCtx(ctx, certId, what)
+	if err != nil {
+		return
+	}
+	return NewSecureString(*key), nil
+}
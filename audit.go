@@ -0,0 +1,119 @@
+package tinycert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one mutating API call. Parameters never include the
+// passphrase, token or request digest.
+type AuditRecord struct {
+	Time       time.Time         `json:"time"`
+	Operation  string            `json:"operation"`
+	Parameters map[string]string `json:"parameters"`
+	Result     string            `json:"result"`
+	Error      string            `json:"error,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+}
+
+// AuditSink receives a record for every mutating call made by a session:
+// ca/new, ca/delete, cert/new, cert/reissue and cert/status.
+type AuditSink interface {
+	Audit(ctx context.Context, record AuditRecord) error
+}
+
+var mutatingAPIs = map[string]bool{
+	"ca/new":       true,
+	"ca/delete":    true,
+	"cert/new":     true,
+	"cert/reissue": true,
+	"cert/status":  true,
+}
+
+// WithAuditSink sends an AuditRecord to sink after every mutating call. A
+// sink error is logged and does not fail the call.
+func (s *Session) WithAuditSink(sink AuditSink) *Session {
+	s.audit = sink
+	return s
+}
+
+type auditReasonKey struct{}
+
+// WithAuditReason attaches a reason, such as a change ticket, to the audit
+// records of calls made with ctx.
+func WithAuditReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, auditReasonKey{}, reason)
+}
+
+func (s *Session) recordAudit(ctx context.Context, api string, list url.Values, err error) {
+	if s.audit == nil || !mutatingAPIs[api] {
+		return
+	}
+
+	record := AuditRecord{
+		Time:       s.clock.Now().UTC(),
+		Operation:  api,
+		Parameters: map[string]string{},
+		Result:     "success",
+	}
+	for name := range list {
+		if !sensitiveFields[name] {
+			record.Parameters[name] = list.Get(name)
+		}
+	}
+	if err != nil {
+		record.Result, record.Error = "failure", err.Error()
+	}
+	record.Reason, _ = ctx.Value(auditReasonKey{}).(string)
+
+	if aerr := s.audit.Audit(ctx, record); aerr != nil {
+		s.logger.Log(LevelError, "unable to write audit record for %s: %v", api, aerr)
+	}
+}
+
+// JSONLinesSink writes each AuditRecord as one line of JSON.
+type JSONLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// OpenAuditLog appends audit records to the file at path, creating it with
+// mode 0600 if needed.
+func OpenAuditLog(path string) (sink *JSONLinesSink, err error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	return NewJSONLinesSink(file), nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (js *JSONLinesSink) Close() error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if c, ok := js.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (js *JSONLinesSink) Audit(ctx context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	_, err = js.w.Write(append(line, '\n'))
+	return err
+}
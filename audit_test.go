@@ -0,0 +1,84 @@
+package tinycert_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_AuditLog(t *testing.T) {
+	fs := newFakeServer(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := tinycert.OpenAuditLog(path)
+	if err != nil {
+		t.Fatal("unable to open audit log", err)
+	}
+
+	sess := fs.connectedSession().WithAuditSink(sink)
+	_, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+
+	if _, err := cert.Details(certId); err != nil {
+		t.Fatal("unable to fetch details", err)
+	}
+	if _, err := cert.ReissueWithOptions(certId, tinycert.ReissueOptions{Reason: "CHG-1234"}); err != nil {
+		t.Fatal("unable to reissue", err)
+	}
+
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/status" {
+			return http.StatusNotFound, `{"code":"404","text":"no such certificate"}`
+		}
+		return 0, ""
+	})
+	if err := cert.WithTransitionCheck(false).RevokeCtx(tinycert.WithAuditReason(context.Background(), "key compromise"), 999); err == nil {
+		t.Fatal("expected revoke of unknown certificate to fail")
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []tinycert.AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record tinycert.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		if strings.Contains(scanner.Text(), fakePassphrase) || strings.Contains(scanner.Text(), "token") || strings.Contains(scanner.Text(), "digest") {
+			t.Errorf("audit line leaks secrets: %s", scanner.Text())
+		}
+		records = append(records, record)
+	}
+
+	var ops []string
+	for _, r := range records {
+		ops = append(ops, r.Operation)
+	}
+	if strings.Join(ops, ",") != "ca/new,cert/new,cert/reissue,cert/status" {
+		t.Fatalf("audited operations = %v", ops)
+	}
+
+	if r := records[1]; r.Parameters["CN"] != "www.example.com" || r.Parameters["SANs[0][DNS]"] != "www.example.com" || r.Result != "success" {
+		t.Errorf("cert/new record = %+v", r)
+	}
+	if r := records[2]; r.Reason != "CHG-1234" {
+		t.Errorf("reissue reason = %q", r.Reason)
+	}
+	if r := records[3]; r.Result != "failure" || r.Error == "" || r.Reason != "key compromise" || r.Parameters["status"] != "revoked" {
+		t.Errorf("revoke record = %+v", r)
+	}
+}
@@ -0,0 +1,232 @@
+package tinycert
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+var ErrInvalidBackup = errors.New("tinycert: invalid backup")
+
+// BackupVersion is the version of the backup format Backup writes.
+const BackupVersion = 1
+
+const backupManifest = "manifest.json"
+
+// BackupManifest describes the contents of a backup. It is stored in the
+// archive as manifest.json, after the files it names.
+type BackupManifest struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	CAs       []BackupCA `json:"cas"`
+}
+
+// BackupCA is a CA in a backup; File is the archive path of its PEM.
+type BackupCA struct {
+	Info         CAInfo              `json:"info"`
+	File         string              `json:"file"`
+	Certificates []BackupCertificate `json:"certificates"`
+}
+
+// BackupCertificate is a certificate in a backup. Files maps "cert",
+// "chain", "key" and "pkcs12" to archive paths. A certificate whose
+// artifacts the API would not return, such as a revoked one, has Error set
+// and no files.
+type BackupCertificate struct {
+	Info    CertificateInfo   `json:"info"`
+	Expires int64             `json:"expires"`
+	Files   map[string]string `json:"files,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Backup writes every CA and certificate of the account to w as a gzipped
+// tar archive: each CA's PEM, each certificate's PEM, chain, decrypted key
+// and PKCS#12 archive, and a manifest of their details. The archive holds
+// private keys in the clear and must be stored as carefully as they are.
+func Backup(ctx context.Context, session *Session, w io.Writer) (manifest *BackupManifest, err error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := session.clock.Now()
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	caClient, certClient := NewCA(session), NewCertificate(session)
+	cas, err := caClient.ListCtx(ctx)
+	if err != nil {
+		return
+	}
+
+	manifest = &BackupManifest{Version: BackupVersion, CreatedAt: now}
+	for _, item := range cas {
+		dir := path.Join("cas", formatInt(item.Id))
+		entry := BackupCA{File: path.Join(dir, "ca.pem")}
+
+		var info *CAInfo
+		if info, err = caClient.DetailsCtx(ctx, item.Id); err != nil {
+			return
+		}
+		entry.Info = *info
+		var pem *string
+		if pem, err = caClient.GetCtx(ctx, item.Id); err != nil {
+			return
+		}
+		if err = add(entry.File, []byte(*pem)); err != nil {
+			return
+		}
+
+		var certs []*CertificateListItem
+		if certs, err = certClient.ListCtx(ctx, item.Id, AnyStatus); err != nil {
+			return
+		}
+		for _, cert := range certs {
+			var backedUp *BackupCertificate
+			if backedUp, err = backupCertificate(ctx, certClient, cert, path.Join(dir, "certs", formatInt(cert.Id)), add); err != nil {
+				return
+			}
+			entry.Certificates = append(entry.Certificates, *backedUp)
+		}
+		manifest.CAs = append(manifest.CAs, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = add(backupManifest, data); err != nil {
+		return
+	}
+	if err = tw.Close(); err != nil {
+		return
+	}
+	err = gz.Close()
+	return
+}
+
+func backupCertificate(ctx context.Context, c *Certificate, item *CertificateListItem, dir string, add func(name string, data []byte) error) (backedUp *BackupCertificate, err error) {
+	backedUp = &BackupCertificate{Expires: item.Expires}
+	info, err := c.DetailsCtx(ctx, item.Id)
+	if err != nil {
+		return
+	}
+	backedUp.Info = *info
+
+	bundle, err := c.GetAllCtx(ctx, item.Id)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		backedUp.Error = apiErr.Error()
+		return backedUp, nil
+	}
+	if err != nil {
+		return
+	}
+
+	backedUp.Files = map[string]string{}
+	for _, f := range []struct {
+		kind, name string
+		data       []byte
+	}{
+		{"cert", "cert.pem", []byte(bundle.Cert)},
+		{"chain", "chain.pem", []byte(bundle.Chain)},
+		{"key", "key.pem", []byte(bundle.Key.Reveal())},
+		{"pkcs12", "cert.p12", bundle.PKCS12},
+	} {
+		name := path.Join(dir, f.name)
+		if err = add(name, f.data); err != nil {
+			return
+		}
+		backedUp.Files[f.kind] = name
+	}
+	return
+}
+
+// ReadBackupManifest returns the manifest of a backup written by Backup.
+func ReadBackupManifest(r io.Reader) (manifest *BackupManifest, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		var hdr *tar.Header
+		if hdr, err = tr.Next(); err == io.EOF {
+			return nil, fmt.Errorf("%w: no %s", ErrInvalidBackup, backupManifest)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if hdr.Name != backupManifest {
+			continue
+		}
+
+		manifest = &BackupManifest{}
+		if err = json.NewDecoder(tr).Decode(manifest); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBackup, backupManifest, err)
+		}
+		if manifest.Version != BackupVersion {
+			return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, manifest.Version)
+		}
+		return
+	}
+}
+
+// RestoreReport maps the ids in a backup to the ids of the CAs and
+// certificates Restore made for them in the account.
+type RestoreReport struct {
+	CAs          map[int64]int64
+	Certificates map[int64]int64
+}
+
+// Restore re-creates the CAs and the good certificates of a backup in the
+// account, with the same subjects and SANs; revoked, held and expired
+// certificates are not restored. TinyCert cannot import keys, so restored
+// CAs and certificates have new keys: services must be given the new
+// certificates, and trust the new CAs. CAs and certificates that already
+// exist, e.g. from an earlier Restore that failed part way, are reused, so a
+// failed Restore can be run again. It stops at the first failure, returning
+// what was restored so far. Restored certificates are recorded in the
+// session's lineage as replacing the backed up ones.
+func Restore(ctx context.Context, session *Session, r io.Reader) (report *RestoreReport, err error) {
+	manifest, err := ReadBackupManifest(r)
+	if err != nil {
+		return
+	}
+
+	caClient, certClient := NewCA(session), NewCertificate(session)
+	report = &RestoreReport{CAs: map[int64]int64{}, Certificates: map[int64]int64{}}
+	for _, entry := range manifest.CAs {
+		var req CARequest
+		if req, err = caRequestFrom(&entry.Info); err != nil {
+			return
+		}
+		var caId *int64
+		if caId, _, err = caClient.EnsureCtx(ctx, req); err != nil {
+			return
+		}
+		report.CAs[entry.Info.Id] = *caId
+
+		for _, cert := range entry.Certificates {
+			if !cert.Info.Status.Has(Good) {
+				continue
+			}
+			info := cert.Info
+			var certId *int64
+			if certId, _, err = certClient.EnsureCtx(ctx, *caId, certRequestFrom(&info)); err != nil {
+				return
+			}
+			report.Certificates[info.Id] = *certId
+			session.lineage.Record(info.Id, *certId, "restore")
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package tinycert_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestBackupRestore(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+	revokedId, err := cert.Create(caId, tinycert.CertRequest{CommonName: "old.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cert.Revoke(*revokedId); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := tinycert.Backup(context.Background(), sess, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.CAs) != 1 || manifest.CAs[0].Info.Id != caId || len(manifest.CAs[0].Certificates) != 2 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	files := readArchive(t, buf.Bytes())
+	if len(parseCerts(t, string(files[manifest.CAs[0].File]))) != 1 {
+		t.Error("no CA certificate in the backup")
+	}
+	for _, backedUp := range manifest.CAs[0].Certificates {
+		if backedUp.Info.Id != certId {
+			continue
+		}
+		leaf := parseCerts(t, string(files[backedUp.Files["cert"]]))[0]
+		if leaf.Subject.CommonName != "www.example.com" || backedUp.Info.CommonName != "www.example.com" {
+			t.Errorf("backed up %s as %+v", leaf.Subject, backedUp.Info)
+		}
+		if len(files[backedUp.Files["key"]]) == 0 {
+			t.Error("no key in the backup")
+		}
+		if _, _, _, err := pkcs12.DecodeChain(files[backedUp.Files["pkcs12"]], fakePassphrase); err != nil {
+			t.Error("pkcs12:", err)
+		}
+	}
+
+	// Restoring into an empty account re-creates the CA and the good
+	// certificate.
+	other := newFakeServer(t)
+	report, err := tinycert.Restore(context.Background(), other.connectedSession(), bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.CAs) != 1 || len(report.Certificates) != 1 || report.Certificates[certId] == 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	// Restoring again reuses them.
+	if _, err = tinycert.Restore(context.Background(), other.connectedSession(), bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if other.callCount("ca/new") != 1 || other.callCount("cert/new") != 1 {
+		t.Errorf("restoring twice created %d CAs and %d certificates", other.callCount("ca/new"), other.callCount("cert/new"))
+	}
+
+	if _, err = tinycert.Restore(context.Background(), other.connectedSession(), bytes.NewReader([]byte("not a backup"))); !errors.Is(err, tinycert.ErrInvalidBackup) {
+		t.Errorf("err = %v, want ErrInvalidBackup", err)
+	}
+}
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
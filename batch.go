@@ -0,0 +1,61 @@
+package tinycert
+
+import (
+	"context"
+	"sync"
+)
+
+const DefaultParallelism = 4
+
+// BatchResult is the outcome of one request of a batch; exactly one of CertId
+// and Err is set.
+type BatchResult struct {
+	Request CertRequest
+	CertId  *int64
+	Err     error
+}
+
+func (c *Certificate) CreateBatch(caId int64, requests []CertRequest) (results []*BatchResult) {
+	return c.CreateBatchCtx(context.Background(), caId, requests)
+}
+
+// CreateBatchCtx issues the requested certificates concurrently, running at
+// most WithParallelism calls at once, and reports each to WithProgress's
+// Progress by common name. Results are returned in request order.
+func (c *Certificate) CreateBatchCtx(ctx context.Context, caId int64, requests []CertRequest) (results []*BatchResult) {
+	results = make([]*BatchResult, len(requests))
+	progress := startProgress(c.progress, len(requests))
+	c.forEach(ctx, len(requests), func(ctx context.Context, i int) {
+		req := requests[i]
+		certId, err := c.CreateCtx(ctx, caId, req)
+		results[i] = &BatchResult{Request: req, CertId: certId, Err: err}
+		progress.item(req.CommonName, err)
+	})
+	progress.done()
+	return
+}
+
+// forEach calls fn for 0..n-1 with at most c.parallelism calls in flight. Once
+// ctx is done the remaining items are still visited so fn can record the
+// context error.
+func (c *Certificate) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int)) {
+	parallelism := c.parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,46 @@
+package tinycert_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_CreateBatch(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+
+	requests := []tinycert.CertRequest{}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("svc-%d.example.com", i)
+		requests = append(requests, tinycert.CertRequest{CommonName: name, OrgName: "acme", CountryCode: "US", Alt: []tinycert.SAN{{DNS: name}}})
+	}
+
+	results := tinycert.NewCertificate(sess).WithParallelism(3).CreateBatch(caId, requests)
+	if len(results) != len(requests) {
+		t.Fatalf("got %d results, want %d", len(results), len(requests))
+	}
+
+	seen := map[int64]bool{}
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("request %d failed: %v", i, res.Err)
+		}
+		if res.Request.CommonName != requests[i].CommonName {
+			t.Errorf("result %d is for %q, want %q", i, res.Request.CommonName, requests[i].CommonName)
+		}
+		if seen[*res.CertId] {
+			t.Errorf("duplicate cert id %d", *res.CertId)
+		}
+		seen[*res.CertId] = true
+	}
+
+	results = tinycert.NewCertificate(sess).CreateBatch(424242, requests[:2])
+	for _, res := range results {
+		if res.Err == nil || res.CertId != nil {
+			t.Errorf("expected per-item error for unknown ca, got %+v", res)
+		}
+	}
+}
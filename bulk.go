@@ -0,0 +1,154 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrConfirmationRequired is returned by bulk operations run without the
+// token of a dry run over the same selection.
+var ErrConfirmationRequired = errors.New("tinycert: bulk operation not confirmed; run it as a dry run and pass back its token")
+
+// BulkOptions control CA.DeleteAll and Certificate.RevokeAll. Nothing is
+// changed unless the selection is confirmed: a dry run returns a Token for
+// what it selected, and the real run must pass it back. If the selection has
+// changed in between, the run fails with ErrConfirmationRequired, so it
+// never touches anything that was not reviewed.
+type BulkOptions struct {
+	DryRun bool
+	Token  string
+	// Parallelism bounds how many calls run at once; zero uses the default
+	// of the CA or Certificate.
+	Parallelism int
+	// Timeout, if set, bounds the whole run. Items not done by then fail
+	// with context.DeadlineExceeded.
+	Timeout time.Duration
+}
+
+// BulkResult is the outcome for one CA or certificate of a bulk operation.
+type BulkResult struct {
+	Id   int64
+	Name string
+	Err  error
+}
+
+// BulkSummary describes a bulk operation. A dry run has a result without
+// error for each selected item.
+type BulkSummary struct {
+	DryRun  bool
+	Token   string
+	Results []*BulkResult
+	// Succeeded and Failed count the results of a real run.
+	Succeeded int
+	Failed    int
+	Duration  time.Duration
+}
+
+func (ca *CA) DeleteAll(filter CAFilter, opts BulkOptions) (summary *BulkSummary, err error) {
+	return ca.DeleteAllCtx(context.Background(), filter, opts)
+}
+
+// DeleteAllCtx deletes every CA filter matches, e.g. the throwaway CAs tests
+// leave behind, once confirmed; see BulkOptions.
+func (ca *CA) DeleteAllCtx(ctx context.Context, filter CAFilter, opts BulkOptions) (summary *BulkSummary, err error) {
+	items, err := ca.ListFilteredCtx(ctx, filter)
+	if err != nil {
+		return
+	}
+	selected := make([]*BulkResult, len(items))
+	for i, item := range items {
+		selected[i] = &BulkResult{Id: item.Id, Name: item.Name}
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism == 0 {
+		parallelism = DefaultParallelism
+	}
+	runner := NewCertificate(ca.session).WithParallelism(parallelism)
+	return runBulk(ctx, runner, "ca/delete", selected, opts, ca.DeleteCtx)
+}
+
+func (c *Certificate) RevokeAll(caId int64, filter CertificateFilter, opts BulkOptions) (summary *BulkSummary, err error) {
+	return c.RevokeAllCtx(context.Background(), caId, filter, opts)
+}
+
+// RevokeAllCtx revokes every certificate of the CA that filter matches, once
+// confirmed; see BulkOptions. A filter without Status selects good and held
+// certificates. Progress is reported to WithProgress's Progress.
+func (c *Certificate) RevokeAllCtx(ctx context.Context, caId int64, filter CertificateFilter, opts BulkOptions) (summary *BulkSummary, err error) {
+	if filter.Status == 0 {
+		filter.Status = Good | Hold
+	}
+	items, err := c.ListFilteredCtx(ctx, caId, filter)
+	if err != nil {
+		return
+	}
+	selected := make([]*BulkResult, len(items))
+	for i, item := range items {
+		selected[i] = &BulkResult{Id: item.Id, Name: item.Name}
+	}
+
+	runner := c
+	if opts.Parallelism != 0 {
+		runner = &Certificate{session: c.session, progress: c.progress}
+		runner.WithParallelism(opts.Parallelism)
+	}
+	return runBulk(ctx, runner, "cert/revoke "+formatInt(caId), selected, opts, c.RevokeCtx)
+}
+
+func runBulk(ctx context.Context, runner *Certificate, operation string, selected []*BulkResult, opts BulkOptions, apply func(ctx context.Context, id int64) error) (summary *BulkSummary, err error) {
+	start := time.Now()
+	summary = &BulkSummary{DryRun: opts.DryRun, Token: bulkToken(operation, selected), Results: selected}
+	if opts.DryRun {
+		return
+	}
+	if opts.Token != summary.Token {
+		return summary, ErrConfirmationRequired
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	progress := startProgress(runner.progress, len(selected))
+	runner.forEach(ctx, len(selected), func(ctx context.Context, i int) {
+		result := selected[i]
+		if result.Err = ctx.Err(); result.Err == nil {
+			result.Err = apply(ctx, result.Id)
+		}
+		progress.item(result.Name, result.Err)
+	})
+	progress.done()
+
+	for _, result := range selected {
+		if result.Err != nil {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+	summary.Duration = time.Since(start)
+	return
+}
+
+// bulkToken identifies the operation and the ids it selected.
+func bulkToken(operation string, selected []*BulkResult) string {
+	ids := make([]int64, len(selected))
+	for i, result := range selected {
+		ids[i] = result.Id
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	h := sha256.New()
+	h.Write([]byte(operation))
+	for _, id := range ids {
+		h.Write([]byte{' '})
+		h.Write([]byte(formatInt(id)))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
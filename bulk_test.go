@@ -0,0 +1,94 @@
+package tinycert_test
+
+import (
+	"errors"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCA_DeleteAll(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	ca := tinycert.NewCA(sess)
+	newCA := func(cn string) {
+		t.Helper()
+		if _, err := ca.Create(tinycert.CARequest{CommonName: cn, OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newCA("throwaway 1")
+	newCA("throwaway 2")
+	newCA("keep")
+	filter := tinycert.CAFilter{Name: regexp.MustCompile("^throwaway")}
+
+	if _, err := ca.DeleteAll(filter, tinycert.BulkOptions{}); !errors.Is(err, tinycert.ErrConfirmationRequired) {
+		t.Fatalf("err = %v, want ErrConfirmationRequired", err)
+	}
+	plan, err := ca.DeleteAll(filter, tinycert.BulkOptions{DryRun: true})
+	if err != nil || len(plan.Results) != 2 || plan.Token == "" {
+		t.Fatalf("dry run = %+v, %v", plan, err)
+	}
+	if fs.callCount("ca/delete") != 0 {
+		t.Fatal("deleted without confirmation")
+	}
+
+	// The token no longer confirms a selection that has changed.
+	newCA("throwaway 3")
+	if _, err = ca.DeleteAll(filter, tinycert.BulkOptions{Token: plan.Token}); !errors.Is(err, tinycert.ErrConfirmationRequired) {
+		t.Fatalf("err = %v, want ErrConfirmationRequired for a changed selection", err)
+	}
+
+	plan, _ = ca.DeleteAll(filter, tinycert.BulkOptions{DryRun: true})
+	summary, err := ca.DeleteAll(filter, tinycert.BulkOptions{Token: plan.Token, Parallelism: 2})
+	if err != nil || summary.Succeeded != 3 || summary.Failed != 0 {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+	if items, _ := ca.List(); len(items) != 1 || items[0].Name != "keep" {
+		t.Errorf("left %+v", items)
+	}
+}
+
+func TestCertificate_RevokeAll(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, keepId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+	for _, cn := range []string{"tmp1.example.com", "tmp2.example.com"} {
+		if _, err := cert.Create(caId, tinycert.CertRequest{CommonName: cn}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filter := tinycert.CertificateFilter{Name: regexp.MustCompile(`^tmp`)}
+
+	plan, err := cert.RevokeAll(caId, filter, tinycert.BulkOptions{DryRun: true})
+	if err != nil || len(plan.Results) != 2 {
+		t.Fatalf("dry run = %+v, %v", plan, err)
+	}
+	var calls atomic.Int32
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/status" && calls.Add(1) == 1 {
+			return 500, `{"code":"500","text":"boom"}`
+		}
+		return 0, ""
+	})
+	summary, err := cert.WithParallelism(1).RevokeAll(caId, filter, tinycert.BulkOptions{Token: plan.Token})
+	if err != nil || summary.Succeeded != 1 || summary.Failed != 1 {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+
+	// Revoked certificates are not selected again.
+	fs.setFail(nil)
+	plan, _ = cert.RevokeAll(caId, filter, tinycert.BulkOptions{DryRun: true})
+	if len(plan.Results) != 1 {
+		t.Fatalf("second dry run = %+v", plan.Results)
+	}
+	if summary, err = cert.RevokeAll(caId, filter, tinycert.BulkOptions{Token: plan.Token}); err != nil || summary.Succeeded != 1 {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+	if info, _ := cert.Details(keepId); info.Status != tinycert.Good {
+		t.Errorf("unselected certificate is %v", info.Status)
+	}
+}
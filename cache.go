@@ -0,0 +1,181 @@
+package tinycert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheKey identifies a cached artifact; Kind is "ca" or "cert".
+type CacheKey struct {
+	Kind string
+	Id   int64
+	What Artifact
+}
+
+func (k CacheKey) String() string {
+	return fmt.Sprintf("%s-%d-%s", k.Kind, k.Id, k.What)
+}
+
+// Cache stores artifacts fetched by CA.Get and Certificate.Get so repeated
+// calls don't hit the API. Private keys and PKCS#12 bundles are never cached.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key CacheKey) (value string, ok bool)
+	Set(key CacheKey, value string)
+	Delete(key CacheKey)
+}
+
+// WithCache serves CA and certificate artifacts from cache when present.
+func (s *Session) WithCache(cache Cache) *Session {
+	s.cache = cache
+	return s
+}
+
+func (s *Session) cached(key CacheKey) (*string, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+	value, ok := s.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	s.logger.Log(LevelDebug, "cache hit for %s", key)
+	return &value, true
+}
+
+func (s *Session) store(key CacheKey, value string) {
+	if s.cache != nil {
+		s.cache.Set(key, value)
+	}
+}
+
+func (s *Session) invalidate(kind string, id int64, what ...Artifact) {
+	if s.cache == nil {
+		return
+	}
+	for _, w := range what {
+		s.cache.Delete(CacheKey{Kind: kind, Id: id, What: w})
+	}
+}
+
+// Invalidate drops the cached certificate and CRL of the CA.
+func (ca *CA) Invalidate(caId int64) {
+	ca.session.invalidate("ca", caId, Cert, CRL)
+}
+
+// Invalidate drops every cached artifact of the certificate.
+func (c *Certificate) Invalidate(certId int64) {
+	c.session.invalidate("cert", certId, Cert, Chain, CSR, KeyDecrypted, Key, PKCS12)
+}
+
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// MemoryCache is an in-process Cache whose entries expire after a TTL.
+type MemoryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	clock   Clock
+	entries map[CacheKey]memoryEntry
+}
+
+// NewMemoryCache returns a MemoryCache; a ttl of zero keeps entries forever.
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, clock: ClockFunc(time.Now), entries: map[CacheKey]memoryEntry{}}
+}
+
+// WithClock makes the cache expire entries by clock instead of time.Now.
+func (mc *MemoryCache) WithClock(clock Clock) *MemoryCache {
+	mc.clock = clock
+	return mc
+}
+
+func (mc *MemoryCache) Get(key CacheKey) (string, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	entry, ok := mc.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !entry.expires.IsZero() && mc.clock.Now().After(entry.expires) {
+		delete(mc.entries, key)
+		return "", false
+	}
+	return entry.value, true
+}
+
+func (mc *MemoryCache) Set(key CacheKey, value string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	entry := memoryEntry{value: value}
+	if mc.ttl > 0 {
+		entry.expires = mc.clock.Now().Add(mc.ttl)
+	}
+	mc.entries[key] = entry
+}
+
+func (mc *MemoryCache) Delete(key CacheKey) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	delete(mc.entries, key)
+}
+
+// DiskCache is a Cache keeping one 0600 file per entry in a directory, so
+// entries survive process restarts. Entries older than the TTL are ignored.
+type DiskCache struct {
+	dir   string
+	ttl   time.Duration
+	clock Clock
+}
+
+// NewDiskCache creates dir if needed; a ttl of zero keeps entries forever.
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir, ttl: ttl, clock: ClockFunc(time.Now)}, nil
+}
+
+// WithClock makes the cache age entries by clock instead of time.Now.
+func (dc *DiskCache) WithClock(clock Clock) *DiskCache {
+	dc.clock = clock
+	return dc
+}
+
+func (dc *DiskCache) path(key CacheKey) string {
+	return filepath.Join(dc.dir, key.String())
+}
+
+func (dc *DiskCache) Get(key CacheKey) (string, bool) {
+	path := dc.path(key)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	if dc.ttl > 0 && dc.clock.Now().Sub(info.ModTime()) > dc.ttl {
+		os.Remove(path)
+		return "", false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+func (dc *DiskCache) Set(key CacheKey, value string) {
+	writeFileAtomic(dc.path(key), []byte(value), 0600)
+}
+
+func (dc *DiskCache) Delete(key CacheKey) {
+	os.Remove(dc.path(key))
+}
@@ -0,0 +1,93 @@
+package tinycert_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCache_ServesRepeatedGets(t *testing.T) {
+	disk, err := tinycert.NewDiskCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, cache := range map[string]tinycert.Cache{
+		"memory": tinycert.NewMemoryCache(time.Hour),
+		"disk":   disk,
+	} {
+		t.Run(name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			sess := fs.connectedSession().WithCache(cache)
+			caId, certId := newCAAndCert(t, sess)
+
+			ca := tinycert.NewCA(sess)
+			cert := tinycert.NewCertificate(sess)
+			for i := 0; i < 3; i++ {
+				if _, err := ca.Get(caId); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cert.Get(certId, tinycert.Chain); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := fs.callCount("ca/get"); got != 1 {
+				t.Errorf("ca/get called %d times, want 1", got)
+			}
+			if got := fs.callCount("cert/get"); got != 1 {
+				t.Errorf("cert/get called %d times, want 1", got)
+			}
+
+			ca.Invalidate(caId)
+			cert.Invalidate(certId)
+			ca.Get(caId)
+			cert.Get(certId, tinycert.Chain)
+			if got := fs.callCount("ca/get") + fs.callCount("cert/get"); got != 4 {
+				t.Errorf("expected invalidated entries to be refetched, got %d calls", got)
+			}
+		})
+	}
+}
+
+func TestCache_SkipsKeys(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := tinycert.NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithCache(disk)
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	secrets := []tinycert.Artifact{tinycert.KeyDecrypted, tinycert.Key, tinycert.PKCS12}
+	for i := 0; i < 2; i++ {
+		for _, what := range secrets {
+			if _, err := cert.Get(certId, what); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got, want := fs.callCount("cert/get"), 2*len(secrets); got != want {
+		t.Errorf("cert/get called %d times, want %d", got, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cache holds %d entries for private keys", len(entries))
+	}
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	cache := tinycert.NewMemoryCache(10 * time.Millisecond)
+	key := tinycert.CacheKey{Kind: "ca", Id: 1, What: tinycert.Cert}
+
+	cache.Set(key, "pem")
+	if v, ok := cache.Get(key); !ok || v != "pem" {
+		t.Fatal("expected cached value")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.Get(key); ok {
+		t.Error("expected entry to expire")
+	}
+}
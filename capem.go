@@ -0,0 +1,98 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// CAPEM is a CA certificate held by a CAPEMCache.
+type CAPEM struct {
+	CaId int64
+	PEM  string
+	// ETag is a hash of PEM: it changes exactly when the PEM does, so callers
+	// can tell whether a CA they have deployed is still current.
+	ETag string
+	// FetchedAt is when the PEM was last fetched from the API, even if it
+	// came back unchanged.
+	FetchedAt time.Time
+}
+
+// CAPEMCache keeps the certificates of CAs in memory. CA certificates almost
+// never change, so pipelines that deploy them often can ask the cache, and
+// compare ETags, instead of fetching them on every run. It is safe for
+// concurrent use.
+type CAPEMCache struct {
+	ca *CA
+	// MaxAge is how long a certificate is served before it is fetched again;
+	// zero serves it until a Refresh.
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	entries map[int64]*CAPEM
+}
+
+func NewCAPEMCache(session *Session) *CAPEMCache {
+	return &CAPEMCache{ca: NewCA(session), entries: map[int64]*CAPEM{}}
+}
+
+func (pc *CAPEMCache) Get(caId int64, refresh bool) (pem *CAPEM, err error) {
+	return pc.GetCtx(context.Background(), caId, refresh)
+}
+
+// GetCtx returns the certificate of the CA, fetching it when it is not cached
+// or older than MaxAge, or when refresh is set. A refresh also bypasses the
+// session's Cache.
+func (pc *CAPEMCache) GetCtx(ctx context.Context, caId int64, refresh bool) (pem *CAPEM, err error) {
+	pc.mu.Lock()
+	entry, ok := pc.entries[caId]
+	pc.mu.Unlock()
+	if ok && !refresh && (pc.MaxAge == 0 || pc.ca.session.clock.Now().Sub(entry.FetchedAt) < pc.MaxAge) {
+		copied := *entry
+		return &copied, nil
+	}
+
+	if refresh {
+		pc.ca.Invalidate(caId)
+	}
+	fetched, err := pc.ca.GetCtx(ctx, caId)
+	if err != nil {
+		return
+	}
+
+	entry = &CAPEM{CaId: caId, PEM: *fetched, ETag: pemETag(*fetched), FetchedAt: pc.ca.session.clock.Now()}
+	pc.mu.Lock()
+	pc.entries[caId] = entry
+	pc.mu.Unlock()
+
+	copied := *entry
+	return &copied, nil
+}
+
+func (pc *CAPEMCache) GetIfChanged(caId int64, etag string, refresh bool) (pem *CAPEM, err error) {
+	return pc.GetIfChangedCtx(context.Background(), caId, etag, refresh)
+}
+
+// GetIfChangedCtx is GetCtx conditional on etag, the ETag of the certificate
+// the caller already has: it returns nil if the certificate still has that
+// ETag.
+func (pc *CAPEMCache) GetIfChangedCtx(ctx context.Context, caId int64, etag string, refresh bool) (pem *CAPEM, err error) {
+	if pem, err = pc.GetCtx(ctx, caId, refresh); err != nil || pem.ETag == etag {
+		return nil, err
+	}
+	return
+}
+
+// Forget drops the certificate of the CA, e.g. after deleting it.
+func (pc *CAPEMCache) Forget(caId int64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	delete(pc.entries, caId)
+}
+
+func pemETag(pem string) string {
+	sum := sha256.Sum256([]byte(pem))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
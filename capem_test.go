@@ -0,0 +1,81 @@
+package tinycert_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCAPEMCache(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+
+	cache := tinycert.NewCAPEMCache(sess)
+	first, err := cache.Get(caId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.CaId != caId || first.ETag == "" || len(parseCerts(t, first.PEM)) != 1 {
+		t.Fatalf("unexpected entry %+v", first)
+	}
+
+	again, err := cache.Get(caId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ETag != first.ETag || fs.callCount("ca/get") != 1 {
+		t.Errorf("expected a cached entry, ca/get called %d times", fs.callCount("ca/get"))
+	}
+
+	refreshed, err := cache.Get(caId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.ETag != first.ETag || !refreshed.FetchedAt.After(first.FetchedAt) || fs.callCount("ca/get") != 2 {
+		t.Errorf("expected a refetch with the same etag, got %+v", refreshed)
+	}
+
+	changed, err := cache.GetIfChanged(caId, first.ETag, false)
+	if err != nil || changed != nil {
+		t.Errorf("GetIfChanged(current etag) = %v, %v, want nil", changed, err)
+	}
+	changed, err = cache.GetIfChanged(caId, `"stale"`, false)
+	if err != nil || changed == nil || changed.ETag != first.ETag {
+		t.Errorf("GetIfChanged(stale etag) = %v, %v", changed, err)
+	}
+	if got := fs.callCount("ca/get"); got != 2 {
+		t.Errorf("ca/get called %d times, want 2", got)
+	}
+}
+
+func TestCAPEMCache_MaxAge(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithCache(tinycert.NewMemoryCache(time.Hour))
+	caId, _ := newCAAndCert(t, sess)
+
+	cache := tinycert.NewCAPEMCache(sess)
+	cache.MaxAge = time.Nanosecond
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(caId, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An expired entry is served from the session cache; only a refresh
+	// goes back to the API.
+	if got := fs.callCount("ca/get"); got != 1 {
+		t.Errorf("ca/get called %d times, want 1", got)
+	}
+	if _, err := cache.Get(caId, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.callCount("ca/get"); got != 2 {
+		t.Errorf("ca/get called %d times after refresh, want 2", got)
+	}
+
+	cache.Forget(caId)
+	if _, err := cache.Get(caId, false); err != nil {
+		t.Fatal(err)
+	}
+}
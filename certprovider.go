@@ -0,0 +1,106 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultRenewBefore is how close to expiry a CertProvider reissues its
+// certificate unless RenewBefore says otherwise.
+const DefaultRenewBefore = 30 * 24 * time.Hour
+
+// CertProvider keeps a TinyCert certificate loaded for a TLS server and swaps
+// in renewed certificates without a restart. Plug GetCertificate into
+// tls.Config and start Run in its own goroutine:
+//
+//	provider, err := tinycert.NewCertProvider(ctx, sess, certId)
+//	go provider.Run(ctx, time.Hour)
+//	server.TLSConfig = &tls.Config{GetCertificate: provider.GetCertificate}
+type CertProvider struct {
+	cert *Certificate
+	// RenewBefore is how long before expiry Refresh reissues the certificate;
+	// zero means DefaultRenewBefore.
+	RenewBefore time.Duration
+
+	mu      sync.RWMutex
+	certId  int64
+	current *tls.Certificate
+}
+
+// NewCertProvider loads certId and returns a provider serving it.
+func NewCertProvider(ctx context.Context, sess *Session, certId int64) (p *CertProvider, err error) {
+	p = &CertProvider{cert: NewCertificate(sess), certId: certId}
+	if p.current, err = p.cert.AsTLSCertificateCtx(ctx, certId); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// GetCertificate returns the current certificate; it never calls TinyCert.
+func (p *CertProvider) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current, nil
+}
+
+// CertId returns the id of the certificate being served, which changes each
+// time Refresh reissues it.
+func (p *CertProvider) CertId() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.certId
+}
+
+// Refresh fetches the certificate again, reissuing it first when it expires
+// within RenewBefore. On error the previous certificate keeps being served.
+func (p *CertProvider) Refresh(ctx context.Context) (err error) {
+	p.mu.RLock()
+	certId, current := p.certId, p.current
+	p.mu.RUnlock()
+
+	renewBefore := p.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = DefaultRenewBefore
+	}
+	if current.Leaf == nil {
+		return errors.New("tinycert: certificate has no leaf")
+	}
+	if current.Leaf.NotAfter.Sub(p.cert.session.clock.Now()) < renewBefore {
+		var newCertId *int64
+		if newCertId, err = p.cert.ReissueWithOptionsCtx(ctx, certId, ReissueOptions{Reason: "expiring"}); err != nil {
+			return
+		}
+		certId = *newCertId
+	}
+
+	tlsCert, err := p.cert.AsTLSCertificateCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.certId, p.current = certId, tlsCert
+	return
+}
+
+// Run refreshes every interval until ctx is done. Refresh failures are logged
+// and keep the previous certificate.
+func (p *CertProvider) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := p.Refresh(ctx); err != nil && ctx.Err() == nil {
+			p.cert.session.logger.Log(LevelWarn, "unable to refresh certificate %d: %v", p.CertId(), err)
+		}
+	}
+}
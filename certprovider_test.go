@@ -0,0 +1,61 @@
+package tinycert_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertProvider_Refresh(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+	ctx := context.Background()
+
+	p, err := tinycert.NewCertProvider(ctx, sess, certId)
+	if err != nil {
+		t.Fatal("NewCertProvider()", err)
+	}
+	first, _ := p.GetCertificate(nil)
+	if first == nil || first.Leaf == nil {
+		t.Fatal("GetCertificate() returned no certificate")
+	}
+
+	// Far from expiry: the same certificate is fetched again.
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal("Refresh()", err)
+	}
+	if p.CertId() != certId || fs.callCount("cert/reissue") != 0 {
+		t.Errorf("Refresh() reissued a fresh certificate")
+	}
+
+	// The fake issues for a year, so this is within the renewal window.
+	p.RenewBefore = 2 * 365 * 24 * time.Hour
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal("Refresh()", err)
+	}
+	if p.CertId() == certId {
+		t.Fatal("Refresh() did not reissue an expiring certificate")
+	}
+	renewed, _ := p.GetCertificate(nil)
+	if renewed.Leaf.SerialNumber.Cmp(first.Leaf.SerialNumber) == 0 {
+		t.Error("GetCertificate() still serves the old certificate")
+	}
+	if got := tinycert.NewCertificate(sess).History(p.CertId()); len(got) != 2 || got[0] != certId {
+		t.Errorf("History() = %v", got)
+	}
+
+	// A failed refresh keeps serving what it had.
+	fs.setFail(func(api string) (int, string) {
+		return http.StatusInternalServerError, `{"code":"500","text":"down"}`
+	})
+	if err := p.Refresh(ctx); err == nil {
+		t.Error("expected Refresh() to fail")
+	}
+	if kept, _ := p.GetCertificate(nil); kept != renewed {
+		t.Error("failed Refresh() replaced the certificate")
+	}
+}
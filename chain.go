@@ -0,0 +1,97 @@
+package tinycert
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+func (c *Certificate) FullChain(certId int64, includeRoot bool) (bundle *string, err error) {
+	return c.FullChainCtx(context.Background(), certId, includeRoot)
+}
+
+// FullChainCtx returns a PEM bundle with the leaf first, followed by its
+// issuing CA and any issuers above it. The issuing CA is always included,
+// even when it is itself the root, as with TinyCert's own CAs; a self-signed
+// root above it is only included when includeRoot is set. The issuing CA is
+// fetched separately when the chain artifact lacks it.
+func (c *Certificate) FullChainCtx(ctx context.Context, certId int64, includeRoot bool) (bundle *string, err error) {
+	leafPem, err := c.GetCtx(ctx, certId, Cert)
+	if err != nil {
+		return
+	}
+	leaf, err := parseCertificatePEM([]byte(*leafPem))
+	if err != nil {
+		return
+	}
+
+	chainPem, err := c.GetCtx(ctx, certId, Chain)
+	if err != nil {
+		return
+	}
+	pool, err := parseCertificatesPEM([]byte(*chainPem))
+	if err != nil {
+		return
+	}
+
+	if findIssuer(leaf, pool) == nil {
+		var caId int64
+		if caId, err = c.caOf(ctx, certId); err != nil {
+			return
+		}
+		var caPem *string
+		if caPem, err = NewCA(c.session).GetCtx(ctx, caId); err != nil {
+			return
+		}
+		var caCerts []*x509.Certificate
+		if caCerts, err = parseCertificatesPEM([]byte(*caPem)); err != nil {
+			return
+		}
+		pool = append(pool, caCerts...)
+	}
+
+	var buf bytes.Buffer
+	for i, cert := range orderChain(leaf, pool) {
+		// Index 0 is the leaf and 1 its issuing CA, which is always kept.
+		if i > 1 && isSelfSigned(cert) && !includeRoot {
+			break
+		}
+		if err = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return
+		}
+	}
+
+	result := buf.String()
+	return &result, nil
+}
+
+// orderChain walks from leaf through its issuers found in pool.
+func orderChain(leaf *x509.Certificate, pool []*x509.Certificate) []*x509.Certificate {
+	chain := []*x509.Certificate{leaf}
+	for cert := leaf; !isSelfSigned(cert) && len(chain) <= len(pool); {
+		issuer := findIssuer(cert, pool)
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, issuer)
+		cert = issuer
+	}
+	return chain
+}
+
+func findIssuer(cert *x509.Certificate, pool []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range pool {
+		if candidate.Equal(cert) {
+			continue
+		}
+		if bytes.Equal(candidate.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
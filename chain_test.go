@@ -0,0 +1,36 @@
+package tinycert_test
+
+import (
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_FullChain(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	for _, tt := range []struct {
+		includeRoot bool
+		want        []string
+	}{
+		{false, []string{"www.example.com", "acme Root CA"}},
+		{true, []string{"www.example.com", "acme Root CA"}},
+	} {
+		bundle, err := cert.FullChain(certId, tt.includeRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs := parseCerts(t, *bundle)
+		if len(certs) != len(tt.want) {
+			t.Fatalf("includeRoot=%v: got %d certs, want %d", tt.includeRoot, len(certs), len(tt.want))
+		}
+		for i, name := range tt.want {
+			if certs[i].Subject.CommonName != name {
+				t.Errorf("includeRoot=%v: cert %d = %q, want %q", tt.includeRoot, i, certs[i].Subject.CommonName, name)
+			}
+		}
+	}
+}
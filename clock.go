@@ -0,0 +1,29 @@
+package tinycert
+
+import "time"
+
+// Clock tells the current time. Renewal, cache expiry, expiry reports and
+// metrics, and the timestamps of events, audit records, lineage and backups
+// read the time from the session's Clock, so tests can simulate an expiry
+// window instead of waiting for it. Latencies and backoffs use the system
+// clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function such as time.Now to the Clock interface.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// WithClock makes the session read the time from clock; nil restores
+// time.Now.
+func (s *Session) WithClock(clock Clock) *Session {
+	if clock == nil {
+		clock = ClockFunc(time.Now)
+	}
+	s.clock = clock
+	return s
+}
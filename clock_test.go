@@ -0,0 +1,164 @@
+package tinycert_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// fakeClock is a Clock tests move by hand.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestMemoryCache_Clock(t *testing.T) {
+	clock := newFakeClock()
+	cache := tinycert.NewMemoryCache(time.Hour).WithClock(clock)
+	key := tinycert.CacheKey{Kind: "ca", Id: 1, What: tinycert.Cert}
+
+	cache.Set(key, "pem")
+	clock.Advance(59 * time.Minute)
+	if _, ok := cache.Get(key); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+	clock.Advance(2 * time.Minute)
+	if _, ok := cache.Get(key); ok {
+		t.Error("entry outlived its TTL")
+	}
+}
+
+func TestCertProvider_Clock(t *testing.T) {
+	fs := newFakeServer(t)
+	clock := newFakeClock()
+	sess := fs.connectedSession().WithClock(clock)
+	_, certId := newCAAndCert(t, sess)
+	ctx := context.Background()
+
+	p, err := tinycert.NewCertProvider(ctx, sess, certId)
+	if err != nil {
+		t.Fatal("NewCertProvider()", err)
+	}
+
+	// The fake issues for a year; 300 days in, it is still far from expiry.
+	clock.Advance(300 * 24 * time.Hour)
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal("Refresh()", err)
+	}
+	if p.CertId() != certId {
+		t.Fatal("Refresh() reissued a certificate outside the renewal window")
+	}
+
+	clock.Advance(40 * 24 * time.Hour)
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal("Refresh()", err)
+	}
+	if p.CertId() == certId {
+		t.Error("Refresh() did not reissue a certificate within DefaultRenewBefore")
+	}
+}
+
+type auditFunc func(ctx context.Context, record tinycert.AuditRecord) error
+
+func (f auditFunc) Audit(ctx context.Context, record tinycert.AuditRecord) error {
+	return f(ctx, record)
+}
+
+func TestSession_ClockTimestamps(t *testing.T) {
+	fs := newFakeServer(t)
+	clock := &fakeClock{now: time.Date(2031, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var mu sync.Mutex
+	var stamps []time.Time
+	stamp := func(at time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		stamps = append(stamps, at)
+	}
+	sess := fs.connectedSession().WithClock(clock).
+		WithEventHandler(func(ev tinycert.Event) { stamp(ev.Time) }).
+		WithAuditSink(auditFunc(func(_ context.Context, record tinycert.AuditRecord) error {
+			stamp(record.Time)
+			return nil
+		}))
+	newCAAndCert(t, sess)
+
+	manifest, err := tinycert.Backup(context.Background(), sess, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp(manifest.CreatedAt)
+
+	if len(stamps) < 3 {
+		t.Fatalf("got %d timestamps, want events, audit records and a backup", len(stamps))
+	}
+	for _, at := range stamps {
+		if !at.Equal(clock.Now()) {
+			t.Errorf("timestamp %s, want the clock's %s", at, clock.Now())
+		}
+	}
+}
+
+func TestExporter_Clock(t *testing.T) {
+	fs := newFakeServer(t)
+	clock := newFakeClock()
+	sess := fs.connectedSession().WithClock(clock)
+	newCAAndCert(t, sess)
+
+	// The fake issues for a year; 400 days on, the certificate has expired.
+	clock.Advance(400 * 24 * time.Hour)
+	exporter := tinycert.NewExporter(sess)
+	if err := exporter.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, pattern := range []string{
+		`(?m)^tinycert_certificate_expiry_seconds\{.*cn="www.example.com".*\} -\d+$`,
+		`(?m)^tinycert_last_refresh_timestamp_seconds ` + strconv.FormatInt(clock.Now().Unix(), 10) + `$`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(body) {
+			t.Errorf("metrics do not match %s:\n%s", pattern, body)
+		}
+	}
+}
+
+func TestCertificateListItem_ExpiryAt(t *testing.T) {
+	now := time.Date(2031, 1, 2, 0, 0, 0, 0, time.UTC)
+	item := tinycert.CertificateListItem{Expires: now.Add(48 * time.Hour).Unix()}
+
+	if left := item.TimeUntilExpiryAt(now); left != 48*time.Hour {
+		t.Errorf("TimeUntilExpiryAt() = %v, want 48h", left)
+	}
+	if !item.IsExpiringWithinAt(now, 72*time.Hour) || item.IsExpiringWithinAt(now, 24*time.Hour) {
+		t.Error("IsExpiringWithinAt() disagrees with a 48h expiry")
+	}
+	if !item.IsExpiringWithinAt(now.Add(49*time.Hour), 0) {
+		t.Error("IsExpiringWithinAt() does not count an expired certificate")
+	}
+}
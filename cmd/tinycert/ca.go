@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func runCA(sess *tinycert.Session, command string, args []string) error {
+	ca := tinycert.NewCA(sess)
+	fs := flag.NewFlagSet("ca "+command, flag.ExitOnError)
+
+	switch command {
+	case "create":
+		orgName := fs.String("o", "", "organization name")
+		locality := fs.String("l", "", "locality")
+		stateCode := fs.String("st", "", "state or province code")
+		countryCode := fs.String("c", "", "two letter country code")
+		orgUnit := fs.String("ou", "", "organizational unit")
+		commonName := fs.String("cn", "", "common name")
+		email := fs.String("email", "", "contact email")
+		hashMethod := fs.String("hash", "sha256", "hash algorithm: sha256, sha384 or sha512")
+		fs.Parse(args)
+
+		hash, err := tinycert.ParseHashAlgorithm(*hashMethod)
+		if err != nil {
+			return err
+		}
+		caId, err := ca.Create(tinycert.CARequest{
+			OrgName:       *orgName,
+			OrgUnit:       *orgUnit,
+			CommonName:    *commonName,
+			Email:         *email,
+			Locality:      *locality,
+			StateCode:     *stateCode,
+			CountryCode:   *countryCode,
+			HashAlgorithm: hash,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(*caId)
+		return nil
+	case "list":
+		fs.Parse(args)
+
+		items, err := ca.List()
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			fmt.Printf("%d\t%s\n", item.Id, item.Name)
+		}
+		return nil
+	case "details":
+		caId := fs.Int64("id", 0, "ca id")
+		fs.Parse(args)
+
+		info, err := ca.Describe(*caId)
+		if err != nil {
+			return err
+		}
+		return printJSON(info)
+	case "get":
+		caId := fs.Int64("id", 0, "ca id")
+		out := fs.String("out", "", "output file (default stdout)")
+		fs.Parse(args)
+
+		pem, err := ca.Get(*caId)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*out, *pem)
+	case "delete":
+		caId := fs.Int64("id", 0, "ca id")
+		fs.Parse(args)
+
+		return ca.Delete(*caId)
+	}
+
+	return fmt.Errorf("unknown ca command %q", command)
+}
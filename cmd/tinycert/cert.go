@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/srohatgi/tinycert"
+)
+
+type stringList []string
+
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
+func parseStatuses(value string) (tinycert.CertificateStatus, error) {
+	return tinycert.ParseCertificateStatus(strings.ReplaceAll(value, ",", "|"))
+}
+
+func runCert(sess *tinycert.Session, command string, args []string) error {
+	cert := tinycert.NewCertificate(sess)
+	fs := flag.NewFlagSet("cert "+command, flag.ExitOnError)
+
+	switch command {
+	case "issue":
+		caId := fs.Int64("ca", 0, "ca id")
+		commonName := fs.String("cn", "", "common name")
+		orgUnit := fs.String("ou", "", "organizational unit")
+		orgName := fs.String("o", "", "organization name")
+		locality := fs.String("l", "", "locality")
+		stateCode := fs.String("st", "", "state or province code")
+		countryCode := fs.String("c", "", "two letter country code")
+		var dns, ips, emails, uris stringList
+		fs.Var(&dns, "dns", "DNS subject alternative name (repeatable)")
+		fs.Var(&ips, "ip", "IP subject alternative name (repeatable)")
+		fs.Var(&emails, "email", "email subject alternative name (repeatable)")
+		fs.Var(&uris, "uri", "URI subject alternative name (repeatable)")
+		fs.Parse(args)
+
+		req := tinycert.CertRequest{
+			CommonName:  *commonName,
+			OrgUnit:     *orgUnit,
+			OrgName:     *orgName,
+			Locality:    *locality,
+			StateCode:   *stateCode,
+			CountryCode: *countryCode,
+		}
+		for _, name := range dns {
+			req.Alt = append(req.Alt, tinycert.SAN{DNS: name})
+		}
+		for _, ip := range ips {
+			req.Alt = append(req.Alt, tinycert.SAN{IP: ip})
+		}
+		for _, email := range emails {
+			req.Alt = append(req.Alt, tinycert.SAN{Email: email})
+		}
+		for _, uri := range uris {
+			req.Alt = append(req.Alt, tinycert.SAN{URI: uri})
+		}
+
+		certId, err := cert.Create(*caId, req)
+		if err != nil {
+			return err
+		}
+		fmt.Println(*certId)
+		return nil
+	case "fetch":
+		certId := fs.Int64("id", 0, "certificate id")
+		what := fs.String("what", "cert", "artifact to fetch: cert, chain, csr, key.dec, key.enc or pkcs12")
+		out := fs.String("out", "", "output file (default stdout)")
+		fs.Parse(args)
+
+		artifact, err := tinycert.ParseArtifact(*what)
+		if err != nil {
+			return err
+		}
+		content, err := cert.Get(*certId, artifact)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*out, *content)
+	case "details":
+		certId := fs.Int64("id", 0, "certificate id")
+		fs.Parse(args)
+
+		info, err := cert.Describe(*certId)
+		if err != nil {
+			return err
+		}
+		return printJSON(info)
+	case "list":
+		caId := fs.Int64("ca", 0, "ca id")
+		status := fs.String("status", "good", "comma separated statuses: expired, good, revoked, hold")
+		fs.Parse(args)
+
+		what, err := parseStatuses(*status)
+		if err != nil {
+			return err
+		}
+		items, err := cert.List(*caId, what)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			fmt.Printf("%d\t%s\t%s\t%d\n", item.Id, item.Name, item.Status, item.Expires)
+		}
+		return nil
+	case "reissue":
+		certId := fs.Int64("id", 0, "certificate id")
+		fs.Parse(args)
+
+		newCertId, err := cert.Reissue(*certId)
+		if err != nil {
+			return err
+		}
+		fmt.Println(*newCertId)
+		return nil
+	case "status":
+		certId := fs.Int64("id", 0, "certificate id")
+		status := fs.String("status", "", "new status: good, revoked or hold")
+		fs.Parse(args)
+
+		s, err := tinycert.ParseCertificateStatus(*status)
+		if err != nil {
+			return err
+		}
+		return cert.Status(*certId, s)
+	case "report":
+		format := fs.String("format", "table", "output format: table, json or csv")
+		fs.Parse(args)
+
+		f, err := tinycert.ParseReportFormat(*format)
+		if err != nil {
+			return err
+		}
+		var progress tinycert.Progress
+		if isTerminal(os.Stderr) {
+			progress = tinycert.NewProgressBar(os.Stderr, "listing CAs")
+		}
+		report, err := tinycert.GenerateReportWithProgress(context.Background(), sess, progress)
+		if err != nil {
+			return err
+		}
+		return report.Write(os.Stdout, f)
+	}
+
+	return fmt.Errorf("unknown cert command %q", command)
+}
+
+// isTerminal reports whether f is a character device, so progress bars are
+// only drawn for people and not into files or pipes.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
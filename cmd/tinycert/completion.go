@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	groups          = []string{"ca", "cert", "completion", "interactive", "keyring"}
+	caCommands      = []string{"create", "list", "details", "get", "delete"}
+	certCommands    = []string{"issue", "fetch", "details", "list", "reissue", "status", "report"}
+	shells          = []string{"bash", "zsh", "fish"}
+	keyringCommands = []string{"set", "delete"}
+)
+
+// globalFlags returns the names of the global flags, with a leading dash, and
+// of those the ones taking a value.
+func globalFlags() (all, withValue []string) {
+	flag.VisitAll(func(f *flag.Flag) {
+		all = append(all, "-"+f.Name)
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !bf.IsBoolFlag() {
+			withValue = append(withValue, "-"+f.Name)
+		}
+	})
+	return
+}
+
+// writeCompletion writes the completion script for shell. The scripts
+// complete command groups, commands and global flags.
+func writeCompletion(w io.Writer, shell string) error {
+	all, withValue := globalFlags()
+	switch shell {
+	case "bash":
+		_, err := fmt.Fprintf(w, bashCompletion, strings.Join(withValue, "|"), strings.Join(append(groups, all...), " "),
+			strings.Join(caCommands, " "), strings.Join(certCommands, " "), strings.Join(shells, " "), strings.Join(keyringCommands, " "))
+		return err
+	case "zsh":
+		_, err := fmt.Fprintf(w, zshCompletion, strings.Join(withValue, "|"), strings.Join(append(groups, all...), " "),
+			strings.Join(caCommands, " "), strings.Join(certCommands, " "), strings.Join(shells, " "), strings.Join(keyringCommands, " "))
+		return err
+	case "fish":
+		var b strings.Builder
+		b.WriteString("complete -c tinycert -f\n")
+		fmt.Fprintf(&b, "complete -c tinycert -n __fish_use_subcommand -a '%s'\n", strings.Join(groups, " "))
+		flag.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&b, "complete -c tinycert -n __fish_use_subcommand -o %s -d '%s'", f.Name, strings.ReplaceAll(f.Usage, "'", `\'`))
+			if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !bf.IsBoolFlag() {
+				b.WriteString(" -r")
+			}
+			b.WriteString("\n")
+		})
+		for _, group := range []struct {
+			name     string
+			commands []string
+		}{{"ca", caCommands}, {"cert", certCommands}, {"completion", shells}, {"keyring", keyringCommands}} {
+			commands := strings.Join(group.commands, " ")
+			fmt.Fprintf(&b, "complete -c tinycert -n '__fish_seen_subcommand_from %s; and not __fish_seen_subcommand_from %s' -a '%s'\n",
+				group.name, commands, commands)
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return fmt.Errorf("unknown shell %q, expected bash, zsh or fish", shell)
+}
+
+// bashCompletion is filled in with the global flags taking a value, the
+// first words, and the ca, cert, completion and keyring commands.
+const bashCompletion = `# bash completion for tinycert; load with: source <(tinycert completion bash)
+_tinycert() {
+	local cur=${COMP_WORDS[COMP_CWORD]} words=() i
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		%[1]s) ((i++)) ;;
+		-*) ;;
+		*) words+=("${COMP_WORDS[i]}") ;;
+		esac
+	done
+	case ${#words[@]} in
+	0) COMPREPLY=($(compgen -W "%[2]s" -- "$cur")) ;;
+	1)
+		case ${words[0]} in
+		ca) COMPREPLY=($(compgen -W "%[3]s" -- "$cur")) ;;
+		cert) COMPREPLY=($(compgen -W "%[4]s" -- "$cur")) ;;
+		completion) COMPREPLY=($(compgen -W "%[5]s" -- "$cur")) ;;
+		keyring) COMPREPLY=($(compgen -W "%[6]s" -- "$cur")) ;;
+		esac
+		;;
+	esac
+}
+complete -o default -F _tinycert tinycert
+`
+
+// zshCompletion takes the same values as bashCompletion.
+const zshCompletion = `#compdef tinycert
+# zsh completion for tinycert; load with: source <(tinycert completion zsh)
+_tinycert() {
+	local -a seen
+	local i
+	for ((i = 2; i < CURRENT; i++)); do
+		case ${words[i]} in
+		%[1]s) ((i++)) ;;
+		-*) ;;
+		*) seen+=(${words[i]}) ;;
+		esac
+	done
+	case ${#seen} in
+	0) compadd -- %[2]s ;;
+	1)
+		case ${seen[1]} in
+		ca) compadd -- %[3]s ;;
+		cert) compadd -- %[4]s ;;
+		completion) compadd -- %[5]s ;;
+		keyring) compadd -- %[6]s ;;
+		*) _files ;;
+		esac
+		;;
+	*) _files ;;
+	esac
+}
+compdef _tinycert tinycert
+`
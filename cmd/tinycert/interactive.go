@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// errQuit ends an interactive session.
+var errQuit = errors.New("quit")
+
+// prompter reads answers to prompts, one per line.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prints prompt and returns the trimmed answer; q quits.
+func (p *prompter) ask(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	if !p.in.Scan() {
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", errQuit
+	}
+	answer := strings.TrimSpace(p.in.Text())
+	if answer == "q" {
+		return "", errQuit
+	}
+	return answer, nil
+}
+
+// choose asks for a number between 1 and n; b returns 0.
+func (p *prompter) choose(prompt string, n int) (int, error) {
+	for {
+		answer, err := p.ask(prompt)
+		if err != nil || answer == "b" {
+			return 0, err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= n {
+			return i, nil
+		}
+		fmt.Fprintf(p.out, "enter a number from 1 to %d, b to go back or q to quit\n", n)
+	}
+}
+
+// runInteractive lets occasional users browse their CAs, drill into their
+// certificates and fetch, hold, release or revoke them without looking up
+// ids and flags.
+func runInteractive(sess *tinycert.Session, in io.Reader, out io.Writer) error {
+	p := &prompter{in: bufio.NewScanner(in), out: out}
+	ca := tinycert.NewCA(sess)
+	for {
+		cas, err := ca.List()
+		if err != nil {
+			return err
+		}
+		if len(cas) == 0 {
+			fmt.Fprintln(out, "no CAs")
+			return nil
+		}
+		fmt.Fprintln(out, "\nCAs:")
+		for i, item := range cas {
+			fmt.Fprintf(out, "%3d) %s (%d)\n", i+1, item.Name, item.Id)
+		}
+		i, err := p.choose("CA (q to quit): ", len(cas))
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			continue
+		}
+		if err = browseCA(p, sess, cas[i-1]); err == errQuit {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func browseCA(p *prompter, sess *tinycert.Session, ca *tinycert.CAListItem) error {
+	cert := tinycert.NewCertificate(sess)
+	for {
+		items, err := cert.List(ca.Id, tinycert.AnyStatus)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Fprintf(p.out, "%s has no certificates\n", ca.Name)
+			return nil
+		}
+		fmt.Fprintf(p.out, "\nCertificates of %s:\n", ca.Name)
+		for i, item := range items {
+			fmt.Fprintf(p.out, "%3d) %-40s %-8s expires %s (%d)\n", i+1, item.Name, item.Status, item.ExpiresAt.Format("2006-01-02"), item.Id)
+		}
+		i, err := p.choose("certificate (b back, q quit): ", len(items))
+		if err != nil || i == 0 {
+			return err
+		}
+		if err = browseCertificate(p, cert, items[i-1]); err != nil {
+			return err
+		}
+	}
+}
+
+func browseCertificate(p *prompter, cert *tinycert.Certificate, item *tinycert.CertificateListItem) error {
+	for {
+		desc, err := cert.Describe(item.Id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(p.out, "\n%s (%d): %s, serial %s, expires %s\n", desc.CommonName, desc.Id, desc.Status, desc.SerialNumber, desc.NotAfter.Format("2006-01-02"))
+		for _, san := range desc.Alt {
+			switch {
+			case san.DNS != "":
+				fmt.Fprintln(p.out, "  DNS:", san.DNS)
+			case san.IP != "":
+				fmt.Fprintln(p.out, "  IP:", san.IP)
+			case san.Email != "":
+				fmt.Fprintln(p.out, "  email:", san.Email)
+			case san.URI != "":
+				fmt.Fprintln(p.out, "  URI:", san.URI)
+			}
+		}
+
+		action, err := p.ask("[f]etch, [h]old, re[l]ease, [r]evoke, [b]ack, [q]uit: ")
+		if err != nil {
+			return err
+		}
+		switch action {
+		case "b":
+			return nil
+		case "f":
+			err = fetchInteractive(p, cert, item.Id)
+		case "h":
+			err = cert.Hold(item.Id)
+		case "l":
+			err = cert.Release(item.Id)
+		case "r":
+			var answer string
+			if answer, err = p.ask(fmt.Sprintf("revoking %s cannot be undone; type yes to revoke: ", desc.CommonName)); err == nil && answer == "yes" {
+				err = cert.Revoke(item.Id)
+			}
+		default:
+			fmt.Fprintln(p.out, "unknown action")
+		}
+		if err == errQuit {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintln(p.out, "error:", err)
+		}
+	}
+}
+
+func fetchInteractive(p *prompter, cert *tinycert.Certificate, certId int64) error {
+	what, err := p.ask("artifact: cert, chain, csr, key.dec, key.enc or pkcs12 [cert]: ")
+	if err != nil {
+		return err
+	}
+	if what == "" {
+		what = "cert"
+	}
+	artifact, err := tinycert.ParseArtifact(what)
+	if err != nil {
+		return err
+	}
+	out, err := p.ask("output file [stdout]: ")
+	if err != nil {
+		return err
+	}
+	content, err := cert.GetCtx(context.Background(), certId, artifact)
+	if err != nil {
+		return err
+	}
+	return writeOutput(out, *content)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/keyring"
+	"golang.org/x/term"
+)
+
+// runKeyring stores the credentials in, or deletes them from, the OS
+// keyring entry named by the -email flag, or the default entry. Secrets are
+// read from the terminal without echo, so they stay out of shell history.
+func runKeyring(email, command string) error {
+	p := keyring.Provider{Account: email}
+
+	switch command {
+	case "set":
+		in := bufio.NewReader(os.Stdin)
+		creds := tinycert.Credentials{Email: email}
+		var err error
+		if creds.Email == "" {
+			if creds.Email, err = readLine(in, "email: ", false); err != nil {
+				return err
+			}
+		}
+		if creds.Passphrase, err = readLine(in, "passphrase: ", true); err != nil {
+			return err
+		}
+		if creds.ApiKey, err = readLine(in, "api key: ", true); err != nil {
+			return err
+		}
+		return p.Store(creds)
+	case "delete":
+		return p.Delete()
+	}
+	return fmt.Errorf("unknown keyring command %q", command)
+}
+
+// readLine prompts on stderr and reads a line from stdin, without echo if
+// secret is set and stdin is a terminal.
+func readLine(in *bufio.Reader, prompt string, secret bool) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if fd := int(os.Stdin.Fd()); secret && term.IsTerminal(fd) {
+		line, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(line)), err
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,179 @@
+// Command tinycert manages TinyCert certificate authorities and certificates
+// from the shell.
+//
+// Usage:
+//
+//	tinycert [global flags] ca create -o ORG -l LOCALITY -st STATE -c COUNTRY [-cn NAME] [-hash sha384]
+//	tinycert [global flags] ca list|details|get|delete ...
+//	tinycert [global flags] cert issue -ca ID -cn NAME [-dns NAME]...
+//	tinycert [global flags] cert fetch -id ID -what chain --out server.pem
+//	tinycert [global flags] cert list|details|reissue|status ...
+//	tinycert [global flags] cert report -format table|json|csv
+//	tinycert [global flags] interactive
+//	tinycert completion bash|zsh|fish
+//	tinycert [-email EMAIL] keyring set|delete
+//
+// The interactive mode lists the CAs to pick one, then its certificates to
+// fetch, hold, release or revoke them. The completion scripts complete
+// commands and global flags; load one with e.g.
+// source <(tinycert completion bash).
+//
+// Credentials are read, in increasing order of precedence, from the config
+// file (~/.tinycert/config.json), the TINYCERT_EMAIL, TINYCERT_PASSWORD and
+// TINYCERT_APIKEY environment variables, and the global flags. With -keyring
+// they are read from the OS keyring instead, from the entry "keyring set"
+// stored: the one for -email, or the default entry.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/keyring"
+)
+
+type config struct {
+	Email      string `json:"email"`
+	Passphrase string `json:"passphrase"`
+	ApiKey     string `json:"api_key"`
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tinycert", "config.json")
+}
+
+func loadConfig(path string, required bool) (cfg config, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(data, &cfg)
+	return
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: tinycert [global flags] <ca|cert> <command> [flags]\n")
+	fmt.Fprintf(os.Stderr, "       tinycert [global flags] interactive\n")
+	fmt.Fprintf(os.Stderr, "       tinycert completion <%s>\n", strings.Join(shells, "|"))
+	fmt.Fprintf(os.Stderr, "       tinycert [-email EMAIL] keyring <%s>\n\nglobal flags:\n", strings.Join(keyringCommands, "|"))
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nca commands: %s\n", strings.Join(caCommands, ", "))
+	fmt.Fprintf(os.Stderr, "cert commands: %s\n", strings.Join(certCommands, ", "))
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tinycert: ")
+
+	configPath := flag.String("config", "", "path to JSON config file (default ~/.tinycert/config.json)")
+	email := flag.String("email", "", "account email")
+	passphrase := flag.String("passphrase", "", "account passphrase")
+	apiKey := flag.String("apikey", "", "account API key")
+	debug := flag.Bool("debug", false, "log API calls to stderr")
+	useKeyring := flag.Bool("keyring", false, "read credentials from the OS keyring (see keyring set)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.Arg(0) == "completion" && flag.NArg() == 2 {
+		if err := writeCompletion(os.Stdout, flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "keyring" && flag.NArg() == 2 {
+		if err := runKeyring(*email, flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.NArg() < 2 && flag.Arg(0) != "interactive" {
+		usage()
+		os.Exit(2)
+	}
+
+	path, required := *configPath, true
+	if path == "" {
+		path, required = defaultConfigPath(), false
+	}
+	cfg, err := loadConfig(path, required)
+	if err != nil {
+		log.Fatalf("unable to read config %s: %v", path, err)
+	}
+
+	sess := tinycert.NewSession().WithUserAgent("tinycert-cli " + tinycert.UserAgent())
+	if cfg.Email != "" && os.Getenv("TINYCERT_EMAIL") == "" {
+		sess.WithEmail(cfg.Email)
+	}
+	if cfg.Passphrase != "" && os.Getenv("TINYCERT_PASSWORD") == "" {
+		sess.WithPassphrase(cfg.Passphrase)
+	}
+	if cfg.ApiKey != "" && os.Getenv("TINYCERT_APIKEY") == "" {
+		sess.WithApiKey(cfg.ApiKey)
+	}
+	if *email != "" {
+		sess.WithEmail(*email)
+	}
+	if *passphrase != "" {
+		sess.WithPassphrase(*passphrase)
+	}
+	if *apiKey != "" {
+		sess.WithApiKey(*apiKey)
+	}
+	if *useKeyring {
+		sess.WithCredentialProvider(keyring.Provider{Account: *email})
+	}
+	if *debug {
+		sess.WithLogger(tinycert.NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), tinycert.LevelDebug))
+		sess.WithDebug(true)
+	}
+
+	if err := sess.Connect(); err != nil {
+		log.Fatalf("unable to connect: %v", err)
+	}
+
+	group, command, args := flag.Arg(0), flag.Arg(1), flag.Args()[min(2, flag.NArg()):]
+	switch group {
+	case "interactive":
+		err = runInteractive(sess, os.Stdin, os.Stdout)
+	case "ca":
+		err = runCA(sess, command, args)
+	case "cert":
+		err = runCert(sess, command, args)
+	default:
+		err = fmt.Errorf("unknown command group %q", group)
+	}
+
+	if derr := sess.Close(); derr != nil && err == nil {
+		err = derr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeOutput(path, content string) error {
+	if path == "" || path == "-" {
+		_, err := fmt.Print(content)
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0600)
+}
@@ -0,0 +1,278 @@
+package tinycert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidConfig = errors.New("tinycert: invalid config")
+
+// Config describes an account and the CAs and certificates it should have,
+// for managing certificates as code: LoadConfig reads it, Plan shows what
+// it takes to make TinyCert match it and Apply does so. In YAML:
+//
+//	account:
+//	  credentials_file: credentials.yaml
+//	renew_before: 720h
+//	cas:
+//	  - name: internal
+//	    org: acme
+//	    locality: San Jose
+//	    state: CA
+//	    country: US
+//	    prune: true
+//	    output: certs/ca.pem
+//	    certificates:
+//	      - common_name: www.example.com
+//	        sans: [www.example.com, example.com, "IP:10.0.0.1"]
+//	        output:
+//	          cert: certs/www.pem
+//	          chain: certs/www-chain.pem
+//	          key: certs/www-key.pem
+//
+// TOML files use the same keys, with [[cas]] and [[cas.certificates]]
+// tables.
+type Config struct {
+	Account AccountConfig `yaml:"account" toml:"account"`
+	// RenewBefore, e.g. "720h", reissues certificates that expire within it.
+	RenewBefore time.Duration `yaml:"renew_before" toml:"renew_before"`
+	CAs         []CAConfig    `yaml:"cas" toml:"cas"`
+}
+
+// AccountConfig says how to reach the TinyCert account. Secrets do not
+// belong in the config file: they are read from CredentialsFile, in the
+// format of FileProvider, or else from the environment as by NewSession.
+type AccountConfig struct {
+	Email           string `yaml:"email" toml:"email"`
+	CredentialsFile string `yaml:"credentials_file" toml:"credentials_file"`
+	BaseURL         string `yaml:"base_url" toml:"base_url"`
+}
+
+// CAConfig describes a CA. An existing CA with the same subject is used as
+// is; see CA.Ensure. Name only labels the CA in plans and reports.
+type CAConfig struct {
+	Name string `yaml:"name" toml:"name"`
+	// Prune revokes the CA's certificates that are not in the config.
+	Prune         bool   `yaml:"prune" toml:"prune"`
+	OrgName       string `yaml:"org" toml:"org"`
+	OrgUnit       string `yaml:"org_unit" toml:"org_unit"`
+	CommonName    string `yaml:"common_name" toml:"common_name"`
+	Email         string `yaml:"email" toml:"email"`
+	Locality      string `yaml:"locality" toml:"locality"`
+	StateCode     string `yaml:"state" toml:"state"`
+	CountryCode   string `yaml:"country" toml:"country"`
+	HashAlgorithm string `yaml:"hash_algorithm" toml:"hash_algorithm"`
+	// Output, if set, is where the CA certificate is written.
+	Output       string       `yaml:"output" toml:"output"`
+	Certificates []CertConfig `yaml:"certificates" toml:"certificates"`
+}
+
+// CertConfig describes a certificate under a CA. Subject fields left empty
+// are taken from the CA. SANs are written like "DNS:www.example.com",
+// "IP:10.0.0.1", "email:ops@example.com" or "URI:spiffe://example/web"; a
+// name without a prefix is taken as an IP address, email address or URI if
+// it looks like one and as a DNS name otherwise.
+type CertConfig struct {
+	CommonName  string       `yaml:"common_name" toml:"common_name"`
+	SANs        []string     `yaml:"sans" toml:"sans"`
+	OrgName     string       `yaml:"org" toml:"org"`
+	OrgUnit     string       `yaml:"org_unit" toml:"org_unit"`
+	Locality    string       `yaml:"locality" toml:"locality"`
+	StateCode   string       `yaml:"state" toml:"state"`
+	CountryCode string       `yaml:"country" toml:"country"`
+	Output      OutputConfig `yaml:"output" toml:"output"`
+}
+
+// OutputConfig names the files a certificate is written to; empty paths are
+// skipped.
+type OutputConfig struct {
+	Cert  string `yaml:"cert" toml:"cert"`
+	Chain string `yaml:"chain" toml:"chain"`
+	Key   string `yaml:"key" toml:"key"`
+}
+
+// LoadConfig reads a config from a YAML (.yaml, .yml) or TOML (.toml) file
+// and validates it. Unknown keys are rejected, to catch typos. Relative
+// paths in the file are resolved against the file's directory.
+func LoadConfig(path string) (config *Config, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	config = &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(config); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".toml":
+		var md toml.MetaData
+		if md, err = toml.Decode(string(data), config); err == nil {
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				err = fmt.Errorf("unknown key %s", undecoded[0])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s: unknown format, expected .yaml, .yml or .toml", ErrInvalidConfig, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+
+	config.resolvePaths(filepath.Dir(path))
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (c *Config) resolvePaths(dir string) {
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	resolve(&c.Account.CredentialsFile)
+	for i := range c.CAs {
+		ca := &c.CAs[i]
+		resolve(&ca.Output)
+		for j := range ca.Certificates {
+			out := &ca.Certificates[j].Output
+			resolve(&out.Cert)
+			resolve(&out.Chain)
+			resolve(&out.Key)
+		}
+	}
+}
+
+// Validate reports every problem with the config in one ErrInvalidConfig
+// error, before any call is made to the API.
+func (c *Config) Validate() error {
+	var problems []string
+	for i, ca := range c.CAs {
+		label := ca.label(i)
+		req, err := ca.request()
+		if err == nil {
+			err = req.Validate()
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ca %s: %v", label, err))
+		}
+		for _, cert := range ca.Certificates {
+			req, err := cert.request(ca)
+			if err == nil {
+				err = req.Validate()
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("ca %s: certificate %q: %v", label, cert.CommonName, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Session returns a session for the account.
+func (c *Config) Session() *Session {
+	s := NewSession()
+	if c.Account.Email != "" {
+		s.WithEmail(c.Account.Email)
+	}
+	if c.Account.BaseURL != "" {
+		s.WithBaseURL(c.Account.BaseURL)
+	}
+	if c.Account.CredentialsFile != "" {
+		s.WithCredentialProvider(FileProvider{Path: c.Account.CredentialsFile})
+	}
+	return s
+}
+
+func (ca CAConfig) label(i int) string {
+	if ca.Name != "" {
+		return ca.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+func (ca CAConfig) request() (req CARequest, err error) {
+	req = CARequest{
+		OrgName:     ca.OrgName,
+		OrgUnit:     ca.OrgUnit,
+		CommonName:  ca.CommonName,
+		Email:       ca.Email,
+		Locality:    ca.Locality,
+		StateCode:   ca.StateCode,
+		CountryCode: ca.CountryCode,
+	}
+	if ca.HashAlgorithm != "" {
+		req.HashAlgorithm, err = ParseHashAlgorithm(ca.HashAlgorithm)
+	}
+	return
+}
+
+func (cert CertConfig) request(ca CAConfig) (req CertRequest, err error) {
+	req = CertRequest{
+		CommonName:  cert.CommonName,
+		OrgName:     orDefault(cert.OrgName, ca.OrgName),
+		OrgUnit:     orDefault(cert.OrgUnit, ca.OrgUnit),
+		Locality:    orDefault(cert.Locality, ca.Locality),
+		StateCode:   orDefault(cert.StateCode, ca.StateCode),
+		CountryCode: orDefault(cert.CountryCode, ca.CountryCode),
+	}
+	for _, name := range cert.SANs {
+		var san SAN
+		if san, err = parseSAN(name); err != nil {
+			return
+		}
+		req.Alt = append(req.Alt, san)
+	}
+	return
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func parseSAN(name string) (san SAN, err error) {
+	if kind, value, ok := strings.Cut(name, ":"); ok {
+		switch strings.ToLower(kind) {
+		case "dns":
+			return SAN{DNS: value}, nil
+		case "ip":
+			return SAN{IP: value}, nil
+		case "email":
+			return SAN{Email: value}, nil
+		case "uri":
+			return SAN{URI: value}, nil
+		}
+	}
+	switch {
+	case net.ParseIP(name) != nil:
+		return SAN{IP: name}, nil
+	case strings.Contains(name, "://"):
+		return SAN{URI: name}, nil
+	case strings.Contains(name, "@"):
+		return SAN{Email: name}, nil
+	case strings.Contains(name, ":"):
+		return san, fmt.Errorf("unknown SAN type in %q", name)
+	}
+	return SAN{DNS: name}, nil
+}
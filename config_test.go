@@ -0,0 +1,146 @@
+package tinycert_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+const yamlConfig = `
+account:
+  credentials_file: credentials.yaml
+renew_before: 720h
+cas:
+  - name: internal
+    org: acme
+    locality: sj
+    state: CA
+    country: US
+    output: certs/ca.pem
+    certificates:
+      - common_name: www.example.com
+        sans: [www.example.com, "IP:10.0.0.1", ops@example.com]
+        output:
+          cert: certs/www.pem
+          key: certs/www-key.pem
+`
+
+const tomlConfig = `
+renew_before = "720h"
+
+[account]
+credentials_file = "credentials.yaml"
+
+[[cas]]
+name = "internal"
+org = "acme"
+locality = "sj"
+state = "CA"
+country = "US"
+
+[[cas.certificates]]
+common_name = "www.example.com"
+sans = ["www.example.com", "IP:10.0.0.1", "ops@example.com"]
+output = { cert = "certs/www.pem" }
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	for name, content := range map[string]string{"certs.yaml": yamlConfig, "certs.toml": tomlConfig} {
+		path := writeConfig(t, name, content)
+		config, err := tinycert.LoadConfig(path)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		dir := filepath.Dir(path)
+		if got := config.Account.CredentialsFile; got != filepath.Join(dir, "credentials.yaml") {
+			t.Errorf("%s: credentials file %q not resolved against the config", name, got)
+		}
+		if config.RenewBefore != 720*time.Hour {
+			t.Errorf("%s: renew before = %s", name, config.RenewBefore)
+		}
+		if len(config.CAs) != 1 || len(config.CAs[0].Certificates) != 1 {
+			t.Fatalf("%s: config = %+v", name, config)
+		}
+		cert := config.CAs[0].Certificates[0]
+		if cert.CommonName != "www.example.com" || len(cert.SANs) != 3 || cert.Output.Cert != filepath.Join(dir, "certs/www.pem") {
+			t.Errorf("%s: certificate = %+v", name, cert)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"typo.yaml":  "cas:\n  - orgname: acme\n",
+		"typo.toml":  "[[cas]]\norgname = \"acme\"\n",
+		"certs.json": "{}",
+	} {
+		_, err := tinycert.LoadConfig(writeConfig(t, name, content))
+		if !errors.Is(err, tinycert.ErrInvalidConfig) {
+			t.Errorf("%s: err = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+	_, err := tinycert.LoadConfig(writeConfig(t, "invalid.yaml", "cas:\n  - org: acme\n    country: USA\n    certificates:\n      - sans: [\"x400:foo\"]\n"))
+	for _, want := range []string{"locality is required", "country code", "unknown SAN type"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestConfig_Apply(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	path := writeConfig(t, "certs.yaml", yamlConfig)
+	config, err := tinycert.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := config.Apply(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || !applied[0].Created || len(applied[0].Certificates) != 1 || !applied[0].Certificates[0].Created {
+		t.Fatalf("applied = %+v", applied)
+	}
+
+	dir := filepath.Dir(path)
+	for _, name := range []string{"ca.pem", "www.pem", "www-key.pem"} {
+		if _, err := os.Stat(filepath.Join(dir, "certs", name)); err != nil {
+			t.Error(err)
+		}
+	}
+	info, err := tinycert.NewCertificate(sess).Details(applied[0].Certificates[0].CertId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tinycert.SAN{{DNS: "www.example.com"}, {IP: "10.0.0.1"}, {Email: "ops@example.com"}}
+	if len(info.Alt) != len(want) || info.OrgName != "acme" || info.Locality != "sj" {
+		t.Errorf("certificate has SANs %+v and subject O=%s L=%s", info.Alt, info.OrgName, info.Locality)
+	}
+
+	// Applying again changes nothing.
+	again, err := config.Apply(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].Created || again[0].CAId != applied[0].CAId || again[0].Certificates[0].Created || again[0].Certificates[0].CertId != applied[0].Certificates[0].CertId {
+		t.Errorf("second apply = %+v", again)
+	}
+	if got := fs.callCount("cert/new"); got != 1 {
+		t.Errorf("cert/new called %d times, want 1", got)
+	}
+}
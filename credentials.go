@@ -0,0 +1,259 @@
+package tinycert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNoCredentials = errors.New("tinycert: no credentials")
+	// ErrMissingCredentials is returned by Connect and ValidateConfig when a
+	// session lacks settings it needs to connect.
+	ErrMissingCredentials = errors.New("tinycert: missing credentials")
+)
+
+// Credentials are the secrets needed to connect to TinyCert.
+type Credentials struct {
+	Email      string `json:"email" yaml:"email"`
+	Passphrase string `json:"passphrase" yaml:"passphrase"`
+	ApiKey     string `json:"api_key" yaml:"api_key"`
+}
+
+// CredentialProvider supplies credentials when a session connects, so they
+// can be rotated without rebuilding the session.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a function to the CredentialProvider
+// interface.
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// WithCredentialProvider fetches credentials from p on every Connect. Values
+// it returns replace those set with WithEmail, WithPassphrase and WithApiKey;
+// empty values leave them unchanged.
+func (s *Session) WithCredentialProvider(p CredentialProvider) *Session {
+	s.credentials = p
+	return s
+}
+
+func (s *Session) loadCredentials(ctx context.Context) error {
+	if s.credentials == nil {
+		return nil
+	}
+
+	creds, err := s.credentials.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if creds.Email != "" {
+		s.email = creds.Email
+	}
+	if creds.Passphrase != "" {
+		s.passphrase = creds.Passphrase
+	}
+	if creds.ApiKey != "" {
+		s.apiKey = creds.ApiKey
+	}
+	return nil
+}
+
+// ValidateConfig checks the session's settings without calling the API: the
+// base URL and API version must be valid and, unless a CredentialProvider
+// supplies them at Connect, the email, passphrase and API key must be set.
+// Missing settings are reported with ErrMissingCredentials, naming the
+// environment variables NewSession reads them from.
+func (s *Session) ValidateConfig() error {
+	if s.configErr != nil {
+		return s.configErr
+	}
+	if s.credentials != nil {
+		return nil
+	}
+	return s.missingCredentials()
+}
+
+func (s *Session) missingCredentials() error {
+	email, passphrase, apiKey := s.secrets()
+
+	var missing []string
+	for _, setting := range []struct{ value, name string }{
+		{email, "email (TINYCERT_EMAIL)"},
+		{passphrase, "passphrase (TINYCERT_PASSWORD)"},
+		{apiKey, "api key (TINYCERT_APIKEY)"},
+	} {
+		if setting.value == "" {
+			missing = append(missing, setting.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s not set", ErrMissingCredentials, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (c Credentials) complete() error {
+	var missing []string
+	if c.Email == "" {
+		missing = append(missing, "email")
+	}
+	if c.Passphrase == "" {
+		missing = append(missing, "passphrase")
+	}
+	if c.ApiKey == "" {
+		missing = append(missing, "api_key")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrNoCredentials, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// EnvProvider reads TINYCERT_EMAIL, TINYCERT_PASSWORD and TINYCERT_APIKEY.
+type EnvProvider struct{}
+
+func (EnvProvider) Credentials(context.Context) (Credentials, error) {
+	creds := Credentials{
+		Email:      os.Getenv("TINYCERT_EMAIL"),
+		Passphrase: os.Getenv("TINYCERT_PASSWORD"),
+		ApiKey:     os.Getenv("TINYCERT_APIKEY"),
+	}
+	return creds, creds.complete()
+}
+
+// FileProvider reads credentials from a JSON or YAML file with email,
+// passphrase and api_key keys. Files ending in .yaml or .yml are parsed as
+// YAML, anything else as JSON. An empty Path means ~/.tinycert/credentials.
+type FileProvider struct {
+	Path string
+}
+
+func (p FileProvider) Credentials(context.Context) (creds Credentials, err error) {
+	path := p.Path
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return creds, err
+		}
+		path = filepath.Join(home, ".tinycert", "credentials")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &creds)
+	default:
+		err = json.Unmarshal(data, &creds)
+	}
+	if err != nil {
+		return creds, fmt.Errorf("tinycert: unable to parse %s: %w", path, err)
+	}
+	return creds, creds.complete()
+}
+
+// AWSSecretsManagerProvider reads credentials stored as a JSON secret string
+// in AWS Secrets Manager. The package does not depend on the AWS SDK;
+// GetSecretValue should wrap the SDK client, e.g.
+//
+//	func(ctx context.Context, id string) (string, error) {
+//		out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &id})
+//		if err != nil {
+//			return "", err
+//		}
+//		return *out.SecretString, nil
+//	}
+type AWSSecretsManagerProvider struct {
+	SecretId       string
+	GetSecretValue func(ctx context.Context, secretId string) (string, error)
+}
+
+func (p AWSSecretsManagerProvider) Credentials(ctx context.Context) (creds Credentials, err error) {
+	secret, err := p.GetSecretValue(ctx, p.SecretId)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal([]byte(secret), &creds); err != nil {
+		return creds, fmt.Errorf("tinycert: unable to parse secret %s: %w", p.SecretId, err)
+	}
+	return creds, creds.complete()
+}
+
+// VaultProvider reads credentials from a HashiCorp Vault KV secret. Path is
+// the API path below /v1/, e.g. "secret/data/tinycert" for a KV version 2
+// mount. Address and Token default to VAULT_ADDR and VAULT_TOKEN.
+type VaultProvider struct {
+	Address string
+	Token   string
+	Path    string
+	Client  *http.Client
+}
+
+func (p VaultProvider) Credentials(ctx context.Context) (creds Credentials, err error) {
+	addr, token, clt := p.Address, p.Token, p.Client
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if clt == nil {
+		clt = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(p.Path, "/"), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := clt.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		return creds, fmt.Errorf("tinycert: vault returned %d for %s", resp.StatusCode, p.Path)
+	}
+
+	// KV version 2 nests the secret in data.data, version 1 in data.
+	var secret struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(body, &secret); err != nil {
+		return creds, fmt.Errorf("tinycert: unable to parse vault secret %s: %w", p.Path, err)
+	}
+	var kv2 struct {
+		Data *Credentials `json:"data"`
+	}
+	if err = json.Unmarshal(secret.Data, &kv2); err == nil && kv2.Data != nil {
+		creds = *kv2.Data
+	} else if err = json.Unmarshal(secret.Data, &creds); err != nil {
+		return creds, fmt.Errorf("tinycert: unable to parse vault secret %s: %w", p.Path, err)
+	}
+	return creds, creds.complete()
+}
@@ -0,0 +1,175 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+var fakeCredentials = tinycert.Credentials{Email: fakeEmail, Passphrase: fakePassphrase, ApiKey: fakeAPIKey}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("TINYCERT_EMAIL", fakeEmail)
+	t.Setenv("TINYCERT_PASSWORD", fakePassphrase)
+	t.Setenv("TINYCERT_APIKEY", "")
+
+	if _, err := (tinycert.EnvProvider{}).Credentials(context.Background()); !errors.Is(err, tinycert.ErrNoCredentials) {
+		t.Error("expected ErrNoCredentials without api key, got", err)
+	}
+
+	t.Setenv("TINYCERT_APIKEY", fakeAPIKey)
+	creds, err := tinycert.EnvProvider{}.Credentials(context.Background())
+	if err != nil || creds != fakeCredentials {
+		t.Errorf("credentials = %+v, %v", creds, err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"credentials":      `{"email":"user@example.com","passphrase":"secret","api_key":"apikey"}`,
+		"credentials.yaml": "email: user@example.com\npassphrase: secret\napi_key: apikey\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		creds, err := tinycert.FileProvider{Path: path}.Credentials(context.Background())
+		if err != nil || creds != fakeCredentials {
+			t.Errorf("%s: credentials = %+v, %v", name, creds, err)
+		}
+	}
+
+	t.Setenv("HOME", dir)
+	if _, err := (tinycert.FileProvider{}).Credentials(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected missing default file, got", err)
+	}
+}
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	p := tinycert.AWSSecretsManagerProvider{
+		SecretId: "prod/tinycert",
+		GetSecretValue: func(ctx context.Context, secretId string) (string, error) {
+			if secretId != "prod/tinycert" {
+				return "", errors.New("unknown secret")
+			}
+			return `{"email":"user@example.com","passphrase":"secret","api_key":"apikey"}`, nil
+		},
+	}
+
+	creds, err := p.Credentials(context.Background())
+	if err != nil || creds != fakeCredentials {
+		t.Errorf("credentials = %+v, %v", creds, err)
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/tinycert":
+			w.Write([]byte(`{"data":{"data":{"email":"user@example.com","passphrase":"secret","api_key":"apikey"},"metadata":{"version":3}}}`))
+		case "/v1/kv/tinycert":
+			w.Write([]byte(`{"data":{"email":"user@example.com","passphrase":"secret","api_key":"apikey"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	for _, path := range []string{"secret/data/tinycert", "kv/tinycert"} {
+		creds, err := tinycert.VaultProvider{Address: vault.URL, Token: "root", Path: path}.Credentials(context.Background())
+		if err != nil || creds != fakeCredentials {
+			t.Errorf("%s: credentials = %+v, %v", path, creds, err)
+		}
+	}
+
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "wrong")
+	if _, err := (tinycert.VaultProvider{Path: "kv/tinycert"}).Credentials(context.Background()); err == nil {
+		t.Error("expected error for rejected vault token")
+	}
+}
+
+func TestSession_WithCredentialProvider(t *testing.T) {
+	fs := newFakeServer(t)
+
+	calls := 0
+	sess := tinycert.NewSession().
+		WithEmail("stale@example.com").
+		WithBaseURL(fs.URL + "/api").
+		WithCredentialProvider(tinycert.CredentialProviderFunc(func(ctx context.Context) (tinycert.Credentials, error) {
+			calls++
+			return fakeCredentials, nil
+		}))
+
+	if err := sess.Connect(); err != nil {
+		t.Fatal("unable to connect with provided credentials", err)
+	}
+	if _, err := tinycert.NewCA(sess).List(); err != nil {
+		t.Error("unable to list cas", err)
+	}
+	if calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+
+	failing := tinycert.NewSession().WithBaseURL(fs.URL + "/api").
+		WithCredentialProvider(tinycert.FileProvider{Path: filepath.Join(t.TempDir(), "missing.json")})
+	if err := failing.Connect(); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected provider error from connect, got", err)
+	}
+}
+
+func TestSession_MissingCredentials(t *testing.T) {
+	t.Setenv("TINYCERT_EMAIL", fakeEmail)
+	t.Setenv("TINYCERT_PASSWORD", "")
+	t.Setenv("TINYCERT_APIKEY", "")
+
+	fs := newFakeServer(t)
+	sess := tinycert.NewSession().WithBaseURL(fs.URL + "/api")
+
+	err := sess.ValidateConfig()
+	if !errors.Is(err, tinycert.ErrMissingCredentials) {
+		t.Fatalf("ValidateConfig() = %v, want ErrMissingCredentials", err)
+	}
+	if want := "tinycert: missing credentials: passphrase (TINYCERT_PASSWORD), api key (TINYCERT_APIKEY) not set"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if err := sess.Connect(); !errors.Is(err, tinycert.ErrMissingCredentials) {
+		t.Errorf("Connect() = %v, want ErrMissingCredentials", err)
+	}
+	if got := fs.callCount("connect"); got != 0 {
+		t.Errorf("connect called %d times, want 0", got)
+	}
+
+	sess.WithPassphrase(fakePassphrase).WithApiKey(fakeAPIKey)
+	if err := sess.ValidateConfig(); err != nil {
+		t.Errorf("ValidateConfig() = %v", err)
+	}
+	if err := tinycert.NewSession().WithBaseURL("ftp://x").ValidateConfig(); !errors.Is(err, tinycert.ErrInvalidBaseURL) {
+		t.Errorf("ValidateConfig() = %v, want ErrInvalidBaseURL", err)
+	}
+
+	// A provider supplies credentials at Connect, so they are checked then.
+	provided := tinycert.NewSession().WithBaseURL(fs.URL + "/api").WithEmail("").
+		WithCredentialProvider(tinycert.CredentialProviderFunc(func(context.Context) (tinycert.Credentials, error) {
+			return tinycert.Credentials{Email: fakeEmail, Passphrase: fakePassphrase}, nil
+		}))
+	if err := provided.ValidateConfig(); err != nil {
+		t.Errorf("ValidateConfig() with a provider = %v", err)
+	}
+	if err := provided.Connect(); !errors.Is(err, tinycert.ErrMissingCredentials) {
+		t.Errorf("Connect() = %v, want ErrMissingCredentials for the api key", err)
+	}
+}
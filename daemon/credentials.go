@@ -0,0 +1,79 @@
+// Package daemon runs renewal daemons built on tinycert as systemd services:
+// it reads the account credentials that systemd passes with LoadCredential=
+// and reports readiness and watchdog keep-alives with sd_notify.
+//
+// A unit for such a daemon might look like:
+//
+//	[Service]
+//	Type=notify
+//	ExecStart=/usr/local/bin/renewer
+//	LoadCredential=tinycert:/etc/tinycert/credentials.json
+//	WatchdogSec=60
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// ErrNoCredentialsDirectory is returned when the process was not started by
+// systemd with credentials.
+var ErrNoCredentialsDirectory = errors.New("daemon: CREDENTIALS_DIRECTORY not set")
+
+// CredentialProvider reads TinyCert credentials from the directory systemd
+// names in $CREDENTIALS_DIRECTORY. The credential called Name, "tinycert" by
+// default, holds all three settings as JSON or YAML, in the format of
+// tinycert.FileProvider. Without it, the credentials Name-email,
+// Name-passphrase and Name-apikey each hold one setting, as written by
+// systemd-creds encrypt.
+type CredentialProvider struct {
+	Name string
+}
+
+var _ tinycert.CredentialProvider = CredentialProvider{}
+
+func (p CredentialProvider) Credentials(ctx context.Context) (creds tinycert.Credentials, err error) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return creds, ErrNoCredentialsDirectory
+	}
+	name := p.Name
+	if name == "" {
+		name = "tinycert"
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err = os.Stat(path); err == nil {
+		return tinycert.FileProvider{Path: path}.Credentials(ctx)
+	}
+
+	var missing []string
+	for _, setting := range []struct {
+		suffix string
+		value  *string
+	}{
+		{"email", &creds.Email},
+		{"passphrase", &creds.Passphrase},
+		{"apikey", &creds.ApiKey},
+	} {
+		data, err := os.ReadFile(path + "-" + setting.suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, name+"-"+setting.suffix)
+			continue
+		}
+		if err != nil {
+			return creds, err
+		}
+		*setting.value = strings.TrimSpace(string(data))
+	}
+	if len(missing) > 0 {
+		return creds, fmt.Errorf("%w: no credential %s in %s", tinycert.ErrNoCredentials, strings.Join(missing, ", "), dir)
+	}
+	return creds, nil
+}
@@ -0,0 +1,39 @@
+package daemon_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/daemon"
+)
+
+func TestCredentialProvider(t *testing.T) {
+	want := tinycert.Credentials{Email: "user@example.com", Passphrase: "secret", ApiKey: "apikey"}
+
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	if _, err := (daemon.CredentialProvider{}).Credentials(context.Background()); !errors.Is(err, daemon.ErrNoCredentialsDirectory) {
+		t.Errorf("err = %v, want ErrNoCredentialsDirectory", err)
+	}
+
+	dir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	os.WriteFile(filepath.Join(dir, "tinycert"), []byte(`{"email":"user@example.com","passphrase":"secret","api_key":"apikey"}`), 0600)
+	if creds, err := (daemon.CredentialProvider{}).Credentials(context.Background()); err != nil || creds != want {
+		t.Errorf("credentials = %+v, %v", creds, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "acme-email"), []byte("user@example.com\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "acme-passphrase"), []byte("secret"), 0600)
+	provider := daemon.CredentialProvider{Name: "acme"}
+	if _, err := provider.Credentials(context.Background()); !errors.Is(err, tinycert.ErrNoCredentials) {
+		t.Errorf("err = %v, want ErrNoCredentials for the missing api key", err)
+	}
+	os.WriteFile(filepath.Join(dir, "acme-apikey"), []byte("apikey\n"), 0600)
+	if creds, err := provider.Credentials(context.Background()); err != nil || creds != want {
+		t.Errorf("credentials = %+v, %v", creds, err)
+	}
+}
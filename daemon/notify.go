@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends state, such as "READY=1", to the service manager over
+// $NOTIFY_SOCKET. It reports whether the state was sent; without a socket,
+// e.g. when not run by systemd, it does nothing and returns false.
+func Notify(state string) (sent bool, err error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Ready tells systemd the daemon has started, for units of Type=notify.
+func Ready() error {
+	_, err := Notify("READY=1")
+	return err
+}
+
+// Stopping tells systemd the daemon is shutting down.
+func Stopping() error {
+	_, err := Notify("STOPPING=1")
+	return err
+}
+
+// Status sets the one-line status systemctl status shows for the unit.
+func Status(status string) error {
+	_, err := Notify("STATUS=" + status)
+	return err
+}
+
+// WatchdogInterval returns the WatchdogSec= of the unit, or 0 if the watchdog
+// is not enabled for this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Watchdog pings the systemd watchdog at half the unit's interval until ctx
+// is done, as long as healthy, if set, reports no error; an unhealthy daemon
+// stops pinging and is restarted by systemd. It returns at once when the
+// watchdog is not enabled.
+func Watchdog(ctx context.Context, healthy func() error) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		if healthy == nil || healthy() == nil {
+			Notify("WATCHDOG=1")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
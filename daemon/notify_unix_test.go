@@ -0,0 +1,85 @@
+//go:build unix
+
+package daemon_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert/daemon"
+)
+
+func listen(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip("unixgram sockets unavailable:", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := daemon.Notify("READY=1"); sent || err != nil {
+		t.Errorf("Notify without a socket = %v, %v", sent, err)
+	}
+
+	conn := listen(t)
+	if err := daemon.Ready(); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, conn); got != "READY=1" {
+		t.Errorf("got %q", got)
+	}
+	daemon.Status("renewed 3 certificates")
+	if got := receive(t, conn); got != "STATUS=renewed 3 certificates" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	conn := listen(t)
+
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "1")
+	if daemon.WatchdogInterval() != 0 {
+		t.Error("watchdog enabled for another process")
+	}
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if got := daemon.WatchdogInterval(); got != 20*time.Millisecond {
+		t.Errorf("interval = %s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		daemon.Watchdog(ctx, nil)
+		close(done)
+	}()
+	for i := 0; i < 2; i++ {
+		if got := receive(t, conn); got != "WATCHDOG=1" {
+			t.Errorf("got %q", got)
+		}
+	}
+	cancel()
+	<-done
+}
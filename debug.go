@@ -0,0 +1,81 @@
+package tinycert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// RequestIDHeader carries the client-generated ID attached to every request,
+// so client logs can be correlated with proxy or server logs.
+const RequestIDHeader = "X-Request-Id"
+
+// WithDebug dumps every request and response to the session's logger at
+// LevelDebug. Passphrases and tokens are redacted from the dumps.
+func (s *Session) WithDebug(debug bool) *Session {
+	s.debug = debug
+	return s
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s *Session) dumpRequest(req *http.Request, vals string) {
+	dump, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		s.logger.Log(LevelDebug, "unable to dump request %s: %v", req.Header.Get(RequestIDHeader), err)
+		return
+	}
+	s.logger.Log(LevelDebug, "request %s:\n%s%s", req.Header.Get(RequestIDHeader), dump, redactForm(vals))
+}
+
+func (s *Session) dumpResponse(id string, resp *http.Response, body []byte) {
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		s.logger.Log(LevelDebug, "unable to dump response %s: %v", id, err)
+		return
+	}
+	s.logger.Log(LevelDebug, "response %s:\n%s%s", id, dump, redactJSON(body))
+}
+
+func redactForm(vals string) string {
+	pairs := strings.Split(vals, "&")
+	for i, pair := range pairs {
+		field, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(field); err != nil || sensitiveFields[name] {
+			pairs[i] = field + "=REDACTED"
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func redactJSON(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+
+	redacted := false
+	for field := range fields {
+		if sensitiveFields[field] {
+			fields[field] = json.RawMessage(`"REDACTED"`)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return []byte("REDACTED")
+	}
+	return out
+}
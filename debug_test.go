@@ -0,0 +1,56 @@
+package tinycert_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_Debug(t *testing.T) {
+	fs := newFakeServer(t)
+
+	var (
+		mu   sync.Mutex
+		logs []string
+		ids  []string
+	)
+	sess := fs.session().
+		WithDebug(true).
+		WithLogger(tinycert.LoggerFunc(func(format string, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			logs = append(logs, fmt.Sprintf(format, args...))
+		})).
+		WithInterceptor(func(req *http.Request, next tinycert.RoundTripFunc) (*http.Response, error) {
+			ids = append(ids, req.Header.Get(tinycert.RequestIDHeader))
+			return next(req)
+		})
+
+	if err := sess.Connect(); err != nil {
+		t.Fatal("unable to connect", err)
+	}
+	if _, err := tinycert.NewCA(sess).List(); err != nil {
+		t.Fatal("unable to list cas", err)
+	}
+
+	if len(ids) != 2 || ids[0] == "" || ids[0] == ids[1] {
+		t.Fatalf("request ids = %q", ids)
+	}
+
+	all := strings.Join(logs, "\n")
+	for _, id := range ids {
+		if !strings.Contains(all, "request "+id) || !strings.Contains(all, "response "+id) {
+			t.Errorf("no dump for request %s", id)
+		}
+	}
+	if !strings.Contains(all, "/connect HTTP/1.1") || !strings.Contains(all, "200 OK") {
+		t.Error("dumps missing request or status line:\n", all)
+	}
+	if strings.Contains(all, "passphrase="+fakePassphrase) || strings.Contains(all, "token-") {
+		t.Error("dumps leaked credentials:\n", all)
+	}
+}
@@ -0,0 +1,156 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertificateDescription is the details of a certificate together with
+// metadata parsed from the certificate itself, for inventory tooling.
+// Fingerprints are colon-separated upper-case hex, as openssl prints them,
+// and the serial number is upper-case hex.
+type CertificateDescription struct {
+	CertificateInfo
+	SerialNumber      string    `json:"serial_number"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+	KeyAlgorithm      string    `json:"key_algorithm"`
+	KeySize           int       `json:"key_size"`
+	SHA1Fingerprint   string    `json:"sha1_fingerprint"`
+	SHA256Fingerprint string    `json:"sha256_fingerprint"`
+}
+
+func (c *Certificate) Describe(certId int64) (desc *CertificateDescription, err error) {
+	return c.DescribeCtx(context.Background(), certId)
+}
+
+// DescribeCtx fetches the details and the certificate and describes them.
+func (c *Certificate) DescribeCtx(ctx context.Context, certId int64) (desc *CertificateDescription, err error) {
+	info, err := c.DetailsCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+	certPem, err := c.GetCtx(ctx, certId, Cert)
+	if err != nil {
+		return
+	}
+	cert, err := parseCertificatePEM([]byte(*certPem))
+	if err != nil {
+		return
+	}
+	return describe(info, cert), nil
+}
+
+func describe(info *CertificateInfo, cert *x509.Certificate) *CertificateDescription {
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	return &CertificateDescription{
+		CertificateInfo:   *info,
+		SerialNumber:      strings.ToUpper(cert.SerialNumber.Text(16)),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		KeyAlgorithm:      cert.PublicKeyAlgorithm.String(),
+		KeySize:           keySize(cert.PublicKey),
+		SHA1Fingerprint:   fingerprint(sha1Sum[:]),
+		SHA256Fingerprint: fingerprint(sha256Sum[:]),
+	}
+}
+
+// CADescription is the details of a CA together with its parsed certificate,
+// for planning root rotation. SubjectKeyId is formatted like a fingerprint.
+type CADescription struct {
+	CAInfo
+	Certificate       *x509.Certificate `json:"-"`
+	NotBefore         time.Time         `json:"not_before"`
+	NotAfter          time.Time         `json:"not_after"`
+	KeyUsage          []string          `json:"key_usage"`
+	SubjectKeyId      string            `json:"subject_key_id"`
+	SHA256Fingerprint string            `json:"sha256_fingerprint"`
+}
+
+func (ca *CA) Describe(caId int64) (desc *CADescription, err error) {
+	return ca.DescribeCtx(context.Background(), caId)
+}
+
+// DescribeCtx fetches the details and the certificate of the CA and
+// describes them.
+func (ca *CA) DescribeCtx(ctx context.Context, caId int64) (desc *CADescription, err error) {
+	info, err := ca.DetailsCtx(ctx, caId)
+	if err != nil {
+		return
+	}
+	caPem, err := ca.GetCtx(ctx, caId)
+	if err != nil {
+		return
+	}
+	cert, err := parseCertificatePEM([]byte(*caPem))
+	if err != nil {
+		return
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	return &CADescription{
+		CAInfo:            *info,
+		Certificate:       cert,
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		KeyUsage:          keyUsages(cert.KeyUsage),
+		SubjectKeyId:      fingerprint(cert.SubjectKeyId),
+		SHA256Fingerprint: fingerprint(sum[:]),
+	}, nil
+}
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digital_signature"},
+	{x509.KeyUsageContentCommitment, "content_commitment"},
+	{x509.KeyUsageKeyEncipherment, "key_encipherment"},
+	{x509.KeyUsageDataEncipherment, "data_encipherment"},
+	{x509.KeyUsageKeyAgreement, "key_agreement"},
+	{x509.KeyUsageCertSign, "cert_sign"},
+	{x509.KeyUsageCRLSign, "crl_sign"},
+	{x509.KeyUsageEncipherOnly, "encipher_only"},
+	{x509.KeyUsageDecipherOnly, "decipher_only"},
+}
+
+// keyUsages names the bits set in usage, in the order RFC 5280 lists them.
+func keyUsages(usage x509.KeyUsage) (names []string) {
+	for _, u := range keyUsageNames {
+		if usage&u.usage != 0 {
+			names = append(names, u.name)
+		}
+	}
+	return
+}
+
+// keySize returns the size of a public key in bits, or 0 for key types it
+// does not know.
+func keySize(pub any) int {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+func fingerprint(sum []byte) string {
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hex, ":")
+}
@@ -0,0 +1,60 @@
+package tinycert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Do calls an API endpoint, such as "cert/details", that the typed methods
+// do not cover, or with parameters they do not send. The call is signed,
+// authenticated with the session token and retried like any other, and API
+// errors come back as *APIError. The JSON response is decoded into out,
+// which may be nil to discard it. As for the typed methods, id, ca_id and
+// cert_id fields the API sends as numeric strings arrive as numbers.
+//
+// Parameter values may be strings, integers, bools, which are sent as "1" or
+// "0" as Fields does, fmt.Stringers, or string slices, which send the
+// parameter once per element. Types such as CertificateStatus are
+// fmt.Stringers, so convert them to int where the API expects a number. Any
+// other type fails with ErrInvalidRequest before a call is made.
+func (s *Session) Do(ctx context.Context, endpoint string, params map[string]any, out any) error {
+	endpoint = strings.Trim(endpoint, "/")
+	if endpoint == "" {
+		return fmt.Errorf("%w: empty endpoint", ErrInvalidRequest)
+	}
+
+	fields := Fields{}
+	for name, value := range params {
+		switch v := value.(type) {
+		case string:
+			fields.Set(name, v)
+		case int:
+			fields.SetInt(name, int64(v))
+		case int32:
+			fields.SetInt(name, int64(v))
+		case int64:
+			fields.SetInt(name, v)
+		case uint:
+			fields.Set(name, fmt.Sprint(v))
+		case uint32:
+			fields.Set(name, fmt.Sprint(v))
+		case uint64:
+			fields.Set(name, fmt.Sprint(v))
+		case bool:
+			fields.SetBool(name, v)
+		case fmt.Stringer:
+			fields.Set(name, v.String())
+		case []string:
+			fields[name] = append([]string(nil), v...)
+		default:
+			return fmt.Errorf("%w: parameter %s has unsupported type %T", ErrInvalidRequest, name, value)
+		}
+	}
+
+	if out == nil {
+		out = new(json.RawMessage)
+	}
+	return s.makeCall(ctx, endpoint, fields.Values(), out)
+}
@@ -0,0 +1,50 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_Do(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+	ctx := context.Background()
+
+	var info tinycert.CertificateInfo
+	if err := sess.Do(ctx, "/cert/details", map[string]any{"cert_id": certId}, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Id != certId || info.CommonName != "www.example.com" {
+		t.Errorf("details = %+v", info)
+	}
+
+	var list []tinycert.CertificateListItem
+	if err := sess.Do(ctx, "cert/list", map[string]any{"ca_id": caId, "what": int(tinycert.AnyStatus)}, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Id != certId {
+		t.Errorf("list = %+v", list)
+	}
+
+	if err := sess.Do(ctx, "cert/status", map[string]any{"cert_id": certId, "status": "revoked"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if details, _ := tinycert.NewCertificate(sess).Details(certId); details.Status != tinycert.Revoked {
+		t.Errorf("status = %v after cert/status", details.Status)
+	}
+
+	if err := sess.Do(ctx, "cert/unknown", nil, nil); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	calls := fs.callCount("cert/details")
+	if err := sess.Do(ctx, "cert/details", map[string]any{"weight": 1.5}, nil); !errors.Is(err, tinycert.ErrInvalidRequest) {
+		t.Errorf("err = %v, want ErrInvalidRequest", err)
+	}
+	if fs.callCount("cert/details") != calls {
+		t.Error("invalid parameters were sent")
+	}
+}
@@ -0,0 +1,114 @@
+package tinycert
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileLayout names the files Certificate.DownloadLayout writes. Empty paths
+// are skipped.
+type FileLayout struct {
+	CertPath  string
+	ChainPath string
+	KeyPath   string
+	// KeyArchive, if set, receives the private key instead of KeyPath, which
+	// must then be empty, so the key is never written in the clear.
+	KeyArchive *KeyArchive
+}
+
+func (c *Certificate) Download(certId int64, what Artifact, path string) (err error) {
+	return c.DownloadCtx(context.Background(), certId, what, path)
+}
+
+// DownloadCtx fetches an artifact and atomically writes it to path with 0600
+// permissions. PKCS12 bundles are written in their binary form.
+func (c *Certificate) DownloadCtx(ctx context.Context, certId int64, what Artifact, path string) (err error) {
+	if what == PKCS12 {
+		var der []byte
+		if der, err = c.GetPKCS12DERCtx(ctx, certId); err != nil {
+			return
+		}
+		return writeFileAtomic(path, der, 0600)
+	}
+
+	content, err := c.GetCtx(ctx, certId, what)
+	if err != nil {
+		return
+	}
+	return writeFileAtomic(path, []byte(*content), 0600)
+}
+
+func (c *Certificate) DownloadLayout(certId int64, layout FileLayout) (err error) {
+	return c.DownloadLayoutCtx(context.Background(), certId, layout)
+}
+
+func (c *Certificate) DownloadLayoutCtx(ctx context.Context, certId int64, layout FileLayout) (err error) {
+	if layout.KeyArchive != nil && layout.KeyPath != "" {
+		return fmt.Errorf("%w: KeyPath and KeyArchive are exclusive", ErrInvalidRequest)
+	}
+
+	files := []struct {
+		path string
+		what Artifact
+	}{
+		{layout.CertPath, Cert},
+		{layout.ChainPath, Chain},
+		{layout.KeyPath, KeyDecrypted},
+	}
+
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if err = c.DownloadCtx(ctx, certId, f.what, f.path); err != nil {
+			return
+		}
+	}
+	if layout.KeyArchive != nil {
+		err = c.ArchiveKeyCtx(ctx, certId, layout.KeyArchive)
+	}
+	return
+}
+
+func (ca *CA) Download(caId int64, path string) (err error) {
+	return ca.DownloadCtx(context.Background(), caId, path)
+}
+
+func (ca *CA) DownloadCtx(ctx context.Context, caId int64, path string) (err error) {
+	pem, err := ca.GetCtx(ctx, caId)
+	if err != nil {
+		return
+	}
+	return writeFileAtomic(path, []byte(*pem), 0600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(perm); err != nil {
+		return
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), path)
+}
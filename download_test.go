@@ -0,0 +1,54 @@
+package tinycert_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_DownloadLayout(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+
+	dir := t.TempDir()
+	layout := tinycert.FileLayout{
+		CertPath:  filepath.Join(dir, "cert.pem"),
+		ChainPath: filepath.Join(dir, "chain.pem"),
+		KeyPath:   filepath.Join(dir, "key.pem"),
+	}
+	if err := tinycert.NewCertificate(sess).DownloadLayout(certId, layout); err != nil {
+		t.Fatal("unable to download certificate", err)
+	}
+	if err := tinycert.NewCA(sess).Download(caId, filepath.Join(dir, "ca.pem")); err != nil {
+		t.Fatal("unable to download ca", err)
+	}
+
+	for name, marker := range map[string]string{
+		"cert.pem":  "BEGIN CERTIFICATE",
+		"chain.pem": "BEGIN CERTIFICATE",
+		"key.pem":   "PRIVATE KEY",
+		"ca.pem":    "BEGIN CERTIFICATE",
+	} {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", name, info.Mode().Perm())
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), marker) {
+			t.Errorf("%s does not contain %q", name, marker)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Errorf("expected no temporary files left behind, found %d entries", len(entries))
+	}
+}
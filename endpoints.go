@@ -0,0 +1,7 @@
+package tinycert
+
+// Client methods of API calls that only send their parameters and decode the
+// response are generated from the table in internal/apigen/endpoints.go. To
+// add such a call, add it to the table and run go generate.
+
+//go:generate go run ./internal/apigen -o endpoints_gen.go
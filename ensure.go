@@ -0,0 +1,132 @@
+package tinycert
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+func (ca *CA) Ensure(spec CARequest) (caId *int64, created bool, err error) {
+	return ca.EnsureCtx(context.Background(), spec)
+}
+
+// EnsureCtx returns the id of an existing CA whose subject matches spec, and
+// only creates one when there is none. OrgUnit, CommonName and Email are
+// only compared when set in spec.
+func (ca *CA) EnsureCtx(ctx context.Context, spec CARequest) (caId *int64, created bool, err error) {
+	if caId, err = ca.find(ctx, spec); err != nil || caId != nil {
+		return
+	}
+	caId, err = ca.CreateCtx(ctx, spec)
+	created = err == nil
+	return
+}
+
+// find returns the id of a CA whose subject matches spec, or nil.
+func (ca *CA) find(ctx context.Context, spec CARequest) (caId *int64, err error) {
+	items, err := ca.ListCtx(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		var info *CAInfo
+		if info, err = ca.DetailsCtx(ctx, item.Id); err != nil {
+			return
+		}
+		if info.OrgName == spec.OrgName && info.Locality == spec.Locality &&
+			info.StateCode == spec.StateCode && info.CountryCode == spec.CountryCode &&
+			matchOptional(info.OrgUnit, spec.OrgUnit) && matchOptional(info.CommonName, spec.CommonName) &&
+			matchOptional(info.Email, spec.Email) {
+			id := item.Id
+			return &id, nil
+		}
+	}
+	return
+}
+
+func matchOptional(got, want string) bool {
+	return want == "" || got == want
+}
+
+func (c *Certificate) Ensure(caId int64, spec CertRequest) (certId *int64, created bool, err error) {
+	return c.EnsureCtx(context.Background(), caId, spec)
+}
+
+// EnsureCtx returns the id of a good or held certificate under the CA with
+// the same common name and SANs as spec, and only issues one when there is
+// none. Names are compared in the normalized form Create sends.
+func (c *Certificate) EnsureCtx(ctx context.Context, caId int64, spec CertRequest) (certId *int64, created bool, err error) {
+	if spec, err = spec.Normalize(); err != nil {
+		return
+	}
+
+	items, err := c.ListCtx(ctx, caId, Good|Hold)
+	if err != nil {
+		return
+	}
+	item, err := c.match(ctx, items, spec)
+	if err != nil {
+		return
+	}
+	if item != nil {
+		id := item.Id
+		return &id, false, nil
+	}
+
+	certId, err = c.CreateCtx(ctx, caId, spec)
+	created = err == nil
+	return
+}
+
+// match returns the item with the same common name and SANs as spec, which
+// must be normalized, or nil.
+func (c *Certificate) match(ctx context.Context, items []*CertificateListItem, spec CertRequest) (match *CertificateListItem, err error) {
+	want := sanKeys(spec.Alt)
+	for _, item := range items {
+		if item.Name != spec.CommonName {
+			continue
+		}
+
+		var info *CertificateInfo
+		if info, err = c.DetailsCtx(ctx, item.Id); err != nil {
+			return
+		}
+		if info.CommonName == spec.CommonName && sameStrings(sanKeys(info.Alt), want) {
+			return item, nil
+		}
+	}
+	return
+}
+
+func sanKeys(alt []SAN) []string {
+	keys := []string{}
+	for _, san := range alt {
+		if san.DNS != "" {
+			keys = append(keys, "DNS:"+strings.ToLower(san.DNS))
+		}
+		if san.Email != "" {
+			keys = append(keys, "email:"+san.Email)
+		}
+		if san.IP != "" {
+			keys = append(keys, "IP:"+san.IP)
+		}
+		if san.URI != "" {
+			keys = append(keys, "URI:"+san.URI)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
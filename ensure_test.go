@@ -0,0 +1,53 @@
+package tinycert_test
+
+import (
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestEnsure(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	ca := tinycert.NewCA(sess)
+	spec := tinycert.CARequest{OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"}
+	caId, created, err := ca.Ensure(spec)
+	if err != nil || !created {
+		t.Fatal("expected ca to be created", created, err)
+	}
+	again, created, err := ca.Ensure(spec)
+	if err != nil || created || *again != *caId {
+		t.Fatal("expected existing ca to be returned", created, err)
+	}
+
+	cert := tinycert.NewCertificate(sess)
+	req := tinycert.CertRequest{CommonName: "www.example.com", OrgName: "acme", Alt: []tinycert.SAN{{DNS: "www.example.com"}}}
+	certId, created, err := cert.Ensure(*caId, req)
+	if err != nil || !created {
+		t.Fatal("expected certificate to be created", created, err)
+	}
+	same, created, err := cert.Ensure(*caId, req)
+	if err != nil || created || *same != *certId {
+		t.Fatal("expected existing certificate to be returned", created, err)
+	}
+
+	// The same names spelled differently are the same certificate.
+	spelled := tinycert.CertRequest{CommonName: "WWW.Example.com.", OrgName: "acme", Alt: []tinycert.SAN{{DNS: "www.EXAMPLE.com"}}}
+	if same, created, err = cert.Ensure(*caId, spelled); err != nil || created || *same != *certId {
+		t.Fatal("expected existing certificate for a differently spelled request", created, err)
+	}
+
+	req.Alt = append(req.Alt, tinycert.SAN{DNS: "example.com"})
+	other, created, err := cert.Ensure(*caId, req)
+	if err != nil || !created || *other == *certId {
+		t.Fatal("expected a new certificate for different SANs", created, err)
+	}
+
+	if got := fs.callCount("ca/new"); got != 1 {
+		t.Errorf("ca/new called %d times, want 1", got)
+	}
+	if got := fs.callCount("cert/new"); got != 2 {
+		t.Errorf("cert/new called %d times, want 2", got)
+	}
+}
@@ -0,0 +1,111 @@
+package tinycert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("tinycert: unauthorized")
+	ErrNotFound     = errors.New("tinycert: not found")
+	ErrRateLimited  = errors.New("tinycert: rate limited")
+
+	ErrInvalidBaseURL = errors.New("tinycert: invalid base url")
+	ErrNotConnected   = errors.New("tinycert: session not connected")
+	ErrClosed         = errors.New("tinycert: session closed")
+	// ErrResponseTooLarge is returned when a response body exceeds the
+	// session's maximum response size.
+	ErrResponseTooLarge = errors.New("tinycert: response too large")
+)
+
+// APIError is returned when the TinyCert API rejects a call. Use errors.Is
+// with the Err* sentinels to branch on the cause, or errors.As to inspect it.
+type APIError struct {
+	HTTPStatus int
+	Code       string
+	Message    string
+	// RetryAfter is the delay requested by the server's Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("tinycert: error from server code = %d, response = %s", e.HTTPStatus, e.Message)
+	}
+	return fmt.Sprintf("tinycert: error from server code = %d, error = %s: %s", e.HTTPStatus, e.Code, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	status := e.status()
+	switch target {
+	case ErrUnauthorized:
+		return status == http.StatusUnauthorized || status == http.StatusForbidden
+	case ErrNotFound:
+		return status == http.StatusNotFound
+	case ErrRateLimited:
+		return status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// RetryAfter returns the delay a rate-limited or unavailable server asked
+// for, if err carries one.
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// status returns the HTTP status describing the failure. Errors reported in
+// the body of a 2xx response carry the HTTP-like status in their code instead.
+func (e *APIError) status() int {
+	if e.HTTPStatus >= 200 && e.HTTPStatus < 300 {
+		if code, err := strconv.Atoi(e.Code); err == nil {
+			return code
+		}
+	}
+	return e.HTTPStatus
+}
+
+// errorEnvelope detects the {"code":...,"text":...} error object TinyCert
+// sometimes returns with a 2xx status.
+func errorEnvelope(httpStatus int, body []byte) *APIError {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+
+	_, hasCode := fields["code"]
+	_, hasText := fields["text"]
+	if !hasCode || !hasText {
+		return nil
+	}
+	return parseAPIError(httpStatus, body)
+}
+
+func parseAPIError(httpStatus int, body []byte) *APIError {
+	type errorResponse struct {
+		Code json.RawMessage `json:"code"`
+		Text string          `json:"text"`
+	}
+
+	apiErr := &APIError{HTTPStatus: httpStatus, Message: string(body)}
+
+	var eres errorResponse
+	if err := json.Unmarshal(body, &eres); err != nil || (len(eres.Code) == 0 && eres.Text == "") {
+		return apiErr
+	}
+
+	if code := string(eres.Code); code != "null" {
+		apiErr.Code = strings.Trim(code, `"`)
+	}
+	apiErr.Message = eres.Text
+	return apiErr
+}
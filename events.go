@@ -0,0 +1,101 @@
+package tinycert
+
+import (
+	"fmt"
+	"time"
+)
+
+type EventType int
+
+const (
+	CACreated EventType = iota
+	CADeleted
+	CertificateCreated
+	CertificateReissued
+	CertificateStatusChanged
+)
+
+func (t EventType) String() string {
+	switch t {
+	case CACreated:
+		return "ca.created"
+	case CADeleted:
+		return "ca.deleted"
+	case CertificateCreated:
+		return "certificate.created"
+	case CertificateReissued:
+		return "certificate.reissued"
+	case CertificateStatusChanged:
+		return "certificate.status_changed"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event describes a mutating operation that succeeded. CertId is the new
+// certificate for CertificateReissued, which also sets PreviousCertId.
+// Status is set for CertificateStatusChanged. CAId is zero when the
+// operation does not identify the CA.
+type Event struct {
+	Type           EventType
+	Time           time.Time
+	CAId           int64
+	CertId         int64
+	PreviousCertId int64
+	Status         CertificateStatus
+}
+
+// EventHandler is called synchronously after each mutating operation, in the
+// goroutine that performed it, so it should return quickly.
+type EventHandler func(Event)
+
+// WithEventHandler registers fn for every event.
+func (s *Session) WithEventHandler(fn EventHandler) *Session {
+	s.handlers = append(s.handlers, fn)
+	return s
+}
+
+// WithEventChannel sends every event to ch. Events are dropped, with a
+// warning logged, if ch is not ready to receive.
+func (s *Session) WithEventChannel(ch chan<- Event) *Session {
+	return s.WithEventHandler(func(ev Event) {
+		select {
+		case ch <- ev:
+		default:
+			s.logger.Log(LevelWarn, "event channel full, dropping %s event", ev.Type)
+		}
+	})
+}
+
+func (s *Session) OnCertificateCreated(fn EventHandler) *Session {
+	return s.WithEventHandler(func(ev Event) {
+		if ev.Type == CertificateCreated {
+			fn(ev)
+		}
+	})
+}
+
+func (s *Session) OnCertificateRevoked(fn EventHandler) *Session {
+	return s.WithEventHandler(func(ev Event) {
+		if ev.Type == CertificateStatusChanged && ev.Status == Revoked {
+			fn(ev)
+		}
+	})
+}
+
+func (s *Session) OnCADeleted(fn EventHandler) *Session {
+	return s.WithEventHandler(func(ev Event) {
+		if ev.Type == CADeleted {
+			fn(ev)
+		}
+	})
+}
+
+func (s *Session) emit(ev Event) {
+	if len(s.handlers) == 0 {
+		return
+	}
+	ev.Time = s.clock.Now()
+	for _, fn := range s.handlers {
+		fn(ev)
+	}
+}
@@ -0,0 +1,72 @@
+package tinycert_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_Events(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	var all []tinycert.EventType
+	var created, revoked, deleted int
+	sess.WithEventHandler(func(ev tinycert.Event) {
+		if ev.Time.IsZero() {
+			t.Errorf("%s event has no time", ev.Type)
+		}
+		all = append(all, ev.Type)
+	}).
+		OnCertificateCreated(func(tinycert.Event) { created++ }).
+		OnCertificateRevoked(func(tinycert.Event) { revoked++ }).
+		OnCADeleted(func(tinycert.Event) { deleted++ })
+
+	ch := make(chan tinycert.Event, 1)
+	sess.WithEventChannel(ch)
+
+	caId, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+
+	newCertId, err := cert.Reissue(certId)
+	if err != nil {
+		t.Fatal("unable to reissue", err)
+	}
+	if err := cert.Revoke(certId); err != nil {
+		t.Fatal("unable to revoke", err)
+	}
+	if err := cert.Hold(*newCertId); err != nil {
+		t.Fatal("unable to hold", err)
+	}
+	if _, err := cert.Details(certId); err != nil {
+		t.Fatal("unable to fetch details", err)
+	}
+	if err := tinycert.NewCA(sess).Delete(caId); err != nil {
+		t.Fatal("unable to delete ca", err)
+	}
+
+	want := []tinycert.EventType{
+		tinycert.CACreated,
+		tinycert.CertificateCreated,
+		tinycert.CertificateReissued,
+		tinycert.CertificateStatusChanged,
+		tinycert.CertificateStatusChanged,
+		tinycert.CADeleted,
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("events = %v, want %v", all, want)
+	}
+	if created != 1 || revoked != 1 || deleted != 1 {
+		t.Errorf("created = %d, revoked = %d, deleted = %d", created, revoked, deleted)
+	}
+
+	if ev := <-ch; ev.Type != tinycert.CACreated || ev.CAId != caId {
+		t.Errorf("first channel event = %+v", ev)
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("full channel received %+v", ev)
+	default:
+	}
+}
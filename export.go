@@ -0,0 +1,127 @@
+package tinycert
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+var ErrInvalidExportFormat = errors.New("tinycert: invalid export format")
+
+// DefaultTruststorePassword protects JKS truststores written by Export; it is
+// the JVM's own default for cacerts.
+const DefaultTruststorePassword = "changeit"
+
+// ExportFormat selects the encoding of CA.Export.
+type ExportFormat int
+
+const (
+	ExportPEM ExportFormat = iota
+	ExportDER
+	ExportJKS
+)
+
+func (f ExportFormat) String() string {
+	switch f {
+	case ExportPEM:
+		return "pem"
+	case ExportDER:
+		return "der"
+	case ExportJKS:
+		return "jks"
+	}
+	return fmt.Sprintf("ExportFormat(%d)", int(f))
+}
+
+// Export returns the CA certificate as PEM, as DER (the first certificate
+// only, since DER holds one), or as a JKS truststore protected by
+// DefaultTruststorePassword.
+func (ca *CA) Export(caId int64, format ExportFormat) (data []byte, err error) {
+	return ca.ExportCtx(context.Background(), caId, format)
+}
+
+func (ca *CA) ExportCtx(ctx context.Context, caId int64, format ExportFormat) (data []byte, err error) {
+	return ca.export(ctx, caId, format, DefaultTruststorePassword)
+}
+
+// ExportJKS is Export with ExportJKS and a caller chosen truststore password.
+func (ca *CA) ExportJKS(caId int64, password string) (data []byte, err error) {
+	return ca.ExportJKSCtx(context.Background(), caId, password)
+}
+
+func (ca *CA) ExportJKSCtx(ctx context.Context, caId int64, password string) (data []byte, err error) {
+	return ca.export(ctx, caId, ExportJKS, password)
+}
+
+func (ca *CA) export(ctx context.Context, caId int64, format ExportFormat, password string) (data []byte, err error) {
+	if format < ExportPEM || format > ExportJKS {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidExportFormat, int(format))
+	}
+
+	pemData, err := ca.GetCtx(ctx, caId)
+	if err != nil {
+		return
+	}
+	ders := certificateDERs([]byte(*pemData))
+	if len(ders) == 0 {
+		return nil, fmt.Errorf("tinycert: no certificate in CA %d", caId)
+	}
+
+	switch format {
+	case ExportPEM:
+		var buf bytes.Buffer
+		for _, der := range ders {
+			pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		}
+		data = buf.Bytes()
+	case ExportDER:
+		data = ders[0]
+	case ExportJKS:
+		data, err = truststore("tinycert-ca-"+formatInt(caId), ders, password)
+	}
+	return
+}
+
+// certificateDERs returns the DER bytes of every CERTIFICATE block in data.
+func certificateDERs(data []byte) (ders [][]byte) {
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return
+		}
+		if block.Type == "CERTIFICATE" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+}
+
+// truststore builds a JKS holding ders as trusted certificate entries named
+// alias, with -<n> appended after the first.
+func truststore(alias string, ders [][]byte, password string) ([]byte, error) {
+	ks := keystore.New(keystore.WithOrderedAliases())
+	now := time.Now()
+	for i, der := range ders {
+		name := alias
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", alias, i)
+		}
+		entry := keystore.TrustedCertificateEntry{
+			CreationTime: now,
+			Certificate:  keystore.Certificate{Type: "X509", Content: der},
+		}
+		if err := ks.SetTrustedCertificateEntry(name, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, []byte(password)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+package tinycert_test
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCA_Export(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+	ca := tinycert.NewCA(sess)
+
+	pemData, err := ca.Export(caId, tinycert.ExportPEM)
+	if err != nil {
+		t.Fatal("Export(pem)", err)
+	}
+	root := parseCerts(t, string(pemData))[0]
+
+	der, err := ca.Export(caId, tinycert.ExportDER)
+	if err != nil {
+		t.Fatal("Export(der)", err)
+	}
+	if parsed, err := x509.ParseCertificate(der); err != nil || !parsed.Equal(root) {
+		t.Errorf("Export(der) does not match the pem: %v", err)
+	}
+
+	jks, err := ca.ExportJKS(caId, "s3cret!")
+	if err != nil {
+		t.Fatal("ExportJKS()", err)
+	}
+	ks := keystore.New()
+	if err := ks.Load(bytes.NewReader(jks), []byte("s3cret!")); err != nil {
+		t.Fatal("unable to load truststore", err)
+	}
+	entry, err := ks.GetTrustedCertificateEntry(fmt.Sprintf("tinycert-ca-%d", caId))
+	if err != nil {
+		t.Fatal("truststore entry", err)
+	}
+	if !bytes.Equal(entry.Certificate.Content, root.Raw) {
+		t.Error("truststore entry does not hold the CA certificate")
+	}
+
+	if _, err := ca.Export(caId, tinycert.ExportFormat(42)); !errors.Is(err, tinycert.ErrInvalidExportFormat) {
+		t.Errorf("Export(42) = %v, want ErrInvalidExportFormat", err)
+	}
+}
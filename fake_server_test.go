@@ -0,0 +1,540 @@
+package tinycert_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	fakeEmail      = "user@example.com"
+	fakePassphrase = "secret"
+	fakeAPIKey     = "apikey"
+)
+
+type fakeCA struct {
+	id   int64
+	form url.Values
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	der  []byte
+}
+
+type fakeCert struct {
+	id     int64
+	caId   int64
+	status string
+	form   url.Values
+	key    *ecdsa.PrivateKey
+	der    []byte
+}
+
+// fakeServer is an in-memory implementation of the TinyCert API used by the
+// tests. It verifies request digests and tokens the same way the real service
+// does and issues real x509 certificates.
+type fakeServer struct {
+	*httptest.Server
+	t testing.TB
+
+	mu     sync.Mutex
+	token  string
+	nextID int64
+	cas    map[int64]*fakeCA
+	certs  map[int64]*fakeCert
+	calls  map[string]int
+
+	// fail, when set, is consulted before every call; a non-zero status
+	// short-circuits the call with that status and body.
+	fail   func(api string) (status int, body string)
+	header http.Header
+}
+
+func newFakeServer(t testing.TB) *fakeServer {
+	fs := &fakeServer{
+		t:      t,
+		nextID: 100,
+		cas:    map[int64]*fakeCA{},
+		certs:  map[int64]*fakeCert{},
+		calls:  map[string]int{},
+	}
+	fs.Server = httptest.NewServer(http.HandlerFunc(fs.handle))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fakeServer) session() *tinycert.Session {
+	return tinycert.NewSession().WithEmail(fakeEmail).WithPassphrase(fakePassphrase).WithApiKey(fakeAPIKey).WithBaseURL(fs.URL + "/api")
+}
+
+func (fs *fakeServer) connectedSession() *tinycert.Session {
+	s := fs.session()
+	if err := s.Connect(); err != nil {
+		fs.t.Fatal("unable to connect to fake server", err)
+	}
+	return s
+}
+
+func (fs *fakeServer) callCount(api string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.calls[api]
+}
+
+func (fs *fakeServer) setFail(fail func(api string) (status int, body string)) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.fail = fail
+}
+
+func (fs *fakeServer) setHeader(name, value string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.header == nil {
+		fs.header = http.Header{}
+	}
+	fs.header.Set(name, value)
+}
+
+func (fs *fakeServer) expireToken() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.token = ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, text string) {
+	writeJSON(w, status, map[string]string{"code": code, "text": text})
+}
+
+func signForm(form url.Values, apiKey string) string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		if name != "digest" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	vals := ""
+	for _, name := range names {
+		if vals != "" {
+			vals += "&"
+		}
+		vals += url.QueryEscape(name) + "=" + url.QueryEscape(form.Get(name))
+	}
+
+	mac := hmac.New(sha256.New, []byte(apiKey))
+	mac.Write([]byte(vals))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (fs *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	api, ok := strings.CutPrefix(r.URL.Path, "/api/v1/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "404", "no such api")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "400", err.Error())
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.calls[api]++
+
+	for name, values := range fs.header {
+		w.Header()[name] = values
+	}
+
+	if fs.fail != nil {
+		if status, body := fs.fail(api); status != 0 {
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+			return
+		}
+	}
+
+	if r.PostForm.Get("digest") != signForm(r.PostForm, fakeAPIKey) {
+		writeError(w, http.StatusBadRequest, "400", "invalid digest")
+		return
+	}
+
+	if api == "connect" {
+		if r.PostForm.Get("email") != fakeEmail || r.PostForm.Get("passphrase") != fakePassphrase {
+			writeError(w, http.StatusUnauthorized, "401", "invalid credentials")
+			return
+		}
+		fs.nextID++
+		fs.token = fmt.Sprintf("token-%d", fs.nextID)
+		writeJSON(w, http.StatusOK, map[string]string{"token": fs.token})
+		return
+	}
+
+	if fs.token == "" || r.PostForm.Get("token") != fs.token {
+		writeError(w, http.StatusUnauthorized, "401", "invalid or expired token")
+		return
+	}
+
+	form := r.PostForm
+	switch api {
+	case "disconnect":
+		fs.token = ""
+		writeJSON(w, http.StatusOK, map[string]string{})
+	case "ca/new":
+		ca, err := fs.newCA(form)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "500", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int64{"ca_id": ca.id})
+	case "ca/list":
+		list := []map[string]interface{}{}
+		for _, id := range fs.sortedCAIds() {
+			list = append(list, map[string]interface{}{"id": id, "name": fs.cas[id].cert.Subject.CommonName})
+		}
+		writeJSON(w, http.StatusOK, list)
+	case "ca/details":
+		ca, ok := fs.lookupCA(w, form)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":       ca.id,
+			"C":        ca.form.Get("C"),
+			"ST":       ca.form.Get("ST"),
+			"L":        ca.form.Get("L"),
+			"O":        ca.form.Get("O"),
+			"OU":       ca.form.Get("OU"),
+			"CN":       ca.cert.Subject.CommonName,
+			"E":        ca.form.Get("E"),
+			"hash_alg": ca.form.Get("hash_method"),
+		})
+	case "ca/get":
+		ca, ok := fs.lookupCA(w, form)
+		if !ok {
+			return
+		}
+		switch form.Get("what") {
+		case "cert":
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("CERTIFICATE", ca.der)})
+		case "crl":
+			crl, err := fs.crl(ca)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "500", err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("X509 CRL", crl)})
+		default:
+			writeError(w, http.StatusBadRequest, "400", "invalid what")
+		}
+	case "ca/delete":
+		ca, ok := fs.lookupCA(w, form)
+		if !ok {
+			return
+		}
+		delete(fs.cas, ca.id)
+		for id, cert := range fs.certs {
+			if cert.caId == ca.id {
+				delete(fs.certs, id)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]string{})
+	case "cert/new":
+		caId, _ := strconv.ParseInt(form.Get("ca_id"), 10, 64)
+		ca, ok := fs.cas[caId]
+		if !ok {
+			writeError(w, http.StatusNotFound, "404", "no such ca")
+			return
+		}
+		cert, err := fs.newCert(ca, form)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "500", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int64{"cert_id": cert.id})
+	case "cert/get":
+		cert, ok := fs.lookupCert(w, form)
+		if !ok {
+			return
+		}
+		keyDER, _ := x509.MarshalPKCS8PrivateKey(cert.key)
+		switch form.Get("what") {
+		case "cert":
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("CERTIFICATE", cert.der)})
+		case "chain":
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("CERTIFICATE", cert.der) + pemEncode("CERTIFICATE", fs.cas[cert.caId].der)})
+		case "csr":
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("CERTIFICATE REQUEST", cert.der)})
+		case "key.dec":
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("PRIVATE KEY", keyDER)})
+		case "key.enc":
+			// Not really encrypted; the block type is enough to tell the variants apart.
+			writeJSON(w, http.StatusOK, map[string]string{"pem": pemEncode("ENCRYPTED PRIVATE KEY", keyDER)})
+		case "pkcs12":
+			leaf, _ := x509.ParseCertificate(cert.der)
+			p12, err := pkcs12.Modern.Encode(cert.key, leaf, []*x509.Certificate{fs.cas[cert.caId].cert}, fakePassphrase)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "500", err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"pkcs12": base64.StdEncoding.EncodeToString(p12)})
+		default:
+			writeError(w, http.StatusBadRequest, "400", "invalid what")
+		}
+	case "cert/details":
+		cert, ok := fs.lookupCert(w, form)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, cert.details())
+	case "cert/list":
+		caId, _ := strconv.ParseInt(form.Get("ca_id"), 10, 64)
+		what, _ := strconv.Atoi(form.Get("what"))
+		list := []map[string]interface{}{}
+		for _, id := range fs.sortedCertIds() {
+			cert := fs.certs[id]
+			if cert.caId != caId || what&statusBit(cert.status) == 0 {
+				continue
+			}
+			list = append(list, map[string]interface{}{
+				"id":      cert.id,
+				"name":    cert.form.Get("CN"),
+				"status":  cert.status,
+				"expires": time.Now().Add(365 * 24 * time.Hour).Unix(),
+			})
+		}
+		writeJSON(w, http.StatusOK, list)
+	case "cert/reissue":
+		cert, ok := fs.lookupCert(w, form)
+		if !ok {
+			return
+		}
+		reissued, err := fs.newCert(fs.cas[cert.caId], cert.form)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "500", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int64{"cert_id": reissued.id})
+	case "cert/status":
+		cert, ok := fs.lookupCert(w, form)
+		if !ok {
+			return
+		}
+		cert.status = form.Get("status")
+		writeJSON(w, http.StatusOK, map[string]string{})
+	default:
+		writeError(w, http.StatusNotFound, "404", "unknown endpoint "+api)
+	}
+}
+
+func statusBit(status string) int {
+	switch status {
+	case "expired":
+		return 1
+	case "good":
+		return 2
+	case "revoked":
+		return 4
+	case "hold":
+		return 8
+	}
+	return 0
+}
+
+func (fs *fakeServer) sortedCAIds() []int64 {
+	ids := []int64{}
+	for id := range fs.cas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (fs *fakeServer) sortedCertIds() []int64 {
+	ids := []int64{}
+	for id := range fs.certs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (fs *fakeServer) lookupCA(w http.ResponseWriter, form url.Values) (*fakeCA, bool) {
+	id, _ := strconv.ParseInt(form.Get("ca_id"), 10, 64)
+	ca, ok := fs.cas[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404", "no such ca")
+	}
+	return ca, ok
+}
+
+func (fs *fakeServer) lookupCert(w http.ResponseWriter, form url.Values) (*fakeCert, bool) {
+	id, _ := strconv.ParseInt(form.Get("cert_id"), 10, 64)
+	cert, ok := fs.certs[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404", "no such certificate")
+	}
+	return cert, ok
+}
+
+func (fs *fakeServer) newCA(form url.Values) (*fakeCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.nextID++
+	cn := form.Get("CN")
+	if cn == "" {
+		cn = form.Get("O") + " Root CA"
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(fs.nextID),
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{form.Get("O")}, Country: []string{form.Get("C")}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	ca := &fakeCA{id: fs.nextID, form: form, key: key, cert: cert, der: der}
+	fs.cas[ca.id] = ca
+	return ca, nil
+}
+
+func (fs *fakeServer) newCert(ca *fakeCA, form url.Values) (*fakeCert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.nextID++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(fs.nextID),
+		Subject:      pkix.Name{CommonName: form.Get("CN"), Organization: []string{form.Get("O")}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for name := range form {
+		if strings.HasSuffix(name, "[DNS]") {
+			tmpl.DNSNames = append(tmpl.DNSNames, form.Get(name))
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &fakeCert{id: fs.nextID, caId: ca.id, status: "good", form: form, key: key, der: der}
+	fs.certs[cert.id] = cert
+	return cert, nil
+}
+
+func (fs *fakeServer) crl(ca *fakeCA) ([]byte, error) {
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(fs.nextID),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(24 * time.Hour),
+	}
+	for _, id := range fs.sortedCertIds() {
+		cert := fs.certs[id]
+		if cert.caId == ca.id && cert.status == "revoked" {
+			tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, x509.RevocationListEntry{
+				SerialNumber:   big.NewInt(cert.id),
+				RevocationTime: time.Now(),
+			})
+		}
+	}
+	return x509.CreateRevocationList(rand.Reader, tmpl, ca.cert, ca.key)
+}
+
+func (c *fakeCert) details() map[string]interface{} {
+	alt := []map[string]string{}
+	for name := range c.form {
+		if strings.HasPrefix(name, "SANs[") {
+			kind := name[strings.LastIndex(name, "[")+1 : len(name)-1]
+			alt = append(alt, map[string]string{kind: c.form.Get(name)})
+		}
+	}
+	return map[string]interface{}{
+		"id":     c.id,
+		"status": c.status,
+		"C":      c.form.Get("C"),
+		"ST":     c.form.Get("ST"),
+		"L":      c.form.Get("L"),
+		"O":      c.form.Get("O"),
+		"OU":     c.form.Get("OU"),
+		"CN":     c.form.Get("CN"),
+		"alt":    alt,
+	}
+}
+
+func pemEncode(blockType string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func parseCert(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseCerts(t *testing.T, data string) (certs []*x509.Certificate) {
+	t.Helper()
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+}
@@ -0,0 +1,201 @@
+// Package filesync keeps the certificate and key files of a server such as
+// nginx or haproxy in step with TinyCert. Files are only rewritten when their
+// content changes, each write is atomic, and the server can be told to reload
+// with a signal or a hook command, so certificates rotate without downtime.
+package filesync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// Default file names, used when Options names no file at all.
+const (
+	DefaultCertFile  = "cert.pem"
+	DefaultChainFile = "fullchain.pem"
+	DefaultKeyFile   = "key.pem"
+)
+
+// Options say which files to write and how to announce a change.
+type Options struct {
+	// CertFile, ChainFile and KeyFile name the leaf certificate, the leaf
+	// followed by its CA, and the decrypted key, relative to the directory.
+	// Empty names are skipped; if all are empty the defaults are written.
+	CertFile  string
+	ChainFile string
+	KeyFile   string
+
+	// PID, or the process whose pid is in PIDFile, such as
+	// /run/nginx.pid, is sent Signal when any file changed. Signal defaults
+	// to SIGHUP.
+	PID     int
+	PIDFile string
+	Signal  os.Signal
+
+	// Hook, if set, is run as a command, e.g. {"systemctl", "reload",
+	// "haproxy"}, when any file changed.
+	Hook []string
+}
+
+func (o Options) files() []struct {
+	name string
+	what tinycert.Artifact
+	perm os.FileMode
+} {
+	if o.CertFile == "" && o.ChainFile == "" && o.KeyFile == "" {
+		o.CertFile, o.ChainFile, o.KeyFile = DefaultCertFile, DefaultChainFile, DefaultKeyFile
+	}
+	return []struct {
+		name string
+		what tinycert.Artifact
+		perm os.FileMode
+	}{
+		{o.CertFile, tinycert.Cert, 0644},
+		{o.ChainFile, tinycert.Chain, 0644},
+		{o.KeyFile, tinycert.KeyDecrypted, 0600},
+	}
+}
+
+// Sync fetches the certificate's files and writes those that differ from
+// what is in dir, then notifies the server if any did. It reports whether
+// anything changed. Files are all fetched before any is written, so a
+// failed fetch leaves dir as it was. The session's Cache is bypassed.
+func Sync(ctx context.Context, session *tinycert.Session, certId int64, dir string, opts Options) (changed bool, err error) {
+	cert := tinycert.NewCertificate(session)
+	cert.Invalidate(certId)
+
+	type update struct {
+		path    string
+		content []byte
+		perm    os.FileMode
+	}
+	var updates []update
+	for _, f := range opts.files() {
+		if f.name == "" {
+			continue
+		}
+		var content *string
+		if content, err = cert.GetCtx(ctx, certId, f.what); err != nil {
+			return
+		}
+		path := filepath.Join(dir, f.name)
+		if current, rerr := os.ReadFile(path); rerr == nil && bytes.Equal(current, []byte(*content)) {
+			continue
+		}
+		updates = append(updates, update{path, []byte(*content), f.perm})
+	}
+	if len(updates) == 0 {
+		return false, nil
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	for _, u := range updates {
+		if err = writeFileAtomic(u.path, u.content, u.perm); err != nil {
+			return
+		}
+	}
+	return true, notify(ctx, opts)
+}
+
+// Run syncs once and then every interval until ctx is done. Failed syncs
+// are reported to onError, if set, and retried at the next tick.
+func Run(ctx context.Context, session *tinycert.Session, certId int64, dir string, opts Options, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := Sync(ctx, session, certId, dir, opts); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func notify(ctx context.Context, opts Options) error {
+	var errs []error
+	if opts.PID != 0 || opts.PIDFile != "" {
+		errs = append(errs, signal(opts))
+	}
+	if len(opts.Hook) > 0 {
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, opts.Hook[0], opts.Hook[1:]...)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, fmt.Errorf("filesync: hook %s: %v: %s", opts.Hook[0], err, strings.TrimSpace(out.String())))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func signal(opts Options) error {
+	pid := opts.PID
+	if opts.PIDFile != "" {
+		data, err := os.ReadFile(opts.PIDFile)
+		if err != nil {
+			return fmt.Errorf("filesync: %w", err)
+		}
+		if pid, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+			return fmt.Errorf("filesync: invalid pid file %s: %w", opts.PIDFile, err)
+		}
+	}
+
+	sig := opts.Signal
+	if sig == nil {
+		sig = syscall.SIGHUP
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("filesync: %w", err)
+	}
+	if err = proc.Signal(sig); err != nil {
+		return fmt.Errorf("filesync: signal %d: %w", pid, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so the server never reads a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(perm); err != nil {
+		return
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), path)
+}
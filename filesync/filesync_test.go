@@ -0,0 +1,128 @@
+package filesync_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/filesync"
+)
+
+// fakeTinyCert serves cert/get from artifacts, keyed by the what field.
+type fakeTinyCert struct {
+	mu        sync.Mutex
+	artifacts map[string]string
+}
+
+func (f *fakeTinyCert) set(what, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.artifacts[what] = content
+}
+
+func newFake(t *testing.T) (*fakeTinyCert, *tinycert.Session) {
+	t.Helper()
+	fake := &fakeTinyCert{artifacts: map[string]string{"cert": "CERT 1", "chain": "CERT 1\nCA", "key.dec": "KEY 1"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"pem": fake.artifacts[r.PostForm.Get("what")]})
+	}))
+	t.Cleanup(srv.Close)
+
+	sess := tinycert.NewSession().WithEmail("user@example.com").WithPassphrase("secret").WithApiKey("apikey").
+		WithBaseURL(srv.URL + "/api").Resume("token")
+	return fake, sess
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSync(t *testing.T) {
+	fake, sess := newFake(t)
+	dir := filepath.Join(t.TempDir(), "tls")
+	ctx := context.Background()
+
+	changed, err := filesync.Sync(ctx, sess, 1, dir, filesync.Options{})
+	if err != nil || !changed {
+		t.Fatalf("first Sync = %v, %v", changed, err)
+	}
+	for name, want := range map[string]string{"cert.pem": "CERT 1", "fullchain.pem": "CERT 1\nCA", "key.pem": "KEY 1"} {
+		if got := readFile(t, filepath.Join(dir, name)); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "key.pem")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key.pem mode = %v, %v", info.Mode(), err)
+	}
+
+	if changed, err = filesync.Sync(ctx, sess, 1, dir, filesync.Options{}); err != nil || changed {
+		t.Errorf("unchanged Sync = %v, %v", changed, err)
+	}
+
+	fake.set("cert", "CERT 2")
+	opts := filesync.Options{CertFile: "server.crt"}
+	if changed, err = filesync.Sync(ctx, sess, 1, dir, opts); err != nil || !changed {
+		t.Errorf("Sync after change = %v, %v", changed, err)
+	}
+	if got := readFile(t, filepath.Join(dir, "server.crt")); got != "CERT 2" {
+		t.Errorf("server.crt = %q", got)
+	}
+}
+
+func TestSync_NotifyErrors(t *testing.T) {
+	_, sess := newFake(t)
+	dir := t.TempDir()
+
+	opts := filesync.Options{PIDFile: filepath.Join(dir, "missing.pid")}
+	changed, err := filesync.Sync(context.Background(), sess, 1, dir, opts)
+	if !changed || err == nil {
+		t.Errorf("Sync = %v, %v; want the change and a pid file error", changed, err)
+	}
+
+	os.Remove(filepath.Join(dir, "cert.pem"))
+	opts = filesync.Options{Hook: []string{filepath.Join(dir, "no-such-command")}}
+	if _, err := filesync.Sync(context.Background(), sess, 1, dir, opts); err == nil {
+		t.Error("expected the failing hook to be reported")
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake, sess := newFake(t)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		filesync.Run(ctx, sess, 1, dir, filesync.Options{KeyFile: "key.pem"}, 10*time.Millisecond, func(err error) { t.Error(err) })
+		close(done)
+	}()
+
+	fake.set("key.dec", "KEY 2")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if data, _ := os.ReadFile(filepath.Join(dir, "key.pem")); string(data) == "KEY 2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("key was not updated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package filesync_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert/filesync"
+)
+
+func TestSync_Notify(t *testing.T) {
+	_, sess := newFake(t)
+	dir := t.TempDir()
+
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
+	defer signal.Stop(hups)
+
+	pidFile := filepath.Join(dir, "server.pid")
+	os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	marker := filepath.Join(dir, "reloaded")
+	opts := filesync.Options{PIDFile: pidFile, Hook: []string{"touch", marker}}
+
+	if _, err := filesync.Sync(context.Background(), sess, 1, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hups:
+	case <-time.After(2 * time.Second):
+		t.Error("no SIGHUP received")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("hook did not run:", err)
+	}
+
+	// Nothing changed, so nothing is notified.
+	os.Remove(marker)
+	if _, err := filesync.Sync(context.Background(), sess, 1, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("hook ran without a change")
+	}
+}
@@ -0,0 +1,252 @@
+package tinycert
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// CAFilter selects CAs client-side; a zero CAFilter matches every CA.
+type CAFilter struct {
+	Name *regexp.Regexp
+}
+
+func (f CAFilter) Match(item *CAListItem) bool {
+	return f.Name == nil || f.Name.MatchString(item.Name)
+}
+
+// CertificateFilter selects certificates. Status is sent to the server (zero
+// means AnyStatus); the other fields are applied client-side, and zero values
+// match everything. The expiry window includes ExpiresAfter and excludes
+// ExpiresBefore.
+type CertificateFilter struct {
+	Name          *regexp.Regexp
+	CommonName    string
+	Status        CertificateStatus
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+}
+
+func (f CertificateFilter) Match(item *CertificateListItem) bool {
+	if f.Name != nil && !f.Name.MatchString(item.Name) {
+		return false
+	}
+	if f.CommonName != "" && item.Name != f.CommonName {
+		return false
+	}
+	if f.Status != 0 && !f.Status.Has(item.Status) {
+		return false
+	}
+	if !f.ExpiresAfter.IsZero() && item.ExpiresAt.Before(f.ExpiresAfter) {
+		return false
+	}
+	if !f.ExpiresBefore.IsZero() && !item.ExpiresAt.Before(f.ExpiresBefore) {
+		return false
+	}
+	return true
+}
+
+func (f CertificateFilter) status() CertificateStatus {
+	if f.Status == 0 {
+		return AnyStatus
+	}
+	return f.Status
+}
+
+func (ca *CA) ListFiltered(filter CAFilter) (items []*CAListItem, err error) {
+	return ca.ListFilteredCtx(context.Background(), filter)
+}
+
+func (ca *CA) ListFilteredCtx(ctx context.Context, filter CAFilter) (items []*CAListItem, err error) {
+	all, err := ca.ListCtx(ctx)
+	if err != nil {
+		return
+	}
+	for _, item := range all {
+		if filter.Match(item) {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+func (c *Certificate) ListFiltered(caId int64, filter CertificateFilter) (items []*CertificateListItem, err error) {
+	return c.ListFilteredCtx(context.Background(), caId, filter)
+}
+
+func (c *Certificate) ListFilteredCtx(ctx context.Context, caId int64, filter CertificateFilter) (items []*CertificateListItem, err error) {
+	all, err := c.ListCtx(ctx, caId, filter.status())
+	if err != nil {
+		return
+	}
+	for _, item := range all {
+		if filter.Match(item) {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// Pager walks a list a page at a time:
+//
+//	pages := cert.ListPages(caId, filter, 50)
+//	for pages.Next() {
+//		for _, item := range pages.Page() {
+//			...
+//		}
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+//
+// The TinyCert API has no server-side paging, so the list is fetched by the
+// first call to Next; paging bounds how much callers process at once.
+type Pager[T any] struct {
+	fetch func() ([]T, error)
+	size  int
+	items []T
+	page  []T
+	err   error
+}
+
+func newPager[T any](size int, fetch func() ([]T, error)) *Pager[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &Pager[T]{fetch: fetch, size: size}
+}
+
+// Next advances to the next page, returning false when there are no more
+// pages or the list could not be fetched.
+func (p *Pager[T]) Next() bool {
+	if p.fetch != nil {
+		p.items, p.err = p.fetch()
+		p.fetch = nil
+	}
+	if p.err != nil || len(p.items) == 0 {
+		p.page = nil
+		return false
+	}
+
+	n := min(p.size, len(p.items))
+	p.page, p.items = p.items[:n:n], p.items[n:]
+	return true
+}
+
+func (p *Pager[T]) Page() []T {
+	return p.page
+}
+
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+func (ca *CA) ListPages(filter CAFilter, pageSize int) *Pager[*CAListItem] {
+	return ca.ListPagesCtx(context.Background(), filter, pageSize)
+}
+
+func (ca *CA) ListPagesCtx(ctx context.Context, filter CAFilter, pageSize int) *Pager[*CAListItem] {
+	return newPager(pageSize, func() ([]*CAListItem, error) {
+		return ca.ListFilteredCtx(ctx, filter)
+	})
+}
+
+func (c *Certificate) ListPages(caId int64, filter CertificateFilter, pageSize int) *Pager[*CertificateListItem] {
+	return c.ListPagesCtx(context.Background(), caId, filter, pageSize)
+}
+
+func (c *Certificate) ListPagesCtx(ctx context.Context, caId int64, filter CertificateFilter, pageSize int) *Pager[*CertificateListItem] {
+	return newPager(pageSize, func() ([]*CertificateListItem, error) {
+		return c.ListFilteredCtx(ctx, caId, filter)
+	})
+}
+
+// DetailedCertificate is a listed certificate with its details.
+type DetailedCertificate struct {
+	Item *CertificateListItem
+	Info *CertificateInfo
+}
+
+// DetailIterator walks a list of certificates with their details:
+//
+//	certs := cert.ListDetailed(caId, tinycert.Good)
+//	for certs.Next() {
+//		fmt.Println(certs.Certificate().Info.Alt)
+//	}
+//	if err := certs.Err(); err != nil {
+//		...
+//	}
+//
+// Details are fetched lazily, WithParallelism certificates at a time, as
+// Next reaches them; iteration stops at the first failure.
+type DetailIterator struct {
+	ctx     context.Context
+	c       *Certificate
+	fetch   func() ([]*CertificateListItem, error)
+	items   []*CertificateListItem
+	batch   []*DetailedCertificate
+	current *DetailedCertificate
+	err     error
+}
+
+func (c *Certificate) ListDetailed(caId int64, status CertificateStatus) *DetailIterator {
+	return c.ListDetailedCtx(context.Background(), caId, status)
+}
+
+// ListDetailedCtx lists the certificates of the CA with status and returns an
+// iterator fetching their details; the list is fetched by the first call to
+// Next.
+func (c *Certificate) ListDetailedCtx(ctx context.Context, caId int64, status CertificateStatus) *DetailIterator {
+	return &DetailIterator{ctx: ctx, c: c, fetch: func() ([]*CertificateListItem, error) {
+		return c.ListCtx(ctx, caId, status)
+	}}
+}
+
+// Next advances to the next certificate, returning false when there are no
+// more or one could not be fetched.
+func (it *DetailIterator) Next() bool {
+	if it.fetch != nil {
+		it.items, it.err = it.fetch()
+		it.fetch = nil
+	}
+	if it.err == nil && len(it.batch) == 0 && len(it.items) > 0 {
+		it.fetchBatch()
+	}
+	if it.err != nil || len(it.batch) == 0 {
+		it.current = nil
+		return false
+	}
+	it.current, it.batch = it.batch[0], it.batch[1:]
+	return true
+}
+
+// fetchBatch fetches the details of the next WithParallelism items at once.
+func (it *DetailIterator) fetchBatch() {
+	n := min(max(it.c.parallelism, 1), len(it.items))
+	items := it.items[:n]
+	it.items = it.items[n:]
+
+	batch := make([]*DetailedCertificate, n)
+	errs := make([]error, n)
+	it.c.forEach(it.ctx, n, func(ctx context.Context, i int) {
+		var info *CertificateInfo
+		info, errs[i] = it.c.DetailsCtx(ctx, items[i].Id)
+		batch[i] = &DetailedCertificate{Item: items[i], Info: info}
+	})
+	for _, err := range errs {
+		if err != nil {
+			it.err = err
+			return
+		}
+	}
+	it.batch = batch
+}
+
+// Certificate returns the certificate Next advanced to.
+func (it *DetailIterator) Certificate() *DetailedCertificate {
+	return it.current
+}
+
+func (it *DetailIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,84 @@
+package tinycert
+
+import (
+	"context"
+	"fmt"
+)
+
+// findPageSize is how many certificates find fetches details for at once.
+const findPageSize = 50
+
+func (c *Certificate) FindByCN(caId int64, cn string) (certs []*CertificateInfo, err error) {
+	return c.FindByCNCtx(context.Background(), caId, cn)
+}
+
+// FindByCNCtx returns every certificate under the CA, in any status, whose
+// common name is cn. Names are compared in the normalized form Create sends,
+// so "WWW.Example.com." finds www.example.com.
+func (c *Certificate) FindByCNCtx(ctx context.Context, caId int64, cn string) (certs []*CertificateInfo, err error) {
+	want, err := normalizeCommonName(cn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: common name: %v", ErrInvalidRequest, err)
+	}
+
+	candidate := func(item *CertificateListItem) bool {
+		name, err := normalizeCommonName(item.Name)
+		return err == nil && name == want
+	}
+	return c.find(ctx, caId, candidate, func(info *CertificateInfo) bool {
+		name, err := normalizeCommonName(info.CommonName)
+		return err == nil && name == want
+	})
+}
+
+func (c *Certificate) FindBySAN(caId int64, san SAN) (certs []*CertificateInfo, err error) {
+	return c.FindBySANCtx(context.Background(), caId, san)
+}
+
+// FindBySANCtx returns every certificate under the CA, in any status, that
+// carries san. cert/list does not report SANs, so the details of every
+// certificate are fetched, WithParallelism at a time.
+func (c *Certificate) FindBySANCtx(ctx context.Context, caId int64, san SAN) (certs []*CertificateInfo, err error) {
+	want, err := san.Normalize()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	return c.find(ctx, caId, nil, func(info *CertificateInfo) bool {
+		for _, alt := range info.Alt {
+			if n, err := alt.Normalize(); err == nil && n == want {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// find pages through the CA's certificates, fetches the details of those
+// candidate accepts (all when it is nil) and returns the ones match accepts.
+func (c *Certificate) find(ctx context.Context, caId int64, candidate func(*CertificateListItem) bool, match func(*CertificateInfo) bool) (certs []*CertificateInfo, err error) {
+	pages := c.ListPagesCtx(ctx, caId, CertificateFilter{}, findPageSize)
+	for pages.Next() {
+		var items []*CertificateListItem
+		for _, item := range pages.Page() {
+			if candidate == nil || candidate(item) {
+				items = append(items, item)
+			}
+		}
+
+		infos := make([]*CertificateInfo, len(items))
+		errs := make([]error, len(items))
+		c.forEach(ctx, len(items), func(ctx context.Context, i int) {
+			infos[i], errs[i] = c.DetailsCtx(ctx, items[i].Id)
+		})
+		for i, info := range infos {
+			if errs[i] != nil {
+				return nil, errs[i]
+			}
+			if match(info) {
+				certs = append(certs, info)
+			}
+		}
+	}
+	return certs, pages.Err()
+}
@@ -0,0 +1,43 @@
+package tinycert_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_Find(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+
+	other, err := cert.Create(caId, tinycert.CertRequest{
+		CommonName: "api.example.com",
+		Alt:        []tinycert.SAN{{DNS: "api.example.com"}, {IP: "10.0.0.7"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := cert.FindByCN(caId, "WWW.Example.com.")
+	if err != nil || len(found) != 1 || found[0].Id != certId {
+		t.Errorf("FindByCN() = %v, %v", found, err)
+	}
+	if found, err = cert.FindByCN(caId, "nothing.example.com"); err != nil || len(found) != 0 {
+		t.Errorf("FindByCN(nothing) = %v, %v", found, err)
+	}
+
+	found, err = cert.FindBySAN(caId, tinycert.SAN{IP: "::ffff:10.0.0.7"})
+	if err != nil || len(found) != 1 || found[0].Id != *other {
+		t.Errorf("FindBySAN() = %v, %v", found, err)
+	}
+
+	if _, err = cert.FindBySAN(caId, tinycert.SAN{}); !errors.Is(err, tinycert.ErrInvalidRequest) {
+		t.Errorf("FindBySAN(empty) = %v, want ErrInvalidRequest", err)
+	}
+	if _, err = cert.FindByCN(caId, "*.*.example.com"); !errors.Is(err, tinycert.ErrInvalidRequest) {
+		t.Errorf("FindByCN(bad wildcard) = %v, want ErrInvalidRequest", err)
+	}
+}
@@ -0,0 +1,124 @@
+package tinycert_test
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFixtures_Decode(t *testing.T) {
+	ptr := func(v int64) *int64 { return &v }
+	str := func(v string) *string { return &v }
+
+	tests := []struct {
+		endpoint string
+		fixture  string
+		call     func(sess *tinycert.Session) (interface{}, error)
+		want     interface{}
+	}{
+		{"connect", "connect.json", func(sess *tinycert.Session) (interface{}, error) {
+			return nil, sess.Connect()
+		}, nil},
+		{"disconnect", "disconnect.json", func(sess *tinycert.Session) (interface{}, error) {
+			return nil, sess.Disconnect()
+		}, nil},
+		{"ca/new", "ca_new.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCA(sess).Create(tinycert.CARequest{OrgName: "Acme", Locality: "San Jose", StateCode: "CA", CountryCode: "US"})
+		}, ptr(1234)},
+		{"ca/list", "ca_list.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCA(sess).List()
+		}, []*tinycert.CAListItem{{Id: 1234, Name: "Acme Root CA"}, {Id: 1235, Name: "Acme Test CA"}}},
+		{"ca/details", "ca_details.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCA(sess).Details(1234)
+		}, &tinycert.CAInfo{Id: 1234, CountryCode: "US", StateCode: "CA", Locality: "San Jose", OrgName: "Acme", OrgUnit: "Security", CommonName: "Acme Root CA", Email: "pki@acme.example", HashAlgorithm: "SHA256"}},
+		{"ca/get", "ca_get.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCA(sess).Get(1234)
+		}, str("-----BEGIN CERTIFICATE-----\nMIIBfake\n-----END CERTIFICATE-----\n")},
+		{"ca/delete", "ca_delete.json", func(sess *tinycert.Session) (interface{}, error) {
+			return nil, tinycert.NewCA(sess).Delete(1234)
+		}, nil},
+		{"cert/new", "cert_new.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCertificate(sess).Create(1234, tinycert.CertRequest{CommonName: "www.example.com"})
+		}, ptr(2101)},
+		{"cert/get", "cert_get.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCertificate(sess).Get(2101, tinycert.Cert)
+		}, str("-----BEGIN CERTIFICATE-----\nMIICfake\n-----END CERTIFICATE-----\n")},
+		{"cert/get", "cert_get_pkcs12.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCertificate(sess).Get(2101, tinycert.PKCS12)
+		}, str("MIIKfakepkcs12")},
+		{"cert/details", "cert_details.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCertificate(sess).Details(2101)
+		}, &tinycert.CertificateInfo{Id: 2101, Status: tinycert.Good, CountryCode: "US", StateCode: "CA", Locality: "San Jose", OrgName: "Acme", OrgUnit: "Web", CommonName: "www.example.com", Alt: []tinycert.SAN{{DNS: "www.example.com"}, {IP: "10.0.0.1"}}}},
+		{"cert/list", "cert_list.json", func(sess *tinycert.Session) (interface{}, error) {
+			items, err := tinycert.NewCertificate(sess).List(1234, tinycert.Good|tinycert.Revoked|tinycert.Expired|tinycert.Hold)
+			if len(items) > 0 {
+				items = items[:1]
+			}
+			return items, err
+		}, []*tinycert.CertificateListItem{{Id: 2101, Name: "www.example.com", Status: tinycert.Good, Expires: 1767225600, ExpiresAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+		{"cert/reissue", "cert_reissue.json", func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCertificate(sess).Reissue(2101)
+		}, ptr(2105)},
+		{"cert/status", "cert_status.json", func(sess *tinycert.Session) (interface{}, error) {
+			return nil, tinycert.NewCertificate(sess).WithTransitionCheck(false).Status(2101, tinycert.Hold)
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSuffix(tt.fixture, ".json"), func(t *testing.T) {
+			fs := newFakeServer(t)
+			sess := fs.connectedSession().WithStrictDecoding(true)
+
+			body := readFixture(t, tt.fixture)
+			fs.setFail(func(api string) (int, string) {
+				if api == tt.endpoint {
+					return http.StatusOK, body
+				}
+				return 0, ""
+			})
+
+			got, err := tt.call(sess)
+			if err != nil {
+				t.Fatal("unable to decode fixture", err)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %s:\n got %#v\nwant %#v", tt.fixture, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixtures_StrictDecoding(t *testing.T) {
+	fs := newFakeServer(t)
+	body := readFixture(t, "ca_details_drift.json")
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/details" {
+			return http.StatusOK, body
+		}
+		return 0, ""
+	})
+
+	sess := fs.connectedSession()
+	if _, err := tinycert.NewCA(sess).Details(1234); err != nil {
+		t.Fatal("lenient decoding should ignore unknown fields", err)
+	}
+
+	sess.WithStrictDecoding(true)
+	if _, err := tinycert.NewCA(sess).Details(1234); err == nil || !strings.Contains(err.Error(), "valid_until") {
+		t.Fatal("strict decoding should reject unknown fields, got", err)
+	}
+}
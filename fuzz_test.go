@@ -0,0 +1,91 @@
+package tinycert_test
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// FuzzSigner_Encode checks that any set of fields encodes to a body that
+// decodes back to the same fields and carries the digest the fake server
+// computes for it.
+func FuzzSigner_Encode(f *testing.F) {
+	f.Add("apikey", "CN", "www.example.com", int64(123), true)
+	f.Add("", "O", "Acme & Co", int64(-1), false)
+	f.Add("k\x00ey", "SANs[0][DNS]", "ünïcödé=+%20", int64(0), true)
+
+	f.Fuzz(func(t *testing.T, apiKey, name, value string, id int64, flag bool) {
+		if name == "digest" || name == "ca_id" || name == "force" {
+			t.Skip()
+		}
+		fields := tinycert.Fields{}.Set(name, value).SetInt("ca_id", id).SetBool("force", flag).Values()
+
+		body := tinycert.NewSigner(apiKey).Encode(fields)
+		decoded, err := url.ParseQuery(body)
+		if err != nil {
+			t.Fatalf("body %q does not decode: %v", body, err)
+		}
+		for field, want := range fields {
+			if got := decoded[field]; len(got) != 1 || got[0] != want[0] {
+				t.Errorf("field %q = %q, want %q", field, got, want)
+			}
+		}
+		if len(decoded) != len(fields)+1 {
+			t.Errorf("body %q has %d fields, want %d", body, len(decoded), len(fields)+1)
+		}
+		if digest := decoded.Get("digest"); digest != signForm(decoded, apiKey) {
+			t.Errorf("digest %s does not match the payload", digest)
+		}
+		if !tinycert.NewSigner(apiKey).Verify(decoded) {
+			t.Error("Verify rejected the encoded body")
+		}
+	})
+}
+
+// FuzzResponseDecoding feeds arbitrary responses to the client; whatever
+// the server says, calls must fail or succeed without panicking.
+func FuzzResponseDecoding(f *testing.F) {
+	for _, body := range []string{
+		`{"ca_id": 1, "C": "US", "CN": "Acme"}`,
+		`[{"id": 1, "name": "www.example.com", "status": "good", "expires": 1700000000}]`,
+		`{"code": 404, "text": "not found"}`,
+		`{"code": "error", "text": 7}`,
+		`{"pkcs12": "MIIC\/AgEDMIIC"}`,
+		`{"pkcs12": "A", "pem": null}`,
+		`{"status": [1, {"a": "}"}], "pkcs12": "`,
+		`{"pem": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"}`,
+		`null`,
+		``,
+	} {
+		f.Add(http.StatusOK, body)
+	}
+	f.Add(http.StatusInternalServerError, `<html>oops</html>`)
+	f.Add(http.StatusTooManyRequests, `{"code": 429}`)
+
+	f.Fuzz(func(t *testing.T, status int, body string) {
+		if status < 200 || status > 599 {
+			t.Skip()
+		}
+		fs := newFakeServer(t)
+		sess := fs.connectedSession().WithRetryPolicy(tinycert.RetryPolicy{})
+		fs.setFail(func(api string) (int, string) { return status, body })
+
+		ca := tinycert.NewCA(sess)
+		cert := tinycert.NewCertificate(sess)
+		ca.List()
+		ca.Details(1)
+		ca.Get(1)
+		cert.List(1, tinycert.AnyStatus)
+		cert.Details(1)
+		cert.Get(1, tinycert.Chain)
+		cert.GetPKCS12DER(1)
+		cert.WritePKCS12To(1, io.Discard)
+		if r := cert.GetPKCS12Reader(1); r != nil {
+			io.Copy(io.Discard, r)
+			r.Close()
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package tinycert
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Bundle holds every artifact of a certificate, as returned by GetAll.
+type Bundle struct {
+	Cert  string
+	Chain string
+	// Key is the decrypted private key PEM.
+	Key SecureString
+	// PKCS12 is the DER-encoded archive; see GetPKCS12 to decode it.
+	PKCS12 []byte
+}
+
+func (c *Certificate) GetAll(certId int64) (bundle *Bundle, err error) {
+	return c.GetAllCtx(context.Background(), certId)
+}
+
+// GetAllCtx fetches the certificate, chain, decrypted key and PKCS#12 archive
+// concurrently, running at most WithParallelism calls at once. The first
+// failure cancels the remaining fetches.
+func (c *Certificate) GetAllCtx(ctx context.Context, certId int64) (bundle *Bundle, err error) {
+	b := &Bundle{}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(c.parallelism, 1))
+
+	for what, dst := range map[Artifact]*string{Cert: &b.Cert, Chain: &b.Chain} {
+		g.Go(func() error {
+			pem, err := c.GetCtx(ctx, certId, what)
+			if err != nil {
+				return err
+			}
+			*dst = *pem
+			return nil
+		})
+	}
+	g.Go(func() error {
+		pem, err := c.GetCtx(ctx, certId, KeyDecrypted)
+		if err != nil {
+			return err
+		}
+		b.Key = NewSecureString(*pem)
+		return nil
+	})
+	g.Go(func() (err error) {
+		b.PKCS12, err = c.GetPKCS12DERCtx(ctx, certId)
+		return
+	})
+
+	if err = g.Wait(); err != nil {
+		return
+	}
+	return b, nil
+}
@@ -0,0 +1,58 @@
+package tinycert_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestCertificate_GetAll(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	bundle, err := tinycert.NewCertificate(sess).GetAll(certId)
+	if err != nil {
+		t.Fatal("unable to fetch bundle", err)
+	}
+
+	cert, err := parseCert(bundle.Cert)
+	if err != nil {
+		t.Fatal("unable to parse certificate", err)
+	}
+	if cert.Subject.CommonName != "www.example.com" {
+		t.Errorf("common name = %q", cert.Subject.CommonName)
+	}
+	if len(parseCerts(t, bundle.Chain)) < 1 {
+		t.Error("empty chain")
+	}
+	if bundle.Key.IsZero() {
+		t.Error("empty key")
+	}
+	if _, p12Cert, _, err := pkcs12.DecodeChain(bundle.PKCS12, fakePassphrase); err != nil || p12Cert.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Error("pkcs12 does not hold the certificate", err)
+	}
+	if got := fs.callCount("cert/get"); got != 4 {
+		t.Errorf("cert/get called %d times, want 4", got)
+	}
+}
+
+func TestCertificate_GetAllError(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/get" {
+			return http.StatusNotFound, `{"code":"404","text":"no such certificate"}`
+		}
+		return 0, ""
+	})
+
+	if _, err := tinycert.NewCertificate(sess).GetAll(certId); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Error("expected ErrNotFound, got", err)
+	}
+}
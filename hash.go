@@ -0,0 +1,65 @@
+package tinycert
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidHashAlgorithm = errors.New("tinycert: invalid hash algorithm")
+
+// HashAlgorithm is the signature hash of a certificate authority. The zero
+// value is SHA256.
+type HashAlgorithm int
+
+const (
+	SHA256 HashAlgorithm = iota
+	SHA384
+	SHA512
+)
+
+func (h HashAlgorithm) String() string {
+	switch h {
+	case SHA256:
+		return "sha256"
+	case SHA384:
+		return "sha384"
+	case SHA512:
+		return "sha512"
+	}
+	return fmt.Sprintf("HashAlgorithm(%d)", int(h))
+}
+
+func (h HashAlgorithm) valid() bool {
+	return h >= SHA256 && h <= SHA512
+}
+
+// ParseHashAlgorithm maps "sha256", "sha384" or "sha512", in any case, to
+// its HashAlgorithm. Anything else, including spellings like "sha-256", is
+// rejected rather than passed on to the API.
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	for h := SHA256; h <= SHA512; h++ {
+		if strings.EqualFold(h.String(), name) {
+			return h, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidHashAlgorithm, name)
+}
+
+func (h HashAlgorithm) MarshalText() ([]byte, error) {
+	if !h.valid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidHashAlgorithm, int(h))
+	}
+	return []byte(h.String()), nil
+}
+
+func (h *HashAlgorithm) UnmarshalText(text []byte) (err error) {
+	*h, err = ParseHashAlgorithm(string(text))
+	return
+}
+
+// ParsedHashAlgorithm returns HashAlgorithm as reported by ca/details, e.g.
+// "SHA256", as a HashAlgorithm.
+func (info *CAInfo) ParsedHashAlgorithm() (HashAlgorithm, error) {
+	return ParseHashAlgorithm(info.HashAlgorithm)
+}
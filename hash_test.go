@@ -0,0 +1,48 @@
+package tinycert_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestParseHashAlgorithm(t *testing.T) {
+	for _, h := range []tinycert.HashAlgorithm{tinycert.SHA256, tinycert.SHA384, tinycert.SHA512} {
+		got, err := tinycert.ParseHashAlgorithm(h.String())
+		if err != nil || got != h {
+			t.Errorf("ParseHashAlgorithm(%q) = %v, %v", h.String(), got, err)
+		}
+	}
+	if got, err := tinycert.ParseHashAlgorithm("SHA512"); err != nil || got != tinycert.SHA512 {
+		t.Errorf("ParseHashAlgorithm(SHA512) = %v, %v", got, err)
+	}
+	for _, name := range []string{"sha-256", "md5", ""} {
+		if _, err := tinycert.ParseHashAlgorithm(name); !errors.Is(err, tinycert.ErrInvalidHashAlgorithm) {
+			t.Errorf("ParseHashAlgorithm(%q): expected ErrInvalidHashAlgorithm, got %v", name, err)
+		}
+	}
+}
+
+func TestHashAlgorithm_JSON(t *testing.T) {
+	var cfg struct {
+		Hash tinycert.HashAlgorithm `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(`{"hash":"sha384"}`), &cfg); err != nil || cfg.Hash != tinycert.SHA384 {
+		t.Errorf("unmarshal = %v, %v", cfg.Hash, err)
+	}
+	if data, err := json.Marshal(cfg); err != nil || string(data) != `{"hash":"sha384"}` {
+		t.Errorf("marshal = %s, %v", data, err)
+	}
+	if err := json.Unmarshal([]byte(`{"hash":"sha-256"}`), &cfg); !errors.Is(err, tinycert.ErrInvalidHashAlgorithm) {
+		t.Error("expected ErrInvalidHashAlgorithm, got", err)
+	}
+}
+
+func TestCAInfo_ParsedHashAlgorithm(t *testing.T) {
+	info := tinycert.CAInfo{HashAlgorithm: "SHA256"}
+	if h, err := info.ParsedHashAlgorithm(); err != nil || h != tinycert.SHA256 {
+		t.Errorf("ParsedHashAlgorithm() = %v, %v", h, err)
+	}
+}
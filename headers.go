@@ -0,0 +1,68 @@
+package tinycert
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+)
+
+// Version is the version of this package. It is sent in the User-Agent
+// header so TinyCert can tell which client made a call.
+const Version = "0.9.0"
+
+// UserAgent returns the User-Agent header sessions send by default, e.g.
+// "tinycert-go/0.9.0 (go1.24.1; linux/amd64)".
+func UserAgent() string {
+	return "tinycert-go/" + Version + " (" + runtime.Version() + "; " + runtime.GOOS + "/" + runtime.GOARCH + ")"
+}
+
+// WithUserAgent replaces the User-Agent header, for CLIs and providers that
+// must identify themselves to TinyCert support. Keeping the library's own
+// token helps both sides:
+//
+//	sess.WithUserAgent("acme-provisioner/1.4 " + tinycert.UserAgent())
+//
+// An empty ua restores the default.
+func (s *Session) WithUserAgent(ua string) *Session {
+	s.userAgent = ua
+	return s
+}
+
+// setHeaders identifies the client and asks for a JSON response, compressed
+// if the server is willing. Go's transport would add the Accept-Encoding
+// header itself, but only for its own transport; asking explicitly keeps
+// compression with custom transports and lets decodeBody bound the size of
+// the decompressed body.
+func (s *Session) setHeaders(req *http.Request) {
+	ua := s.userAgent
+	if ua == "" {
+		ua = UserAgent()
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", ua)
+}
+
+// decodeBody returns the body of resp, decompressed if it is gzipped. An
+// empty body is returned as is whatever its encoding, as some proxies mark
+// empty error responses gzipped.
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	switch resp.Header.Get("Content-Encoding") {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip":
+		body := bufio.NewReader(resp.Body)
+		if _, err := body.Peek(1); err == io.EOF {
+			return body, nil
+		}
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("tinycert: invalid gzip response: %w", err)
+		}
+		return gz, nil
+	}
+	return nil, fmt.Errorf("tinycert: unsupported response encoding %q", resp.Header.Get("Content-Encoding"))
+}
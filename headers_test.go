@@ -0,0 +1,123 @@
+package tinycert_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// gzipServer serves the fake server's responses gzipped to clients that
+// accept it, recording the headers of each request.
+func gzipServer(t *testing.T, fs *fakeServer) (srv *httptest.Server, headers func() []http.Header) {
+	var mu sync.Mutex
+	var seen []http.Header
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Clone())
+		mu.Unlock()
+
+		rec := httptest.NewRecorder()
+		fs.Config.Handler.ServeHTTP(rec, r)
+		for name, values := range rec.Header() {
+			w.Header()[name] = values
+		}
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+			return
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(rec.Body.Bytes())
+		gz.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(rec.Code)
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+}
+
+func TestSession_CompressedResponses(t *testing.T) {
+	fs := newFakeServer(t)
+	srv, headers := gzipServer(t, fs)
+
+	sess := fs.session().WithBaseURL(srv.URL + "/api")
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	caId, certId := newCAAndCert(t, sess)
+	if items, err := tinycert.NewCertificate(sess).List(caId, tinycert.Good); err != nil || len(items) != 1 || items[0].Id != certId {
+		t.Errorf("list = %v, %v", items, err)
+	}
+
+	for _, h := range headers() {
+		if h.Get("Accept") != "application/json" || h.Get("Accept-Encoding") != "gzip" || !strings.HasPrefix(h.Get("User-Agent"), "tinycert-go/") {
+			t.Fatalf("request headers = %v", h)
+		}
+	}
+
+	// Errors are decompressed too, and the size limit applies to the
+	// decompressed body.
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" {
+			return http.StatusOK, `{"code":"400","text":"` + strings.Repeat("x", 4096) + `"}`
+		}
+		return 0, ""
+	})
+	ca := tinycert.NewCA(sess.WithRetryPolicy(tinycert.RetryPolicy{}))
+	var apiErr *tinycert.APIError
+	if _, err := ca.List(); !errors.As(err, &apiErr) {
+		t.Errorf("err = %v, want an APIError", err)
+	}
+	sess.WithMaxResponseSize(1024)
+	if _, err := ca.List(); !errors.Is(err, tinycert.ErrResponseTooLarge) {
+		t.Errorf("err = %v, want ErrResponseTooLarge", err)
+	}
+}
+
+func TestSession_EmptyGzipResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	sess := tinycert.NewSession().WithBaseURL(srv.URL + "/api").WithRetryPolicy(tinycert.RetryPolicy{}).Resume("token")
+	_, err := tinycert.NewCA(sess).List()
+	var apiErr *tinycert.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusBadGateway {
+		t.Errorf("err = %v, want an APIError with status 502", err)
+	}
+}
+
+func TestSession_WithUserAgent(t *testing.T) {
+	fs := newFakeServer(t)
+	srv, headers := gzipServer(t, fs)
+
+	sess := fs.session().WithBaseURL(srv.URL + "/api").WithUserAgent("acme-provisioner/1.4 " + tinycert.UserAgent())
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	want := "acme-provisioner/1.4 tinycert-go/" + tinycert.Version + " ("
+	if got := headers()[0].Get("User-Agent"); !strings.HasPrefix(got, want) {
+		t.Errorf("User-Agent = %q, want prefix %q", got, want)
+	}
+
+	sess.WithUserAgent("")
+	tinycert.NewCA(sess).List()
+	if got := headers()[1].Get("User-Agent"); got != tinycert.UserAgent() {
+		t.Errorf("User-Agent = %q, want the default", got)
+	}
+}
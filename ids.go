@@ -0,0 +1,61 @@
+package tinycert
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// idFields are the response fields holding CA and certificate ids.
+var idFields = map[string]bool{
+	"id":      true,
+	"ca_id":   true,
+	"cert_id": true,
+}
+
+// normalizeIDs rewrites ids the API sent as numeric strings, e.g.
+// {"cert_id":"2101"}, as numbers, so they decode into the int64 Id fields
+// either way. This is done on the response rather than with UnmarshalJSON
+// methods so that WithStrictDecoding still applies to the types, and types
+// embedding them, such as CertificateDescription, keep their own decoding.
+// Bodies without such ids are returned as they are.
+func normalizeIDs(body []byte) []byte {
+	if !bytes.Contains(body, []byte(`id"`)) {
+		return body
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || !normalizeValue(v) {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// normalizeValue replaces numeric string ids in v and reports whether it
+// replaced any.
+func normalizeValue(v any) (changed bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		for name, value := range v {
+			if s, ok := value.(string); ok && idFields[name] {
+				if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+					v[name] = json.Number(s)
+					changed = true
+				}
+				continue
+			}
+			changed = normalizeValue(value) || changed
+		}
+	case []any:
+		for _, value := range v {
+			changed = normalizeValue(value) || changed
+		}
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package tinycert_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestDecode_NumericStringIDs(t *testing.T) {
+	createCA := func(sess *tinycert.Session) (interface{}, error) {
+		id, err := tinycert.NewCA(sess).Create(tinycert.CARequest{OrgName: "Acme", Locality: "San Jose", StateCode: "CA", CountryCode: "US"})
+		if err != nil {
+			return nil, err
+		}
+		return *id, nil
+	}
+	tests := []struct {
+		name     string
+		endpoint string
+		body     string
+		call     func(sess *tinycert.Session) (interface{}, error)
+		want     interface{}
+	}{
+		{"ca/new number", "ca/new", `{"ca_id": 1234}`, createCA, int64(1234)},
+		{"ca/new string", "ca/new", `{"ca_id": "1234"}`, createCA, int64(1234)},
+		{"cert/reissue string", "cert/reissue", `{"cert_id": "2105"}`, func(sess *tinycert.Session) (interface{}, error) {
+			id, err := tinycert.NewCertificate(sess).Reissue(2101)
+			if err != nil {
+				return nil, err
+			}
+			return *id, nil
+		}, int64(2105)},
+		{"ca/list mixed", "ca/list", `[{"id": 1234, "name": "a"}, {"id": "1235", "name": "b"}]`, func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCA(sess).List()
+		}, []*tinycert.CAListItem{{Id: 1234, Name: "a"}, {Id: 1235, Name: "b"}}},
+		{"cert/details string", "cert/details", `{"id": "2101", "status": "good", "CN": "www.example.com", "alt": [{"DNS": "www.example.com"}]}`, func(sess *tinycert.Session) (interface{}, error) {
+			return tinycert.NewCertificate(sess).Details(2101)
+		}, &tinycert.CertificateInfo{Id: 2101, Status: tinycert.Good, CommonName: "www.example.com", Alt: []tinycert.SAN{{DNS: "www.example.com"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			sess := fs.connectedSession().WithStrictDecoding(true)
+			fs.setFail(func(api string) (int, string) {
+				if api == tt.endpoint {
+					return http.StatusOK, tt.body
+				}
+				return 0, ""
+			})
+
+			got, err := tt.call(sess)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode_NonNumericStringID(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" {
+			return http.StatusOK, `[{"id": "abc", "name": "a"}]`
+		}
+		return 0, ""
+	})
+
+	if _, err := tinycert.NewCA(sess).List(); err == nil {
+		t.Fatal("expected an id that is not a number to fail")
+	}
+}
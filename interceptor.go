@@ -0,0 +1,40 @@
+package tinycert
+
+import (
+	"context"
+	"net/http"
+)
+
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Interceptor wraps the HTTP round trip of every API call. It may inspect or
+// modify the request, call next to continue the chain (or skip it to serve a
+// cached response), and inspect the response.
+type Interceptor func(req *http.Request, next RoundTripFunc) (*http.Response, error)
+
+type endpointKey struct{}
+
+// Endpoint returns the TinyCert API endpoint, e.g. "cert/get", of the request
+// an interceptor is handling.
+func Endpoint(ctx context.Context) string {
+	api, _ := ctx.Value(endpointKey{}).(string)
+	return api
+}
+
+// WithInterceptor appends interceptors to the session's chain. The first
+// interceptor added is the outermost.
+func (s *Session) WithInterceptor(interceptors ...Interceptor) *Session {
+	s.interceptors = append(s.interceptors, interceptors...)
+	return s
+}
+
+func (s *Session) roundTrip(req *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(s.client(req.Context(), Endpoint(req.Context())).Do)
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := s.interceptors[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return interceptor(req, inner)
+		}
+	}
+	return next(req)
+}
@@ -0,0 +1,56 @@
+package tinycert_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_Interceptors(t *testing.T) {
+	fs := newFakeServer(t)
+
+	var order []string
+	record := func(name string) tinycert.Interceptor {
+		return func(req *http.Request, next tinycert.RoundTripFunc) (*http.Response, error) {
+			order = append(order, name+">"+tinycert.Endpoint(req.Context()))
+			req.Header.Set("X-Trace-"+name, "1")
+			resp, err := next(req)
+			order = append(order, name+"<")
+			return resp, err
+		}
+	}
+	cached := func(req *http.Request, next tinycert.RoundTripFunc) (*http.Response, error) {
+		if tinycert.Endpoint(req.Context()) != "ca/list" {
+			return next(req)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`[{"id":7,"name":"cached"}]`)),
+			Request:    req,
+		}, nil
+	}
+
+	sess := fs.session().WithInterceptor(record("outer"), record("inner"), cached)
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := tinycert.NewCA(sess).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Name != "cached" {
+		t.Errorf("expected cached response, got %+v", items)
+	}
+	if got := fs.callCount("ca/list"); got != 0 {
+		t.Errorf("ca/list reached server %d times, want 0", got)
+	}
+
+	want := "outer>connect inner>connect inner< outer< outer>ca/list inner>ca/list inner< outer<"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("interceptor order = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// endpoints lists the API calls whose client methods are generated into
+// endpoints_gen.go. Calls that need more than sending their parameters and
+// decoding the response, such as caching or emitting events, are written by
+// hand in lib.go instead.
+var endpoints = []endpoint{
+	{
+		Type:     "CA",
+		Method:   "Details",
+		API:      "ca/details",
+		Params:   []param{{Name: "caId", Field: "ca_id", Type: "int64"}},
+		Response: "CAInfo",
+		Result:   "caInfo",
+	},
+	{
+		Type:     "Certificate",
+		Method:   "Details",
+		API:      "cert/details",
+		Params:   []param{{Name: "certId", Field: "cert_id", Type: "int64"}},
+		Response: "CertificateInfo",
+		Result:   "certInfo",
+	},
+}
@@ -0,0 +1,131 @@
+// Command apigen generates the client methods of the TinyCert API calls
+// listed in endpoints.go. Each endpoint gets a method on its type that sends
+// the parameters as form fields and decodes the response, and its Ctx
+// variant:
+//
+//	go generate github.com/srohatgi/tinycert
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// endpoint describes one API call.
+type endpoint struct {
+	// Type is the client type the methods are added to, CA or Certificate.
+	Type string
+	// Method names the methods; the second one has a Ctx suffix.
+	Method string
+	// Doc, if set, documents the Ctx method.
+	Doc string
+	// API is the call's path, e.g. "ca/details".
+	API    string
+	Params []param
+	// Response is the type the response is decoded into and Result names
+	// the pointer to it that the methods return.
+	Response string
+	Result   string
+}
+
+// param is a method parameter sent as a form field. Type is int64, string,
+// bool, or a type whose String method gives the field's value.
+type param struct {
+	Name  string
+	Field string
+	Type  string
+}
+
+// setter returns the Fields method call adding p.
+func (p param) setter() string {
+	switch p.Type {
+	case "int64":
+		return fmt.Sprintf("SetInt(%q, %s)", p.Field, p.Name)
+	case "string":
+		return fmt.Sprintf("Set(%q, %s)", p.Field, p.Name)
+	case "bool":
+		return fmt.Sprintf("SetBool(%q, %s)", p.Field, p.Name)
+	}
+	return fmt.Sprintf("Set(%q, %s.String())", p.Field, p.Name)
+}
+
+var receivers = map[string]string{"CA": "ca", "Certificate": "c"}
+
+var funcs = template.FuncMap{
+	"receiver": func(e endpoint) string { return receivers[e.Type] },
+	"params": func(e endpoint) string {
+		var list []string
+		for _, p := range e.Params {
+			list = append(list, p.Name+" "+p.Type)
+		}
+		return strings.Join(list, ", ")
+	},
+	"args": func(e endpoint) string {
+		var list []string
+		for _, p := range e.Params {
+			list = append(list, ", "+p.Name)
+		}
+		return strings.Join(list, "")
+	},
+	"fields": func(e endpoint) string {
+		fields := "Fields{}"
+		for _, p := range e.Params {
+			fields += "." + p.setter()
+		}
+		return fields + ".Values()"
+	},
+	"comment": func(doc string) string {
+		return "// " + strings.ReplaceAll(strings.TrimSpace(doc), "\n", "\n// ")
+	},
+}
+
+var source = template.Must(template.New("").Funcs(funcs).Parse(`// Code generated by apigen from internal/apigen/endpoints.go; DO NOT EDIT.
+
+package tinycert
+
+import "context"
+{{range .}}{{$r := receiver .}}
+func ({{$r}} *{{.Type}}) {{.Method}}({{params .}}) ({{.Result}} *{{.Response}}, err error) {
+	return {{$r}}.{{.Method}}Ctx(context.Background(){{args .}})
+}
+{{if .Doc}}
+{{comment .Doc}}{{end}}
+func ({{$r}} *{{.Type}}) {{.Method}}Ctx(ctx context.Context{{if .Params}}, {{params .}}{{end}}) ({{.Result}} *{{.Response}}, err error) {
+	return call[{{.Response}}](ctx, {{$r}}.session, {{printf "%q" .API}}, {{fields .}})
+}
+{{end}}`))
+
+func generate(endpoints []endpoint) ([]byte, error) {
+	for _, e := range endpoints {
+		if receivers[e.Type] == "" {
+			return nil, fmt.Errorf("endpoint %s: unknown type %q", e.API, e.Type)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := source.Execute(&buf, endpoints); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	out := flag.String("o", "endpoints_gen.go", "output file")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("apigen: ")
+
+	src, err := generate(endpoints)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGenerate_UpToDate(t *testing.T) {
+	want, err := generate(endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../../endpoints_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("endpoints_gen.go is stale; run go generate")
+	}
+}
+
+func TestGenerate_Params(t *testing.T) {
+	src, err := generate([]endpoint{{
+		Type:   "Certificate",
+		Method: "Frobnicate",
+		Doc:    "FrobnicateCtx frobnicates a certificate.",
+		API:    "cert/frobnicate",
+		Params: []param{
+			{Name: "certId", Field: "cert_id", Type: "int64"},
+			{Name: "note", Field: "note", Type: "string"},
+			{Name: "force", Field: "force", Type: "bool"},
+			{Name: "what", Field: "what", Type: "Artifact"},
+		},
+		Response: "CertificateInfo",
+		Result:   "info",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"func (c *Certificate) Frobnicate(certId int64, note string, force bool, what Artifact) (info *CertificateInfo, err error) {",
+		"return c.FrobnicateCtx(context.Background(), certId, note, force, what)",
+		"// FrobnicateCtx frobnicates a certificate.\nfunc (c *Certificate) FrobnicateCtx(ctx context.Context, certId int64, note string, force bool, what Artifact)",
+		`Fields{}.SetInt("cert_id", certId).Set("note", note).SetBool("force", force).Set("what", what.String()).Values()`,
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated source lacks %q:\n%s", want, src)
+		}
+	}
+
+	if _, err := generate([]endpoint{{Type: "Session", API: "x/y"}}); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
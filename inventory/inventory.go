@@ -0,0 +1,169 @@
+// Package inventory collects every CA and certificate of a TinyCert account
+// into one typed value, and renders it as JSON, CSV or Markdown tables for
+// runbooks and dashboards.
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// Inventory is the CAs of an account with their certificates.
+type Inventory struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	CAs         []CA      `json:"cas"`
+}
+
+// CA is a CA and the certificates it issued.
+type CA struct {
+	Info         tinycert.CAInfo `json:"info"`
+	Certificates []Certificate   `json:"certificates"`
+}
+
+// Certificate is a certificate with its details.
+type Certificate struct {
+	Info      tinycert.CertificateInfo `json:"info"`
+	ExpiresAt time.Time                `json:"expires_at"`
+}
+
+// Collect fetches the details of every CA of the account and of each of
+// their certificates, in any status. The details of the certificates of a CA
+// are fetched WithParallelism at a time.
+func Collect(ctx context.Context, session *tinycert.Session) (inv *Inventory, err error) {
+	ca := tinycert.NewCA(session)
+	cert := tinycert.NewCertificate(session)
+	items, err := ca.ListCtx(ctx)
+	if err != nil {
+		return
+	}
+
+	inv = &Inventory{GeneratedAt: time.Now().UTC(), CAs: []CA{}}
+	for _, item := range items {
+		var info *tinycert.CAInfo
+		if info, err = ca.DetailsCtx(ctx, item.Id); err != nil {
+			return nil, err
+		}
+		entry := CA{Info: *info, Certificates: []Certificate{}}
+
+		certs := cert.ListDetailedCtx(ctx, item.Id, tinycert.AnyStatus)
+		for certs.Next() {
+			c := certs.Certificate()
+			entry.Certificates = append(entry.Certificates, Certificate{Info: *c.Info, ExpiresAt: c.Item.ExpiresAt})
+		}
+		if err = certs.Err(); err != nil {
+			return nil, err
+		}
+		inv.CAs = append(inv.CAs, entry)
+	}
+	return
+}
+
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatCSV
+	FormatMarkdown
+)
+
+// ParseFormat parses "json", "csv" or "markdown".
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	}
+	return 0, fmt.Errorf("inventory: unknown format %q", name)
+}
+
+var csvHeader = []string{"ca_id", "ca_name", "cert_id", "common_name", "status", "expires_at", "sans"}
+
+// Render writes the inventory in format. CSV has a row per certificate, with
+// the CA's id and common name on each; Markdown has a section per CA with a
+// table of its certificates.
+func (inv *Inventory) Render(format Format) ([]byte, error) {
+	var b bytes.Buffer
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(&b)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(inv); err != nil {
+			return nil, err
+		}
+	case FormatCSV:
+		cw := csv.NewWriter(&b)
+		cw.Write(csvHeader)
+		for _, ca := range inv.CAs {
+			for _, cert := range ca.Certificates {
+				cw.Write([]string{
+					strconv.FormatInt(ca.Info.Id, 10), ca.Info.CommonName, strconv.FormatInt(cert.Info.Id, 10),
+					cert.Info.CommonName, cert.Info.Status.String(), cert.ExpiresAt.Format(time.RFC3339), sans(cert.Info.Alt, " "),
+				})
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return nil, err
+		}
+	case FormatMarkdown:
+		fmt.Fprintf(&b, "# TinyCert inventory\n\nGenerated %s.\n", inv.GeneratedAt.Format(time.RFC3339))
+		for _, ca := range inv.CAs {
+			fmt.Fprintf(&b, "\n## %s (CA %d)\n\n", markdownCell(caTitle(ca.Info)), ca.Info.Id)
+			if len(ca.Certificates) == 0 {
+				b.WriteString("_No certificates._\n")
+				continue
+			}
+			b.WriteString("| Id | Common name | Status | Expires | SANs |\n|---:|---|---|---|---|\n")
+			for _, cert := range ca.Certificates {
+				fmt.Fprintf(&b, "| %d | %s | %s | %s | %s |\n", cert.Info.Id, markdownCell(cert.Info.CommonName), cert.Info.Status,
+					cert.ExpiresAt.Format(time.DateOnly), markdownCell(sans(cert.Info.Alt, ", ")))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("inventory: unknown format %d", int(format))
+	}
+	return b.Bytes(), nil
+}
+
+// caTitle names a CA by its common name, or its organization if it has none.
+func caTitle(info tinycert.CAInfo) string {
+	if info.CommonName != "" {
+		return info.CommonName
+	}
+	return info.OrgName
+}
+
+// sans formats SANs as "DNS:www.example.com", joined with sep.
+func sans(alt []tinycert.SAN, sep string) string {
+	names := make([]string, 0, len(alt))
+	for _, san := range alt {
+		switch {
+		case san.DNS != "":
+			names = append(names, "DNS:"+san.DNS)
+		case san.Email != "":
+			names = append(names, "email:"+san.Email)
+		case san.IP != "":
+			names = append(names, "IP:"+san.IP)
+		case san.URI != "":
+			names = append(names, "URI:"+san.URI)
+		}
+	}
+	return strings.Join(names, sep)
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ")
+
+func markdownCell(value string) string {
+	return markdownEscaper.Replace(value)
+}
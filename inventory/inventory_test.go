@@ -0,0 +1,108 @@
+package inventory_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/inventory"
+)
+
+func newSession(t *testing.T) *tinycert.Session {
+	t.Helper()
+	responses := map[string]string{
+		"ca/list":    `[{"id": 1, "name": "Acme Root"}, {"id": 2, "name": "Empty"}]`,
+		"ca/details": `{"id": 1, "CN": "Acme Root", "O": "Acme", "C": "US", "ST": "CA", "L": "San Jose", "hash_alg": "SHA256"}`,
+		"cert/list":  `[{"id": 10, "name": "www.example.com", "status": "good", "expires": 1767225600}, {"id": 11, "name": "a|b", "status": "revoked", "expires": 1767225600}]`,
+	}
+	details := map[string]string{
+		"10": `{"id": 10, "status": "good", "CN": "www.example.com", "alt": [{"DNS": "www.example.com"}, {"IP": "10.0.0.1"}]}`,
+		"11": `{"id": 11, "status": "revoked", "CN": "a|b", "alt": []}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		api := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+		switch {
+		case api == "ca/details" && r.PostForm.Get("ca_id") == "2":
+			w.Write([]byte(`{"id": 2, "O": "Empty Org"}`))
+		case api == "cert/list" && r.PostForm.Get("ca_id") == "2":
+			w.Write([]byte(`[]`))
+		case api == "cert/details":
+			w.Write([]byte(details[r.PostForm.Get("cert_id")]))
+		default:
+			w.Write([]byte(responses[api]))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return tinycert.NewSession().WithEmail("user@example.com").WithPassphrase("secret").WithApiKey("apikey").
+		WithBaseURL(srv.URL + "/api").Resume("token")
+}
+
+func TestCollectAndRender(t *testing.T) {
+	inv, err := inventory.Collect(context.Background(), newSession(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.CAs) != 2 || len(inv.CAs[0].Certificates) != 2 || len(inv.CAs[1].Certificates) != 0 {
+		t.Fatalf("unexpected inventory %+v", inv)
+	}
+	if cert := inv.CAs[0].Certificates[0]; cert.Info.Id != 10 || len(cert.Info.Alt) != 2 || cert.ExpiresAt.Year() != 2026 {
+		t.Errorf("unexpected certificate %+v", cert)
+	}
+
+	data, err := inv.Render(inventory.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded inventory.Inventory
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.CAs) != 2 || decoded.CAs[0].Certificates[1].Info.Status != tinycert.Revoked {
+		t.Errorf("JSON does not round trip: %v\n%s", err, data)
+	}
+
+	data, err = inv.Render(inventory.FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("CSV rows = %v, %v", rows, err)
+	}
+	if want := "1,Acme Root,10,www.example.com,good,2026-01-01T00:00:00Z,DNS:www.example.com IP:10.0.0.1"; strings.Join(rows[1], ",") != want {
+		t.Errorf("CSV row = %v, want %s", rows[1], want)
+	}
+
+	data, err = inv.Render(inventory.FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## Acme Root (CA 1)\n\n| Id | Common name | Status | Expires | SANs |\n|---:|---|---|---|---|\n",
+		"| 10 | www.example.com | good | 2026-01-01 | DNS:www.example.com, IP:10.0.0.1 |\n",
+		`| 11 | a\|b | revoked | 2026-01-01 |  |`,
+		"## Empty Org (CA 2)\n\n_No certificates._\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Markdown lacks %q:\n%s", want, data)
+		}
+	}
+
+	if _, err := inv.Render(inventory.Format(42)); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]inventory.Format{"json": inventory.FormatJSON, "csv": inventory.FormatCSV, "markdown": inventory.FormatMarkdown, "md": inventory.FormatMarkdown} {
+		if got, err := inventory.ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := inventory.ParseFormat("yaml"); err == nil {
+		t.Error("expected ParseFormat(yaml) to fail")
+	}
+}
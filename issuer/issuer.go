@@ -0,0 +1,201 @@
+// Package issuer adapts TinyCert to the shape of a cert-manager external
+// issuer: a signer that turns a certificate request into a signed chain, and
+// a registry of connected sessions keyed by issuer resource that reconcilers
+// built on controller-runtime can share.
+//
+// TinyCert always generates the private key itself; it cannot sign a key
+// supplied in a CSR. Sign therefore issues a certificate with the subject and
+// SANs of the CSR and returns the TinyCert generated key alongside the chain.
+// cert-manager verifies that a signed CertificateRequest matches the CSR's
+// public key, so the result cannot be handed back to cert-manager unchanged;
+// controllers must write the returned key and chain to the target Secret
+// themselves.
+package issuer
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/srohatgi/tinycert"
+)
+
+var ErrInvalidCSR = errors.New("issuer: invalid certificate signing request")
+
+// Credentials identify the TinyCert account backing an issuer resource.
+type Credentials struct {
+	Email      string
+	Passphrase string
+	ApiKey     string
+}
+
+// Bundle is the result of signing a request.
+type Bundle struct {
+	CertId   int64
+	ChainPEM []byte
+	CAPEM    []byte
+	KeyPEM   []byte
+}
+
+// Issuer issues certificates from one TinyCert CA.
+type Issuer struct {
+	session *tinycert.Session
+	caId    int64
+}
+
+func New(session *tinycert.Session, caId int64) *Issuer {
+	return &Issuer{session: session, caId: caId}
+}
+
+// Sign issues a certificate with the subject and SANs of the PEM encoded CSR.
+// See the package documentation for why the CSR's own key is not used.
+func (is *Issuer) Sign(ctx context.Context, csrPEM []byte) (bundle *Bundle, err error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, ErrInvalidCSR
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	return is.Issue(ctx, requestFromCSR(csr))
+}
+
+// Issue issues a certificate for req and fetches its chain, CA and key.
+func (is *Issuer) Issue(ctx context.Context, req tinycert.CertRequest) (bundle *Bundle, err error) {
+	cert := tinycert.NewCertificate(is.session)
+
+	certId, err := cert.CreateCtx(ctx, is.caId, req)
+	if err != nil {
+		return
+	}
+
+	chain, err := cert.GetCtx(ctx, *certId, tinycert.Chain)
+	if err != nil {
+		return
+	}
+	key, err := cert.GetCtx(ctx, *certId, tinycert.KeyDecrypted)
+	if err != nil {
+		return
+	}
+	ca, err := tinycert.NewCA(is.session).GetCtx(ctx, is.caId)
+	if err != nil {
+		return
+	}
+
+	return &Bundle{CertId: *certId, ChainPEM: []byte(*chain), CAPEM: []byte(*ca), KeyPEM: []byte(*key)}, nil
+}
+
+func requestFromCSR(csr *x509.CertificateRequest) tinycert.CertRequest {
+	first := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	req := tinycert.CertRequest{
+		CommonName:  csr.Subject.CommonName,
+		OrgUnit:     first(csr.Subject.OrganizationalUnit),
+		OrgName:     first(csr.Subject.Organization),
+		Locality:    first(csr.Subject.Locality),
+		StateCode:   first(csr.Subject.Province),
+		CountryCode: first(csr.Subject.Country),
+	}
+	for _, name := range csr.DNSNames {
+		req.Alt = append(req.Alt, tinycert.SAN{DNS: name})
+	}
+	for _, ip := range csr.IPAddresses {
+		req.Alt = append(req.Alt, tinycert.SAN{IP: ip.String()})
+	}
+	for _, email := range csr.EmailAddresses {
+		req.Alt = append(req.Alt, tinycert.SAN{Email: email})
+	}
+	for _, uri := range csr.URIs {
+		req.Alt = append(req.Alt, tinycert.SAN{URI: uri.String()})
+	}
+	return req
+}
+
+// Clients hands out connected sessions keyed by issuer resource (for example
+// "namespace/name"), so concurrent reconciles of the same issuer share one
+// TinyCert session. It is safe for concurrent use.
+type Clients struct {
+	mu       sync.Mutex
+	sessions map[string]*entry
+	// NewSession builds the session for a set of credentials; it defaults to
+	// tinycert.NewSession configured with the credentials.
+	NewSession func(creds Credentials) *tinycert.Session
+}
+
+type entry struct {
+	mu      sync.Mutex
+	creds   Credentials
+	session *tinycert.Session
+}
+
+func NewClients() *Clients {
+	return &Clients{sessions: map[string]*entry{}}
+}
+
+// Issuer returns an Issuer for the CA, connecting a new session when the key
+// is unknown or its credentials changed. Keys connect independently of each
+// other.
+func (c *Clients) Issuer(ctx context.Context, key string, creds Credentials, caId int64) (*Issuer, error) {
+	c.mu.Lock()
+	e, ok := c.sessions[key]
+	if !ok {
+		e = &entry{}
+		c.sessions[key] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session != nil && e.creds == creds {
+		return New(e.session, caId), nil
+	}
+
+	var session *tinycert.Session
+	if c.NewSession != nil {
+		session = c.NewSession(creds)
+	} else {
+		session = tinycert.NewSession().WithEmail(creds.Email).WithPassphrase(creds.Passphrase).WithApiKey(creds.ApiKey)
+	}
+	session.WithAutoReconnect(true)
+	if err := session.ConnectCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	if e.session != nil {
+		e.session.DisconnectCtx(ctx)
+	}
+	e.creds, e.session = creds, session
+	return New(session, caId), nil
+}
+
+// Forget disconnects and drops the session for key, e.g. when the issuer
+// resource is deleted.
+func (c *Clients) Forget(ctx context.Context, key string) error {
+	c.mu.Lock()
+	e, ok := c.sessions[key]
+	delete(c.sessions, key)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		return nil
+	}
+	return e.session.DisconnectCtx(ctx)
+}
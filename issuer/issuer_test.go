@@ -0,0 +1,254 @@
+package issuer_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/issuer"
+)
+
+// fakeAPI answers the calls an Issuer makes, recording them. Connects for an
+// email in block wait until its channel is closed.
+type fakeAPI struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	created []url.Values
+	block   map[string]chan struct{}
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, *httptest.Server) {
+	f := &fakeAPI{calls: map[string]int{}, block: map[string]chan struct{}{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeAPI) handle(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	api := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+
+	f.mu.Lock()
+	f.calls[api]++
+	block := f.block[r.PostForm.Get("email")]
+	f.mu.Unlock()
+
+	switch api {
+	case "connect":
+		if block != nil {
+			<-block
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "token"})
+	case "disconnect":
+		w.Write([]byte(`{}`))
+	case "cert/new":
+		f.mu.Lock()
+		f.created = append(f.created, r.PostForm)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]int64{"cert_id": 7})
+	case "cert/get":
+		json.NewEncoder(w).Encode(map[string]string{"pem": r.PostForm.Get("what") + " of " + r.PostForm.Get("cert_id")})
+	case "ca/get":
+		json.NewEncoder(w).Encode(map[string]string{"pem": "ca " + r.PostForm.Get("ca_id")})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"404","text":"unknown call"}`))
+	}
+}
+
+func (f *fakeAPI) createdForms() []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]url.Values(nil), f.created...)
+}
+
+func (f *fakeAPI) callCount(api string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[api]
+}
+
+func newSession(srv *httptest.Server, creds issuer.Credentials) *tinycert.Session {
+	return tinycert.NewSession().WithEmail(creds.Email).WithPassphrase(creds.Passphrase).WithApiKey(creds.ApiKey).
+		WithBaseURL(srv.URL + "/api")
+}
+
+func newCSR(t *testing.T, tmpl *x509.CertificateRequest) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func pemCSR(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestIssuer_Sign(t *testing.T) {
+	f, srv := newFakeAPI(t)
+	sess := newSession(srv, issuer.Credentials{Email: "user@example.com", Passphrase: "secret", ApiKey: "apikey"}).Resume("token")
+
+	web, _ := url.Parse("spiffe://example.org/web")
+	csr := newCSR(t, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         "www.example.com",
+			OrganizationalUnit: []string{"ops"},
+			Organization:       []string{"acme"},
+			Locality:           []string{"San Jose"},
+			Province:           []string{"CA"},
+			Country:            []string{"US"},
+		},
+		DNSNames:       []string{"www.example.com", "example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses: []string{"ops@example.com"},
+		URIs:           []*url.URL{web},
+	})
+
+	bundle, err := issuer.New(sess, 3).Sign(context.Background(), pemCSR(csr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.CertId != 7 || string(bundle.ChainPEM) != "chain of 7" || string(bundle.KeyPEM) != "key.dec of 7" || string(bundle.CAPEM) != "ca 3" {
+		t.Errorf("unexpected bundle %+v", bundle)
+	}
+
+	created := f.createdForms()
+	if len(created) != 1 {
+		t.Fatalf("cert/new called %d times, want 1", len(created))
+	}
+	form := created[0]
+	for name, want := range map[string]string{
+		"ca_id":          "3",
+		"CN":             "www.example.com",
+		"OU":             "ops",
+		"O":              "acme",
+		"L":              "San Jose",
+		"ST":             "CA",
+		"C":              "US",
+		"SANs[0][DNS]":   "www.example.com",
+		"SANs[1][DNS]":   "example.com",
+		"SANs[2][IP]":    "10.0.0.1",
+		"SANs[3][email]": "ops@example.com",
+		"SANs[4][URI]":   "spiffe://example.org/web",
+	} {
+		if got := form.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestIssuer_SignInvalidCSR(t *testing.T) {
+	f, srv := newFakeAPI(t)
+	is := issuer.New(newSession(srv, issuer.Credentials{Email: "user@example.com"}).Resume("token"), 3)
+
+	valid := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}})
+	tampered := bytes.Clone(valid)
+	tampered[len(tampered)-1] ^= 0xff
+	// Replace the ecdsa-with-SHA256 signature algorithm with an unknown one.
+	unsupported := bytes.Replace(valid, []byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x04, 0x03, 0x02}, []byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x04, 0x03, 0x7f}, 1)
+
+	for name, csrPEM := range map[string][]byte{
+		"not PEM":               []byte("not a csr"),
+		"certificate":           pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: valid}),
+		"garbage":               pemCSR([]byte("garbage")),
+		"bad signature":         pemCSR(tampered),
+		"unsupported algorithm": pemCSR(unsupported),
+	} {
+		if _, err := is.Sign(context.Background(), csrPEM); !errors.Is(err, issuer.ErrInvalidCSR) {
+			t.Errorf("%s: err = %v, want ErrInvalidCSR", name, err)
+		}
+	}
+	if got := f.callCount("cert/new"); got != 0 {
+		t.Errorf("cert/new called %d times for invalid CSRs", got)
+	}
+}
+
+func TestClients(t *testing.T) {
+	f, srv := newFakeAPI(t)
+	clients := issuer.NewClients()
+	clients.NewSession = func(creds issuer.Credentials) *tinycert.Session { return newSession(srv, creds) }
+	ctx := context.Background()
+	creds := issuer.Credentials{Email: "user@example.com", Passphrase: "secret", ApiKey: "apikey"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := clients.Issuer(ctx, "ns/issuer", creds, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.callCount("connect"); got != 1 {
+		t.Errorf("connect called %d times for the same credentials, want 1", got)
+	}
+
+	rotated := creds
+	rotated.Passphrase = "rotated"
+	if _, err := clients.Issuer(ctx, "ns/issuer", rotated, 3); err != nil {
+		t.Fatal(err)
+	}
+	if connects, disconnects := f.callCount("connect"), f.callCount("disconnect"); connects != 2 || disconnects != 1 {
+		t.Errorf("after new credentials: %d connects and %d disconnects, want 2 and 1", connects, disconnects)
+	}
+
+	if err := clients.Forget(ctx, "ns/issuer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clients.Forget(ctx, "ns/issuer"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.callCount("disconnect"); got != 2 {
+		t.Errorf("disconnect called %d times, want 2", got)
+	}
+}
+
+func TestClients_ConnectsIndependently(t *testing.T) {
+	f, srv := newFakeAPI(t)
+	release := make(chan struct{})
+	f.mu.Lock()
+	f.block["slow@example.com"] = release
+	f.mu.Unlock()
+	defer close(release)
+
+	clients := issuer.NewClients()
+	clients.NewSession = func(creds issuer.Credentials) *tinycert.Session { return newSession(srv, creds) }
+	ctx := context.Background()
+
+	go clients.Issuer(ctx, "ns/slow", issuer.Credentials{Email: "slow@example.com", Passphrase: "secret", ApiKey: "apikey"}, 1)
+	for f.callCount("connect") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := clients.Issuer(ctx, "ns/fast", issuer.Credentials{Email: "fast@example.com", Passphrase: "secret", ApiKey: "apikey"}, 2)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow connect blocked another issuer")
+	}
+}
@@ -0,0 +1,41 @@
+package tinycert
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// KeepAlive makes a cheap authenticated call every interval until ctx is
+// done, so the session token does not expire while a long-running process is
+// idle. Start it in its own goroutine once connected:
+//
+//	go sess.KeepAlive(ctx, 10*time.Minute)
+//
+// Failed pings are logged. With WithAutoReconnect a ping that finds the token
+// expired connects again. Ticks while the session is disconnected are skipped.
+func (s *Session) KeepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if s.currentToken() == nil {
+			continue
+		}
+		if err := s.ping(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Log(LevelWarn, "keep-alive: %v", err)
+		}
+	}
+}
+
+// ping lists the CAs, bypassing the cache, which is the cheapest call that
+// needs a valid token.
+func (s *Session) ping(ctx context.Context) error {
+	_, err := call[[]*CAListItem](ctx, s, "ca/list", url.Values{})
+	return err
+}
@@ -0,0 +1,304 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrWrongArchiveKey = errors.New("tinycert: wrong key for key archive")
+	ErrKeyNotInArchive = errors.New("tinycert: key not in archive")
+)
+
+// scrypt parameters for passphrase keys, as recommended for interactive
+// use.
+const (
+	archiveScryptN = 1 << 15
+	archiveScryptR = 8
+	archiveScryptP = 1
+)
+
+// ArchiveKey unlocks a KeyArchive. Make one with PassphraseArchiveKey or
+// SSHArchiveKey.
+type ArchiveKey struct {
+	kind   string
+	secret []byte
+	// fingerprint identifies an SSH key, to tell a wrong key from a
+	// corrupted archive.
+	fingerprint string
+}
+
+// PassphraseArchiveKey derives the archive key from a passphrase with
+// scrypt.
+func PassphraseArchiveKey(passphrase string) ArchiveKey {
+	return ArchiveKey{kind: "scrypt", secret: []byte(passphrase)}
+}
+
+// SSHArchiveKey derives the archive key from an Ed25519, ECDSA or RSA SSH
+// private key in PEM or OpenSSH form; passphrase decrypts it and may be empty
+// for an unencrypted key. The same private key is needed to open the
+// archive: keys are not encrypted to the public key.
+func SSHArchiveKey(privateKey []byte, passphrase string) (key ArchiveKey, err error) {
+	var raw interface{}
+	if passphrase == "" {
+		raw, err = ssh.ParseRawPrivateKey(privateKey)
+	} else {
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+	}
+	if err != nil {
+		return key, fmt.Errorf("tinycert: ssh key: %w", err)
+	}
+
+	key.kind = "ssh"
+	var public interface{}
+	switch k := raw.(type) {
+	case *ed25519.PrivateKey:
+		key.secret, public = k.Seed(), k.Public()
+	case ed25519.PrivateKey:
+		key.secret, public = k.Seed(), k.Public()
+	case *ecdsa.PrivateKey:
+		key.secret, public = k.D.Bytes(), k.Public()
+	case *rsa.PrivateKey:
+		key.secret, public = k.D.Bytes(), k.Public()
+	default:
+		return key, fmt.Errorf("tinycert: ssh key: unsupported type %T", raw)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return key, fmt.Errorf("tinycert: ssh key: %w", err)
+	}
+	key.fingerprint = ssh.FingerprintSHA256(sshPublic)
+	return
+}
+
+// archiveFile is the on-disk form of a KeyArchive. Entries are sealed one by
+// one, with their names as additional data, so a key can be extracted
+// without decrypting the others and cannot be passed off under another name.
+type archiveFile struct {
+	Version     int               `json:"version"`
+	KDF         string            `json:"kdf"`
+	Salt        []byte            `json:"salt"`
+	N           int               `json:"n,omitempty"`
+	R           int               `json:"r,omitempty"`
+	P           int               `json:"p,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Check       []byte            `json:"check"`
+	Keys        map[string][]byte `json:"keys"`
+}
+
+const archiveCheck = "tinycert key archive"
+
+// KeyArchive is a file of private keys encrypted with AES-256-GCM, so keys
+// downloaded from TinyCert never sit on disk in the clear. Keys are stored
+// under names, such as certificate ids; Extract writes one out when a
+// service needs it. The file is rewritten atomically on every change. A
+// KeyArchive is safe for concurrent use, but not for use by several
+// processes at once.
+type KeyArchive struct {
+	path string
+
+	mu   sync.Mutex
+	aead cipher.AEAD
+	file archiveFile
+}
+
+// OpenKeyArchive opens the archive at path with key, or prepares a new one
+// there if the file does not exist yet; it is written on the first Put.
+func OpenKeyArchive(path string, key ArchiveKey) (archive *KeyArchive, err error) {
+	archive = &KeyArchive{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		archive.file = archiveFile{Version: 1, KDF: key.kind, Fingerprint: key.fingerprint, Keys: map[string][]byte{}}
+		if key.kind == "scrypt" {
+			archive.file.N, archive.file.R, archive.file.P = archiveScryptN, archiveScryptR, archiveScryptP
+		}
+		archive.file.Salt = make([]byte, 16)
+		if _, err = rand.Read(archive.file.Salt); err != nil {
+			return nil, err
+		}
+		if archive.aead, err = archive.file.cipher(key); err != nil {
+			return nil, err
+		}
+		if archive.file.Check, err = archive.seal(archiveCheck, []byte(archiveCheck)); err != nil {
+			return nil, err
+		}
+		return archive, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &archive.file); err != nil {
+		return nil, fmt.Errorf("tinycert: key archive %s: %w", path, err)
+	}
+	if archive.file.Version != 1 {
+		return nil, fmt.Errorf("tinycert: key archive %s: unsupported version %d", path, archive.file.Version)
+	}
+	if archive.file.KDF != key.kind || archive.file.Fingerprint != key.fingerprint {
+		return nil, fmt.Errorf("%w: %s is locked with a different %s key", ErrWrongArchiveKey, path, archive.file.KDF)
+	}
+	if archive.aead, err = archive.file.cipher(key); err != nil {
+		return nil, err
+	}
+	if _, err = archive.open(archiveCheck, archive.file.Check); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWrongArchiveKey, path)
+	}
+	if archive.file.Keys == nil {
+		archive.file.Keys = map[string][]byte{}
+	}
+	return
+}
+
+func (f *archiveFile) cipher(key ArchiveKey) (aead cipher.AEAD, err error) {
+	derived := make([]byte, 32)
+	switch f.KDF {
+	case "scrypt":
+		if derived, err = scrypt.Key(key.secret, f.Salt, f.N, f.R, f.P, len(derived)); err != nil {
+			return
+		}
+	case "ssh":
+		if _, err = io.ReadFull(hkdf.New(sha256.New, key.secret, f.Salt, []byte(archiveCheck)), derived); err != nil {
+			return
+		}
+	default:
+		return nil, fmt.Errorf("tinycert: key archive: unknown kdf %q", f.KDF)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext as the entry name, prefixed with its nonce.
+func (a *KeyArchive) seal(name string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize(), a.aead.NonceSize()+len(plaintext)+a.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plaintext, []byte(name)), nil
+}
+
+func (a *KeyArchive) open(name string, sealed []byte) ([]byte, error) {
+	if len(sealed) < a.aead.NonceSize() {
+		return nil, errors.New("tinycert: key archive: truncated entry")
+	}
+	nonce, ciphertext := sealed[:a.aead.NonceSize()], sealed[a.aead.NonceSize():]
+	return a.aead.Open(nil, nonce, ciphertext, []byte(name))
+}
+
+// Put stores a private key under name, replacing any key already there.
+func (a *KeyArchive) Put(name string, key SecureString) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sealed, err := a.seal(name, []byte(key.Reveal()))
+	if err != nil {
+		return
+	}
+	previous, existed := a.file.Keys[name]
+	a.file.Keys[name] = sealed
+	if err = a.save(); err != nil {
+		if existed {
+			a.file.Keys[name] = previous
+		} else {
+			delete(a.file.Keys, name)
+		}
+	}
+	return
+}
+
+// Get returns the private key stored under name.
+func (a *KeyArchive) Get(name string) (key SecureString, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sealed, ok := a.file.Keys[name]
+	if !ok {
+		return key, fmt.Errorf("%w: %s", ErrKeyNotInArchive, name)
+	}
+	plaintext, err := a.open(name, sealed)
+	if err != nil {
+		return key, fmt.Errorf("tinycert: key archive: %s: %w", name, err)
+	}
+	return NewSecureString(string(plaintext)), nil
+}
+
+// Extract writes the private key stored under name to path, with 0600
+// permissions, for a service that reads its key from a file.
+func (a *KeyArchive) Extract(name, path string) (err error) {
+	key, err := a.Get(name)
+	if err != nil {
+		return
+	}
+	return writeFileAtomic(path, []byte(key.Reveal()), 0600)
+}
+
+// Delete removes the key stored under name, if any.
+func (a *KeyArchive) Delete(name string) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	previous, ok := a.file.Keys[name]
+	if !ok {
+		return nil
+	}
+	delete(a.file.Keys, name)
+	if err = a.save(); err != nil {
+		a.file.Keys[name] = previous
+	}
+	return
+}
+
+// Names returns the names of the stored keys, sorted.
+func (a *KeyArchive) Names() (names []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for name := range a.file.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func (a *KeyArchive) save() error {
+	data, err := json.MarshalIndent(&a.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.path, data, 0600)
+}
+
+func (c *Certificate) ArchiveKey(certId int64, archive *KeyArchive) (err error) {
+	return c.ArchiveKeyCtx(context.Background(), certId, archive)
+}
+
+// ArchiveKeyCtx fetches the decrypted private key of the certificate and
+// stores it in the archive under the certificate id, without writing it
+// anywhere else: the key is fetched past the session's cache.
+func (c *Certificate) ArchiveKeyCtx(ctx context.Context, certId int64, archive *KeyArchive) (err error) {
+	key, err := c.fetch(ctx, certId, KeyDecrypted)
+	if err != nil {
+		return
+	}
+	return archive.Put(formatInt(certId), NewSecureString(*key))
+}
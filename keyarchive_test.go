@@ -0,0 +1,156 @@
+package tinycert_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestKeyArchive(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+	keyPEM, err := cert.Get(certId, tinycert.KeyDecrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.json")
+	archive, err := tinycert.OpenKeyArchive(path, tinycert.PassphraseArchiveKey("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := tinycert.FileLayout{CertPath: filepath.Join(dir, "cert.pem"), KeyArchive: archive}
+	if err = cert.DownloadLayout(certId, layout); err != nil {
+		t.Fatal(err)
+	}
+	layout.KeyPath = filepath.Join(dir, "key.pem")
+	if err = cert.DownloadLayout(certId, layout); !errors.Is(err, tinycert.ErrInvalidRequest) {
+		t.Errorf("err = %v, want ErrInvalidRequest for KeyPath with KeyArchive", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("PRIVATE KEY")) {
+		t.Error("key archive holds the key in the clear")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key archive mode = %v, %v", info.Mode(), err)
+	}
+
+	if _, err = tinycert.OpenKeyArchive(path, tinycert.PassphraseArchiveKey("wrong")); !errors.Is(err, tinycert.ErrWrongArchiveKey) {
+		t.Errorf("err = %v, want ErrWrongArchiveKey", err)
+	}
+	if archive, err = tinycert.OpenKeyArchive(path, tinycert.PassphraseArchiveKey("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	name := strconv.FormatInt(certId, 10)
+	if names := archive.Names(); len(names) != 1 || names[0] != name {
+		t.Errorf("names = %v", names)
+	}
+	if key, err := archive.Get(name); err != nil || key.Reveal() != *keyPEM {
+		t.Errorf("key = %v, %v", key, err)
+	}
+	out := filepath.Join(dir, "extracted.pem")
+	if err = archive.Extract(name, out); err != nil {
+		t.Fatal(err)
+	}
+	if extracted, _ := os.ReadFile(out); string(extracted) != *keyPEM {
+		t.Error("extracted key differs")
+	}
+
+	if err = archive.Delete(name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = archive.Get(name); !errors.Is(err, tinycert.ErrKeyNotInArchive) {
+		t.Errorf("err = %v, want ErrKeyNotInArchive", err)
+	}
+}
+
+func TestCertificate_ArchiveKey(t *testing.T) {
+	cacheDir := t.TempDir()
+	disk, err := tinycert.NewDiskCache(cacheDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithCache(disk)
+	_, certId := newCAAndCert(t, sess)
+
+	archive, err := tinycert.OpenKeyArchive(filepath.Join(t.TempDir(), "keys.json"), tinycert.PassphraseArchiveKey("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := tinycert.NewCertificate(sess)
+	if err = cert.ArchiveKey(certId, archive); err != nil {
+		t.Fatal(err)
+	}
+	key, err := archive.Get(strconv.FormatInt(certId, 10))
+	if err != nil || !strings.Contains(key.Reveal(), "PRIVATE KEY") {
+		t.Fatalf("archived key = %v, %v", key, err)
+	}
+
+	entries, _ := os.ReadDir(cacheDir)
+	for _, entry := range entries {
+		data, _ := os.ReadFile(filepath.Join(cacheDir, entry.Name()))
+		if bytes.Contains(data, []byte("PRIVATE KEY")) {
+			t.Errorf("cache entry %s holds the private key", entry.Name())
+		}
+	}
+}
+
+func TestKeyArchive_SSHKey(t *testing.T) {
+	newSSHKey := func() []byte {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := ssh.MarshalPrivateKey(private, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(block)
+	}
+	mine, other := newSSHKey(), newSSHKey()
+
+	key, err := tinycert.SSHArchiveKey(mine, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	archive, err := tinycert.OpenKeyArchive(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = archive.Put("web", tinycert.NewSecureString("secret key")); err != nil {
+		t.Fatal(err)
+	}
+
+	if archive, err = tinycert.OpenKeyArchive(path, key); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := archive.Get("web"); err != nil || got.Reveal() != "secret key" {
+		t.Errorf("key = %q, %v", got.Reveal(), err)
+	}
+
+	otherKey, err := tinycert.SSHArchiveKey(other, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wrong := range []tinycert.ArchiveKey{otherKey, tinycert.PassphraseArchiveKey("secret")} {
+		if _, err = tinycert.OpenKeyArchive(path, wrong); !errors.Is(err, tinycert.ErrWrongArchiveKey) {
+			t.Errorf("err = %v, want ErrWrongArchiveKey", err)
+		}
+	}
+}
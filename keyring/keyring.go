@@ -0,0 +1,104 @@
+// Package keyring keeps TinyCert credentials in the OS keyring: the macOS
+// Keychain, the Windows Credential Manager, or the Secret Service, such as
+// GNOME Keyring or KWallet, on Linux and BSD. The passphrase and API key then
+// never appear in environment variables, config files or shell history.
+//
+// It is a package of its own so that only programs that use it link the OS
+// keyring code.
+package keyring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/srohatgi/tinycert"
+	gokeyring "github.com/zalando/go-keyring"
+)
+
+// DefaultService is the service Provider stores credentials under unless
+// Service says otherwise.
+const DefaultService = "tinycert"
+
+// Provider reads credentials from the OS keyring. Store them once with Store,
+// or with the CLI's "tinycert keyring set".
+type Provider struct {
+	// Service is the keyring service name; empty means DefaultService.
+	Service string
+	// Account names the entry, e.g. the account email, to keep several
+	// accounts apart; empty means "default".
+	Account string
+}
+
+var _ tinycert.CredentialProvider = Provider{}
+
+func (p Provider) entry() (service, account string) {
+	service, account = p.Service, p.Account
+	if service == "" {
+		service = DefaultService
+	}
+	if account == "" {
+		account = "default"
+	}
+	return
+}
+
+func (p Provider) Credentials(context.Context) (creds tinycert.Credentials, err error) {
+	service, account := p.entry()
+	secret, err := gokeyring.Get(service, account)
+	if errors.Is(err, gokeyring.ErrNotFound) {
+		return creds, fmt.Errorf("%w: no %s entry for %s in the OS keyring", tinycert.ErrNoCredentials, service, account)
+	}
+	if err != nil {
+		return creds, fmt.Errorf("keyring: unable to read the OS keyring: %w", err)
+	}
+	if err = json.Unmarshal([]byte(secret), &creds); err != nil {
+		return creds, fmt.Errorf("keyring: unable to parse %s entry for %s in the OS keyring: %w", service, account, err)
+	}
+	return creds, complete(creds)
+}
+
+// Store saves complete credentials in the OS keyring, replacing the entry if
+// there is one.
+func (p Provider) Store(creds tinycert.Credentials) error {
+	if err := complete(creds); err != nil {
+		return err
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	service, account := p.entry()
+	if err = gokeyring.Set(service, account, string(data)); err != nil {
+		return fmt.Errorf("keyring: unable to write the OS keyring: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the entry from the OS keyring, if there is one.
+func (p Provider) Delete() error {
+	service, account := p.entry()
+	if err := gokeyring.Delete(service, account); err != nil && !errors.Is(err, gokeyring.ErrNotFound) {
+		return fmt.Errorf("keyring: unable to delete from the OS keyring: %w", err)
+	}
+	return nil
+}
+
+func complete(creds tinycert.Credentials) error {
+	var missing []string
+	for _, setting := range []struct{ name, value string }{
+		{"email", creds.Email},
+		{"passphrase", creds.Passphrase},
+		{"api_key", creds.ApiKey},
+	} {
+		if setting.value == "" {
+			missing = append(missing, setting.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", tinycert.ErrNoCredentials, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package keyring_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/keyring"
+	gokeyring "github.com/zalando/go-keyring"
+)
+
+func TestProvider(t *testing.T) {
+	gokeyring.MockInit()
+	creds := tinycert.Credentials{Email: "user@example.com", Passphrase: "secret", ApiKey: "apikey"}
+	p := keyring.Provider{Account: creds.Email}
+
+	if _, err := p.Credentials(context.Background()); !errors.Is(err, tinycert.ErrNoCredentials) {
+		t.Error("expected ErrNoCredentials before Store, got", err)
+	}
+	if err := p.Store(tinycert.Credentials{Email: creds.Email}); !errors.Is(err, tinycert.ErrNoCredentials) {
+		t.Error("expected Store to refuse incomplete credentials, got", err)
+	}
+
+	if err := p.Store(creds); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Credentials(context.Background())
+	if err != nil || got != creds {
+		t.Errorf("credentials = %+v, %v", got, err)
+	}
+	if _, err := (keyring.Provider{}).Credentials(context.Background()); !errors.Is(err, tinycert.ErrNoCredentials) {
+		t.Error("expected the default entry to be separate, got", err)
+	}
+
+	if err := p.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Delete(); err != nil {
+		t.Error("deleting a missing entry failed", err)
+	}
+	if _, err := p.Credentials(context.Background()); !errors.Is(err, tinycert.ErrNoCredentials) {
+		t.Error("expected ErrNoCredentials after Delete, got", err)
+	}
+}
@@ -2,44 +2,79 @@ package tinycert
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
+// Session holds TinyCert credentials and the token obtained by Connect. Once
+// configured, a Session is safe for concurrent use by multiple goroutines;
+// the With* builder methods are not and should be called before first use.
 type Session struct {
+	mu         sync.Mutex
 	email      string
 	passphrase string
 	apiKey     string
+	baseURL    string
+	apiVersion string
 	serverPath string
+	configErr  error
 	clt        *http.Client
 	token      *string
-	debug      bool
-	logger     func(format string, args ...interface{})
+	retry      RetryPolicy
+	reconnect  bool
+	logger     Logger
+
+	interceptors []Interceptor
+	tracer       trace.Tracer
+	metrics      *callMetrics
+	observers    []func(api string, err error)
+	limiter      *RateLimiter
+	cache        Cache
+	lineage      LineageStore
+	strict       bool
+	debug        bool
+	credentials  CredentialProvider
+	handlers     []EventHandler
+	audit        AuditSink
+	closed       bool
+	maxResponse  int64
+	userAgent    string
+	clock        Clock
+	connects     singleflight.Group
+
+	endpointTimeouts map[string]time.Duration
 }
 
+const (
+	DefaultBaseURL    = "https://www.tinycert.org/api/"
+	DefaultAPIVersion = "v1"
+)
+
 func NewSession() *Session {
 	s := &Session{
-		serverPath: "https://www.tinycert.org/api/v1/",
+		baseURL:    DefaultBaseURL,
+		apiVersion: DefaultAPIVersion,
+		serverPath: DefaultBaseURL + DefaultAPIVersion + "/",
 		email:      os.Getenv("TINYCERT_EMAIL"),
 		passphrase: os.Getenv("TINYCERT_PASSWORD"),
 		apiKey:     os.Getenv("TINYCERT_APIKEY"),
-		clt:        &http.Client{},
-	}
-
-	s.logger = func(format string, args ...interface{}) {
-		if s.debug {
-			log.Printf(format+"\n", args...)
-		}
+		clt:        &http.Client{Timeout: DefaultTimeout, Transport: newTransport()},
+		logger:     nopLogger{},
+		clock:      ClockFunc(time.Now),
 	}
+	s.lineage = newMemoryLineage(func() time.Time { return s.clock.Now() })
 
 	return s
 }
@@ -59,99 +94,456 @@ func (s *Session) WithApiKey(apiKey string) *Session {
 	return s
 }
 
-func (s *Session) WithLogger(logfn func(format string, args ...interface{})) *Session {
-	s.debug = true
-	s.logger = logfn
+// WithBaseURL points the session at a different API root, such as a proxy
+// or a mock server. The API version is appended to it, so
+// "https://proxy.example/api" with version "v1" calls
+// "https://proxy.example/api/v1/connect". An invalid URL makes every call
+// fail with ErrInvalidBaseURL.
+func (s *Session) WithBaseURL(baseURL string) *Session {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		s.configErr = fmt.Errorf("%w: %q", ErrInvalidBaseURL, baseURL)
+		return s
+	}
+	s.baseURL = strings.TrimRight(baseURL, "/") + "/"
+	s.setServerPath()
+	return s
+}
+
+// WithAPIVersion selects the API version path segment, "v1" by default. An
+// empty version uses the base URL as is.
+func (s *Session) WithAPIVersion(version string) *Session {
+	version = strings.Trim(version, "/")
+	if strings.ContainsAny(version, "/?#") {
+		s.configErr = fmt.Errorf("%w: api version %q", ErrInvalidBaseURL, version)
+		return s
+	}
+	s.apiVersion = version
+	s.setServerPath()
+	return s
+}
+
+func (s *Session) setServerPath() {
+	s.serverPath = s.baseURL
+	if s.apiVersion != "" {
+		s.serverPath += s.apiVersion + "/"
+	}
+}
+
+func (s *Session) WithRetryPolicy(policy RetryPolicy) *Session {
+	s.retry = policy
+	return s
+}
+
+// WithAutoReconnect makes the session transparently run connect again and
+// retry the call once when the API rejects an expired token.
+func (s *Session) WithAutoReconnect(reconnect bool) *Session {
+	s.reconnect = reconnect
+	return s
+}
+
+// WithStrictDecoding makes calls fail when a response carries fields this
+// package does not know about, so API schema drift is caught early.
+func (s *Session) WithStrictDecoding(strict bool) *Session {
+	s.strict = strict
+	return s
+}
+
+func (s *Session) WithLogger(logger Logger) *Session {
+	if logger == nil {
+		s.logger = nopLogger{}
+		return s
+	}
+	s.logger = redactingLogger{next: logger}
 	return s
 }
 
 func (s *Session) Connect() (err error) {
+	return s.ConnectCtx(context.Background())
+}
+
+// ConnectCtx obtains a session token. Concurrent calls, including automatic
+// reconnects, share a single connect request and its token: TinyCert
+// invalidates earlier tokens on every connect, so parallel connects would
+// otherwise log each other out.
+func (s *Session) ConnectCtx(ctx context.Context) (err error) {
+	if s.isClosed() {
+		return ErrClosed
+	}
+	return s.connectShared(ctx, nil)
+}
+
+// connectShared connects, joining a connect already in flight. If stale is
+// set, the caller was refused with that token and the connect is skipped
+// when another caller has replaced it in the meantime. The shared call is not
+// cancelled with ctx, which only stops this caller waiting for it.
+func (s *Session) connectShared(ctx context.Context, stale *string) error {
+	shared := context.WithoutCancel(ctx)
+	ch := s.connects.DoChan("connect", func() (interface{}, error) {
+		if stale != nil {
+			if current := s.currentToken(); current != nil && *current != *stale {
+				return nil, nil
+			}
+			s.setToken(nil)
+		}
+		return nil, s.connect(shared)
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Session) connect(ctx context.Context) (err error) {
 	type connectResponse struct {
 		Token string `json:"token"`
 	}
 
-	res, err := s.makeCall("connect", []*fieldValues{{"email", s.email}, {"passphrase", s.passphrase}}, &connectResponse{})
+	if err = s.loadCredentials(ctx); err != nil {
+		return
+	}
+	if err = s.missingCredentials(); err != nil {
+		return
+	}
+
+	email, passphrase, _ := s.secrets()
+	res, err := call[connectResponse](ctx, s, "connect", url.Values{"email": {email}, "passphrase": {passphrase}})
 	if err != nil {
 		return
 	}
 
-	cres := res.(*connectResponse)
-	s.token = &cres.Token
+	s.setToken(&res.Token)
 	return
 }
 
 func (s *Session) Disconnect() (err error) {
+	return s.DisconnectCtx(context.Background())
+}
+
+func (s *Session) DisconnectCtx(ctx context.Context) (err error) {
 	type disconnectResponse struct{}
 
-	_, err = s.makeCall("disconnect", []*fieldValues{}, &disconnectResponse{})
+	_, err = call[disconnectResponse](ctx, s, "disconnect", url.Values{})
+
+	return
+}
+
+// Close disconnects the session if it is connected and drops its
+// credentials, implementing io.Closer. Any later call fails with ErrClosed.
+// Close is safe to call more than once; only the first call does anything.
+func (s *Session) Close() (err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	connected := s.token != nil
+	s.mu.Unlock()
+
+	if connected {
+		err = s.DisconnectCtx(context.Background())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.token = nil
+	s.email, s.passphrase, s.apiKey = "", "", ""
+	return
+}
+
+// WithSession connects session, runs fn with it and closes the session when
+// fn returns or panics, so callers cannot forget to disconnect:
+//
+//	err := tinycert.WithSession(ctx, tinycert.NewSession(), func(s *tinycert.Session) error {
+//		_, err := tinycert.NewCA(s).ListCtx(ctx)
+//		return err
+//	})
+//
+// session is configured but not yet connected, and cannot be used after
+// WithSession returns. Calls in fn should use ctx so that cancelling it ends
+// them; if ctx is done before fn runs, fn is not called.
+func WithSession(ctx context.Context, session *Session, fn func(s *Session) error) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if err = session.ConnectCtx(ctx); err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := session.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}()
+
+	return fn(session)
+}
+
+// Token returns the token obtained by Connect, or "" if the session is not
+// connected. Together with Resume it lets a short-lived process, such as a
+// Lambda, persist a session between invocations instead of reconnecting.
+func (s *Session) Token() string {
+	if token := s.currentToken(); token != nil {
+		return *token
+	}
+	return ""
+}
+
+// Resume makes the session use a token saved from an earlier Token call. Use
+// Validate to check the token is still live.
+func (s *Session) Resume(token string) *Session {
+	if token == "" {
+		s.setToken(nil)
+	} else {
+		s.setToken(&token)
+	}
+	return s
+}
+
+func (s *Session) Validate() (err error) {
+	return s.ValidateCtx(context.Background())
+}
+
+// ValidateCtx checks that the session token is still accepted by the server.
+// It returns ErrNotConnected if there is no token and ErrUnauthorized if the
+// token has expired; it never reconnects, even with WithAutoReconnect.
+func (s *Session) ValidateCtx(ctx context.Context) (err error) {
+	if s.isClosed() {
+		return ErrClosed
+	}
+	if s.currentToken() == nil {
+		return ErrNotConnected
+	}
+	if s.configErr != nil {
+		return s.configErr
+	}
+
+	ctx, finish := s.instrument(ctx, "ca/list", nil)
+	defer func() { finish(err) }()
 
+	err = s.doCall(ctx, "ca/list", url.Values{}, &[]*CAListItem{})
 	return
 }
 
-type fieldValues struct {
-	name  string
-	value interface{}
+func (s *Session) secrets() (email, passphrase, apiKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.email, s.passphrase, s.apiKey
 }
 
-type fvColl []*fieldValues
+func (s *Session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
 
-func (fv fvColl) Len() int {
-	return len(fv)
+func (s *Session) currentToken() *string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
 }
 
-func (fv fvColl) Less(i, j int) bool {
-	return fv[i].name < fv[j].name
+func (s *Session) setToken(token *string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
 }
 
-func (fv fvColl) Swap(i, j int) {
-	fv[i], fv[j] = fv[j], fv[i]
+// call makes an API call and decodes its response as a T.
+func call[T any](ctx context.Context, s *Session, api string, fields url.Values) (*T, error) {
+	response := new(T)
+	if err := s.makeCall(ctx, api, fields, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *Session) makeCall(ctx context.Context, api string, list url.Values, response interface{}) (err error) {
+	ctx, finish := s.instrument(ctx, api, list)
+	defer func() {
+		finish(err)
+		s.recordAudit(ctx, api, list, err)
+	}()
+
+	if s.configErr != nil {
+		return s.configErr
+	}
+	if s.isClosed() {
+		return ErrClosed
+	}
+
+	token := s.currentToken()
+
+	err = s.doCall(ctx, api, list, response)
+	if err == nil || !s.reconnect || token == nil || api == "connect" || api == "disconnect" || !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	s.logger.Log(LevelInfo, "api: %s rejected session token, reconnecting", api)
+	if err = s.connectShared(ctx, token); err != nil {
+		return err
+	}
+
+	return s.doCall(ctx, api, list, response)
 }
 
-func (s *Session) makeCall(api string, list fvColl, response interface{}) (interface{}, error) {
-	if s.token != nil {
-		list = append(list, &fieldValues{"token", *s.token})
+// doCall signs and posts one API call and decodes the response into response.
+func (s *Session) doCall(ctx context.Context, api string, fields url.Values, response interface{}) error {
+	params := make(url.Values, len(fields)+1)
+	for name, values := range fields {
+		params[name] = values
+	}
+	if token := s.currentToken(); token != nil {
+		params.Set("token", *token)
 	}
 
-	sort.Sort(list)
+	_, _, apiKey := s.secrets()
+	vals := NewSigner(apiKey).Encode(params)
+
+	s.logger.Log(LevelDebug, "api: %s payload: %s&digest=REDACTED", api, redactValues(params).Encode())
+
+	var stream func(io.Reader) error
+	if sr, ok := response.(*streamResponse); ok {
+		stream = sr.consume
+	}
+
+	meta := callMetaFrom(ctx)
+	meta.startCall(api)
+	start := time.Now()
+	var body []byte
+	var err error
+	var history []RetryAttempt
+	attempt := 1
+	defer func() { meta.finishCall(attempt-1, time.Since(start)) }()
+	for ; ; attempt++ {
+		body, err = s.post(ctx, api, vals, stream)
+		if err != nil {
+			history = append(history, newRetryAttempt(err))
+		}
+		if err == nil || attempt >= s.retry.attempts() || !s.retry.retryable(ctx, api, err) {
+			break
+		}
 
-	vals := ""
-	for _, fv := range list {
-		if vals != "" {
-			vals += "&"
+		wait, ok := s.retry.wait(attempt, err)
+		if !ok {
+			s.logger.Log(LevelWarn, "api: %s attempt %d failed: %v, retry-after exceeds %s", api, attempt, err, s.retry.MaxRetryAfter)
+			break
+		}
+		s.logger.Log(LevelWarn, "api: %s attempt %d failed: %v, retrying in %s", api, attempt, err, wait)
+		history[len(history)-1].Delay = wait
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
 		}
-		vals += url.QueryEscape(fv.name) + "=" + url.QueryEscape(fmt.Sprintf("%v", fv.value))
+	}
+	if err != nil && len(history) > 1 {
+		return &RetryError{Endpoint: api, Attempts: history}
+	}
+	if err != nil {
+		return err
+	}
+	if stream != nil {
+		s.logger.Log(LevelDebug, "response from server: streamed")
+		return nil
 	}
 
-	mac := hmac.New(sha256.New, []byte(s.apiKey))
-	mac.Write([]byte(vals))
-	digest := hex.EncodeToString(mac.Sum(nil))
+	if api == "connect" {
+		s.logger.Log(LevelDebug, "response from server: REDACTED")
+	} else {
+		s.logger.Log(LevelDebug, "response from server: %s", body)
+	}
 
-	vals += "&digest=" + url.QueryEscape(digest)
+	dec := json.NewDecoder(bytes.NewReader(normalizeIDs(body)))
+	if s.strict {
+		dec.DisallowUnknownFields()
+	}
+	err = dec.Decode(response)
+	if err != nil {
+		s.logger.Log(LevelError, "unable to unmarshal %s response: %v", api, err)
+		return err
+	}
 
-	s.logger("api: %s payload: %s", api, vals)
+	return nil
+}
 
-	resp, err := s.clt.Post(s.serverPath+api, "application/x-www-form-urlencoded", strings.NewReader(vals))
+// post sends one request. The body of a successful response is returned, or,
+// when stream is set, handed to stream instead of being read into memory.
+// Either way at most the session's maximum response size is read.
+func (s *Session) post(ctx context.Context, api, vals string, stream func(io.Reader) error) ([]byte, error) {
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx = context.WithValue(ctx, endpointKey{}, api)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.serverPath+api, strings.NewReader(vals))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.setHeaders(req)
+	id := newRequestID()
+	req.Header.Set(RequestIDHeader, id)
+	if s.debug {
+		s.dumpRequest(req, vals)
+	}
+
+	meta := callMetaFrom(ctx)
+	resp, err := s.roundTrip(req)
+	if err != nil {
+		meta.attempt(id, 0, nil)
+		s.logger.Log(LevelError, "error calling tinycert (request %s): %v", id, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	meta.attempt(id, resp.StatusCode, nil)
+
+	decoded, err := decodeBody(resp)
 	if err != nil {
-		s.logger("error calling tinycert", err)
 		return nil, err
 	}
+	body := &limitedReader{r: decoded, n: s.maxResponseSize()}
+	if stream != nil && resp.StatusCode == http.StatusOK {
+		if s.debug {
+			s.dumpResponse(id, resp, nil)
+		}
+		if err = stream(body); err != nil {
+			return nil, &streamError{err: err}
+		}
+		return nil, nil
+	}
 
 	var buf bytes.Buffer
-	buf.ReadFrom(resp.Body)
+	if _, err = buf.ReadFrom(body); err != nil {
+		return nil, err
+	}
+	meta.attempt(id, resp.StatusCode, buf.Bytes())
+	if s.debug {
+		s.dumpResponse(id, resp, buf.Bytes())
+	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("error from server code = %d, response = %s", resp.StatusCode, buf.String())
+		apiErr := parseAPIError(resp.StatusCode, buf.Bytes())
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		if apiErr.RetryAfter > 0 && s.limiter != nil {
+			s.limiter.pause(apiErr.RetryAfter)
+		}
+		return nil, apiErr
 	}
 
-	s.logger("response from server: %s", buf.String())
-
-	err = json.Unmarshal(buf.Bytes(), response)
-	if err != nil {
-		s.logger("unable to unmarshal struct")
-		return nil, err
+	if apiErr := errorEnvelope(resp.StatusCode, buf.Bytes()); apiErr != nil {
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, apiErr
 	}
 
-	return response, nil
+	return buf.Bytes(), nil
 }
 
 type CAListItem struct {
@@ -181,173 +573,234 @@ func NewCA(session *Session) *CA {
 	}
 }
 
-func (ca *CA) Create(orgName, locality, stateCode, countryCode, hashMethod string) (caId *int64, err error) {
-	list := []*fieldValues{
-		{"C", countryCode},
-		{"L", locality},
-		{"O", orgName},
-		{"ST", stateCode},
-		{"hash_method", hashMethod},
+func (ca *CA) Create(req CARequest) (caId *int64, err error) {
+	return ca.CreateCtx(context.Background(), req)
+}
+
+// CreateCtx validates req and creates a certificate authority from it.
+func (ca *CA) CreateCtx(ctx context.Context, req CARequest) (caId *int64, err error) {
+	if err = req.Validate(); err != nil {
+		return
+	}
+
+	list := url.Values{
+		"C":           {req.CountryCode},
+		"L":           {req.Locality},
+		"O":           {req.OrgName},
+		"ST":          {req.StateCode},
+		"hash_method": {req.HashAlgorithm.String()},
+	}
+	for name, value := range map[string]string{"CN": req.CommonName, "E": req.Email, "OU": req.OrgUnit} {
+		if value != "" {
+			list.Set(name, value)
+		}
 	}
 
 	type idResponse struct {
 		CaId int64 `json:"ca_id"`
 	}
 
-	res, err := ca.session.makeCall("ca/new", list, &idResponse{})
+	res, err := call[idResponse](ctx, ca.session, "ca/new", list)
 	if err != nil {
 		return
 	}
-	caId = &res.(*idResponse).CaId
+	caId = &res.CaId
+	ca.session.emit(Event{Type: CACreated, CAId: *caId})
 	return
 }
 
 func (ca *CA) List() (items []*CAListItem, err error) {
-	res, err := ca.session.makeCall("ca/list", []*fieldValues{}, &[]*CAListItem{})
-	if err != nil {
-		return
-	}
-	items = *res.(*[]*CAListItem)
-	return
+	return ca.ListCtx(context.Background())
 }
 
-func (ca *CA) Details(caId int64) (caInfo *CAInfo, err error) {
-	res, err := ca.session.makeCall("ca/details", []*fieldValues{{"ca_id", caId}}, &CAInfo{})
+func (ca *CA) ListCtx(ctx context.Context) (items []*CAListItem, err error) {
+	res, err := call[[]*CAListItem](ctx, ca.session, "ca/list", url.Values{})
 	if err != nil {
 		return
 	}
-	caInfo = res.(*CAInfo)
+	items = *res
 	return
 }
 
 func (ca *CA) Get(caId int64) (pem *string, err error) {
+	return ca.GetCtx(context.Background(), caId)
+}
+
+func (ca *CA) GetCtx(ctx context.Context, caId int64) (pem *string, err error) {
+	return ca.GetArtifactCtx(ctx, caId, Cert)
+}
+
+func (ca *CA) GetArtifact(caId int64, what Artifact) (pem *string, err error) {
+	return ca.GetArtifactCtx(context.Background(), caId, what)
+}
+
+// GetArtifactCtx fetches an artifact of the CA. TinyCert only exposes the CA
+// certificate and its CRL, so any other artifact is rejected. The CRL changes
+// whenever one of the CA's certificates is revoked or held, so it is never
+// served from the cache.
+func (ca *CA) GetArtifactCtx(ctx context.Context, caId int64, what Artifact) (pem *string, err error) {
+	if what != Cert && what != CRL {
+		return nil, fmt.Errorf("%w: %s is not available for a CA", ErrInvalidArtifact, what)
+	}
+
+	key := CacheKey{Kind: "ca", Id: caId, What: what}
+	if what == Cert {
+		if cached, ok := ca.session.cached(key); ok {
+			return cached, nil
+		}
+	}
+
 	type pemInfo struct {
 		Pem string `json:"pem"`
 	}
-	res, err := ca.session.makeCall("ca/get", []*fieldValues{{"ca_id", caId}, {"what", "cert"}}, &pemInfo{})
+	res, err := call[pemInfo](ctx, ca.session, "ca/get", Fields{}.SetInt("ca_id", caId).Set("what", what.String()).Values())
 	if err != nil {
 		return
 	}
-	pem = &res.(*pemInfo).Pem
+	pem = &res.Pem
+	if what == Cert {
+		ca.session.store(key, *pem)
+	}
 	return
 }
 
 func (ca *CA) Delete(caId int64) (err error) {
+	return ca.DeleteCtx(context.Background(), caId)
+}
+
+func (ca *CA) DeleteCtx(ctx context.Context, caId int64) (err error) {
 	type deleted struct{}
-	_, err = ca.session.makeCall("ca/delete", []*fieldValues{{"ca_id", caId}}, &deleted{})
+	_, err = call[deleted](ctx, ca.session, "ca/delete", Fields{}.SetInt("ca_id", caId).Values())
+	if err == nil {
+		ca.Invalidate(caId)
+		ca.session.emit(Event{Type: CADeleted, CAId: caId})
+	}
 	return
 }
 
-type CertificateStatus int
-
-const (
-	Expired CertificateStatus = 1 << iota
-	Good
-	Revoked
-	Hold
-)
-
-type CertificatePart int
+type CertificateInfo struct {
+	Id          int64             `json:"id"`
+	Status      CertificateStatus `json:"status"`
+	CountryCode string            `json:"C"`
+	StateCode   string            `json:"ST"`
+	Locality    string            `json:"L"`
+	OrgName     string            `json:"O"`
+	OrgUnit     string            `json:"OU"`
+	CommonName  string            `json:"CN"`
+	Alt         []SAN             `json:"alt"`
+}
 
-const (
-	CertificateOnly CertificatePart = iota
-	CertificateWithChain
-	CertificateSigningRequest
-	PrivateKeyDecrypted
-	PrivateKeyEncrypted
-	KeyAndCertificate
-)
+type CertificateListItem struct {
+	Id      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Status  CertificateStatus `json:"status"`
+	Expires int64             `json:"expires"`
+	// ExpiresAt is Expires as a time; it is filled in by Certificate.List.
+	ExpiresAt time.Time `json:"expires_at"`
+}
 
-func (cp CertificatePart) toString() string {
-	switch cp {
-	case CertificateOnly:
-		return "cert"
-	case CertificateWithChain:
-		return "chain"
-	case CertificateSigningRequest:
-		return "csr"
-	case PrivateKeyDecrypted:
-		return "key.dec"
-	case PrivateKeyEncrypted:
-		return "key.enc"
-	case KeyAndCertificate:
-		return "pkcs12"
-	}
-	return ""
+// TimeUntilExpiry returns how long the certificate has left by the system
+// clock; it is negative once the certificate has expired.
+func (item *CertificateListItem) TimeUntilExpiry() time.Duration {
+	return item.TimeUntilExpiryAt(time.Now())
 }
 
-func (cs CertificateStatus) toString() string {
-	switch cs {
-	case Expired:
-		return "expired"
-	case Good:
-		return "good"
-	case Hold:
-		return "hold"
-	case Revoked:
-		return "revoked"
-	}
-	return ""
+// TimeUntilExpiryAt returns how long the certificate has left at now, e.g.
+// the time of a session's Clock.
+func (item *CertificateListItem) TimeUntilExpiryAt(now time.Time) time.Duration {
+	return time.Unix(item.Expires, 0).Sub(now)
 }
 
-type SAN struct {
-	DNS   string
-	Email string
-	IP    string
-	URI   string
+// IsExpiringWithin reports whether the certificate expires within d from now,
+// including when it has already expired.
+func (item *CertificateListItem) IsExpiringWithin(d time.Duration) bool {
+	return item.IsExpiringWithinAt(time.Now(), d)
 }
 
-type CertificateInfo struct {
-	Id          int64  `json:"id"`
-	Status      string `json:"status"`
-	CountryCode string `json:"C"`
-	StateCode   string `json:"ST"`
-	Locality    string `json:"L"`
-	OrgName     string `json:"O"`
-	OrgUnit     string `json:"OU"`
-	CommonName  string `json:"CN"`
-	Alt         []SAN  `json:"alt"`
+// IsExpiringWithinAt is IsExpiringWithin at now instead of the system
+// clock's time.
+func (item *CertificateListItem) IsExpiringWithinAt(now time.Time, d time.Duration) bool {
+	return item.TimeUntilExpiryAt(now) < d
 }
 
-type CertificateListItem struct {
-	Id      int64
-	Name    string
-	Status  string
-	Expires int64
+// ParsedStatus returns Status.
+//
+// Deprecated: Status is a CertificateStatus itself; use it directly.
+func (item *CertificateListItem) ParsedStatus() CertificateStatus {
+	return item.Status
 }
 
 type Certificate struct {
-	session *Session
+	session     *Session
+	parallelism int
+	progress    Progress
+	// skipTransitionCheck makes Status skip CanTransitionTo.
+	skipTransitionCheck bool
 }
 
 func NewCertificate(session *Session) *Certificate {
-	return &Certificate{session: session}
+	return &Certificate{session: session, parallelism: DefaultParallelism}
+}
+
+// WithParallelism bounds how many API calls batch operations such as
+// CreateBatch run at once.
+func (c *Certificate) WithParallelism(parallelism int) *Certificate {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	c.parallelism = parallelism
+	return c
+}
+
+// WithTransitionCheck sets whether Status, and Revoke, Hold and Release,
+// fetch the certificate's current status first and fail with
+// ErrInvalidTransition when the change is not allowed, instead of leaving it
+// to the API's error. It is on by default; turning it off saves the extra
+// call, or lets a change through that the server allows after all.
+func (c *Certificate) WithTransitionCheck(check bool) *Certificate {
+	c.skipTransitionCheck = !check
+	return c
 }
 
-func (c *Certificate) Create(caId int64, commonName, orgUnit, orgName, locality, stateCode, countryCode string, alt []SAN) (certId *int64, err error) {
-	list := []*fieldValues{
-		{"C", countryCode},
-		{"CN", commonName},
-		{"L", locality},
-		{"O", orgName},
-		{"OU", orgUnit},
-		{"ST", stateCode},
-		{"ca_id", caId},
+// WithProgress reports the progress of batch operations such as CreateBatch
+// to p.
+func (c *Certificate) WithProgress(p Progress) *Certificate {
+	c.progress = p
+	return c
+}
+
+func (c *Certificate) Create(caId int64, req CertRequest) (certId *int64, err error) {
+	return c.CreateCtx(context.Background(), caId, req)
+}
+
+// CreateCtx validates req and issues a certificate under the CA.
+func (c *Certificate) CreateCtx(ctx context.Context, caId int64, req CertRequest) (certId *int64, err error) {
+	if req, err = req.Normalize(); err != nil {
+		return
 	}
 
-	for index, san := range alt {
+	list := Fields{
+		"C":  {req.CountryCode},
+		"CN": {req.CommonName},
+		"L":  {req.Locality},
+		"O":  {req.OrgName},
+		"OU": {req.OrgUnit},
+		"ST": {req.StateCode},
+	}.SetInt("ca_id", caId).Values()
+
+	for index, san := range req.Alt {
 		prefix := fmt.Sprintf("SANs[%d]", index)
 		if len(san.Email) > 0 {
-			list = append(list, &fieldValues{prefix + "[email]", san.Email})
+			list.Set(prefix+"[email]", san.Email)
 		}
 		if len(san.DNS) > 0 {
-			list = append(list, &fieldValues{prefix + "[DNS]", san.DNS})
+			list.Set(prefix+"[DNS]", san.DNS)
 		}
 		if len(san.IP) > 0 {
-			list = append(list, &fieldValues{prefix + "[IP]", san.IP})
+			list.Set(prefix+"[IP]", san.IP)
 		}
 		if len(san.URI) > 0 {
-			list = append(list, &fieldValues{prefix + "[URI]", san.URI})
+			list.Set(prefix+"[URI]", san.URI)
 		}
 	}
 
@@ -355,66 +808,133 @@ func (c *Certificate) Create(caId int64, commonName, orgUnit, orgName, locality,
 		CertId int64 `json:"cert_id"`
 	}
 
-	res, err := c.session.makeCall("cert/new", list, &idResponse{})
+	res, err := call[idResponse](ctx, c.session, "cert/new", list)
 	if err != nil {
 		return
 	}
-	certId = &res.(*idResponse).CertId
+	certId = &res.CertId
+	c.session.emit(Event{Type: CertificateCreated, CAId: caId, CertId: *certId})
 	return
 }
 
-func (c *Certificate) Get(certId int64, what CertificatePart) (result *string, err error) {
-	type pemInfo struct {
-		Pem    string `json:"pem"`
-		Pkcs12 string `json:"pkcs12"`
+// Get fetches any artifact of a certificate. GetCertPEM, GetChainPEM,
+// GetPrivateKeyPEM and GetPKCS12 say what they return more plainly.
+func (c *Certificate) Get(certId int64, what Artifact) (result *string, err error) {
+	return c.GetCtx(context.Background(), certId, what)
+}
+
+func (c *Certificate) GetCtx(ctx context.Context, certId int64, what Artifact) (result *string, err error) {
+	if !what.valid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidArtifact, int(what))
 	}
-	res, err := c.session.makeCall("cert/get", []*fieldValues{{"cert_id", certId}, {"what", what.toString()}}, &pemInfo{})
-	if err != nil {
+
+	key := CacheKey{Kind: "cert", Id: certId, What: what}
+	if !what.secret() {
+		if cached, ok := c.session.cached(key); ok {
+			return cached, nil
+		}
+	}
+
+	if result, err = c.fetch(ctx, certId, what); err != nil {
 		return
 	}
-	myPemInfo := res.(*pemInfo)
-	if len(myPemInfo.Pem) > 0 {
-		result = &myPemInfo.Pem
-	} else {
-		result = &myPemInfo.Pkcs12
+	if !what.secret() {
+		c.session.store(key, *result)
 	}
 	return
 }
 
-func (c *Certificate) Details(certId int64) (certInfo *CertificateInfo, err error) {
-	res, err := c.session.makeCall("cert/details", []*fieldValues{{"cert_id", certId}}, &CertificateInfo{})
+// fetch asks cert/get for an artifact, bypassing the cache.
+func (c *Certificate) fetch(ctx context.Context, certId int64, what Artifact) (result *string, err error) {
+	type pemInfo struct {
+		Pem    string `json:"pem"`
+		Pkcs12 string `json:"pkcs12"`
+	}
+	res, err := call[pemInfo](ctx, c.session, "cert/get", Fields{}.SetInt("cert_id", certId).Set("what", what.String()).Values())
 	if err != nil {
 		return
 	}
-	certInfo = res.(*CertificateInfo)
+	// cert/get answers pkcs12 requests in the pkcs12 field and everything
+	// else in the pem field.
+	if what == PKCS12 {
+		result = &res.Pkcs12
+	} else {
+		result = &res.Pem
+	}
+	if *result == "" {
+		return nil, fmt.Errorf("tinycert: cert/get returned no %s for certificate %d", what, certId)
+	}
 	return
 }
 
 func (c *Certificate) List(caId int64, status CertificateStatus) (list []*CertificateListItem, err error) {
-	res, err := c.session.makeCall("cert/list", []*fieldValues{{"ca_id", caId}, {"what", status}}, &[]*CertificateListItem{})
+	return c.ListCtx(context.Background(), caId, status)
+}
+
+func (c *Certificate) ListCtx(ctx context.Context, caId int64, status CertificateStatus) (list []*CertificateListItem, err error) {
+	if status == 0 || status&^AnyStatus != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+
+	res, err := call[[]*CertificateListItem](ctx, c.session, "cert/list", Fields{}.SetInt("ca_id", caId).SetInt("what", int64(status)).Values())
 	if err != nil {
 		return
 	}
-	list = *res.(*[]*CertificateListItem)
+	list = *res
+	for _, item := range list {
+		item.ExpiresAt = time.Unix(item.Expires, 0).UTC()
+	}
 	return
 }
 
 func (c *Certificate) Reissue(certId int64) (newCertId *int64, err error) {
+	return c.ReissueCtx(context.Background(), certId)
+}
+
+func (c *Certificate) ReissueCtx(ctx context.Context, certId int64) (newCertId *int64, err error) {
+	return c.reissue(ctx, certId, "")
+}
+
+// reissue reissues the certificate and records the replacement in the
+// lineage with reason.
+func (c *Certificate) reissue(ctx context.Context, certId int64, reason string) (newCertId *int64, err error) {
 	type idResponse struct {
 		CertId int64 `json:"cert_id"`
 	}
 
-	res, err := c.session.makeCall("cert/reissue", []*fieldValues{{"cert_id", certId}}, &idResponse{})
+	res, err := call[idResponse](ctx, c.session, "cert/reissue", Fields{}.SetInt("cert_id", certId).Values())
 	if err != nil {
 		return
 	}
-	newCertId = &res.(*idResponse).CertId
+	newCertId = &res.CertId
+	c.session.lineage.Record(certId, *newCertId, reason)
+	c.session.emit(Event{Type: CertificateReissued, CertId: *newCertId, PreviousCertId: certId})
 	return
 }
 
 func (c *Certificate) Status(certId int64, status CertificateStatus) (err error) {
+	return c.StatusCtx(context.Background(), certId, status)
+}
+
+func (c *Certificate) StatusCtx(ctx context.Context, certId int64, status CertificateStatus) (err error) {
+	if status.toString() == "" {
+		return fmt.Errorf("%w: %s, expected a single status", ErrInvalidStatus, status)
+	}
+	if !c.skipTransitionCheck {
+		var info *CertificateInfo
+		if info, err = c.DetailsCtx(ctx, certId); err != nil {
+			return
+		}
+		if !info.Status.CanTransitionTo(status) {
+			return fmt.Errorf("%w: certificate %d is %s and cannot be made %s", ErrInvalidTransition, certId, info.Status, status)
+		}
+	}
+
 	type updated struct{}
 
-	_, err = c.session.makeCall("cert/status", []*fieldValues{{"cert_id", certId}, {"status", status.toString()}}, &updated{})
+	_, err = call[updated](ctx, c.session, "cert/status", Fields{}.SetInt("cert_id", certId).Set("status", status.toString()).Values())
+	if err == nil {
+		c.session.emit(Event{Type: CertificateStatusChanged, CertId: certId, Status: status})
+	}
 	return
 }
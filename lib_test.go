@@ -20,7 +20,7 @@ func Test_CA(t *testing.T) {
 
 	ca := tinycert.NewCA(sess)
 
-	caId, err := ca.Create("splunk", "ca", "ca", "US", "sha256")
+	caId, err := ca.Create(tinycert.CARequest{OrgName: "splunk", Locality: "ca", StateCode: "ca", CountryCode: "US"})
 	if err != nil {
 		t.Fatal("unable to create ca", err)
 	}
@@ -36,7 +36,14 @@ func Test_CA(t *testing.T) {
 
 	cert := tinycert.NewCertificate(sess)
 
-	newCertId, err := cert.Create(*caId, "hello", "ou", "oname", "sj", "CA", "US", nil)
+	newCertId, err := cert.Create(*caId, tinycert.CertRequest{
+		CommonName:  "hello",
+		OrgUnit:     "ou",
+		OrgName:     "oname",
+		Locality:    "sj",
+		StateCode:   "CA",
+		CountryCode: "US",
+	})
 	if err != nil {
 		t.Fatal("error building certificate", err)
 	}
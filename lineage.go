@@ -0,0 +1,171 @@
+package tinycert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnknownLineage is returned by ReissueWithOptions with a Request when the
+// certificate is in none of the account's CAs, so the CA to issue its
+// replacement under is unknown.
+var ErrUnknownLineage = errors.New("tinycert: certificate not found in any CA")
+
+// Replacement records that one certificate was replaced by another.
+type Replacement struct {
+	OldCertId int64
+	NewCertId int64
+	Reason    string
+	At        time.Time
+}
+
+// LineageStore remembers which certificate replaced which. TinyCert does not
+// expose this itself, so it is recorded client-side by Reissue; plug in a
+// persistent store with WithLineageStore to keep it across processes.
+type LineageStore interface {
+	Record(oldCertId, newCertId int64, reason string)
+	// ReplacedBy returns the replacement of certId, if any.
+	ReplacedBy(certId int64) (Replacement, bool)
+	// Replaces returns the replacement that produced certId, if any.
+	Replaces(certId int64) (Replacement, bool)
+}
+
+func (s *Session) WithLineageStore(store LineageStore) *Session {
+	s.lineage = store
+	return s
+}
+
+type memoryLineage struct {
+	now        func() time.Time
+	mu         sync.Mutex
+	replacedBy map[int64]Replacement
+	replaces   map[int64]Replacement
+}
+
+// NewMemoryLineage returns a LineageStore held in memory, timing replacements
+// by the system clock. A session's default store uses the session's Clock.
+func NewMemoryLineage() LineageStore {
+	return newMemoryLineage(time.Now)
+}
+
+func newMemoryLineage(now func() time.Time) *memoryLineage {
+	return &memoryLineage{now: now, replacedBy: map[int64]Replacement{}, replaces: map[int64]Replacement{}}
+}
+
+func (ml *memoryLineage) Record(oldCertId, newCertId int64, reason string) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	r := Replacement{OldCertId: oldCertId, NewCertId: newCertId, Reason: reason, At: ml.now()}
+	ml.replacedBy[oldCertId] = r
+	ml.replaces[newCertId] = r
+}
+
+func (ml *memoryLineage) ReplacedBy(certId int64) (Replacement, bool) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	r, ok := ml.replacedBy[certId]
+	return r, ok
+}
+
+func (ml *memoryLineage) Replaces(certId int64) (Replacement, bool) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	r, ok := ml.replaces[certId]
+	return r, ok
+}
+
+// ReissueOptions customise ReissueWithOptions.
+type ReissueOptions struct {
+	// Reason is recorded in the lineage.
+	Reason string
+	// Request, when set, changes the subject or SANs: a new certificate is
+	// issued from it under the same CA instead of reissuing the old one as is.
+	Request *CertRequest
+	// RevokeOld revokes the replaced certificate once the new one exists.
+	RevokeOld bool
+}
+
+func (c *Certificate) ReissueWithOptions(certId int64, opts ReissueOptions) (newCertId *int64, err error) {
+	return c.ReissueWithOptionsCtx(context.Background(), certId, opts)
+}
+
+func (c *Certificate) ReissueWithOptionsCtx(ctx context.Context, certId int64, opts ReissueOptions) (newCertId *int64, err error) {
+	if _, ok := ctx.Value(auditReasonKey{}).(string); !ok && opts.Reason != "" {
+		ctx = WithAuditReason(ctx, opts.Reason)
+	}
+
+	if opts.Request == nil {
+		if newCertId, err = c.reissue(ctx, certId, opts.Reason); err != nil {
+			return
+		}
+	} else {
+		var caId int64
+		if caId, err = c.caOf(ctx, certId); err != nil {
+			return
+		}
+		if newCertId, err = c.CreateCtx(ctx, caId, *opts.Request); err != nil {
+			return
+		}
+		c.session.lineage.Record(certId, *newCertId, opts.Reason)
+		c.session.emit(Event{Type: CertificateReissued, CAId: caId, CertId: *newCertId, PreviousCertId: certId})
+	}
+
+	if opts.RevokeOld {
+		err = c.StatusCtx(ctx, certId, Revoked)
+	}
+	return
+}
+
+// caOf finds the CA a certificate was issued by; cert/details does not
+// report it, so the CAs' certificate lists are searched.
+func (c *Certificate) caOf(ctx context.Context, certId int64) (caId int64, err error) {
+	cas, err := NewCA(c.session).ListCtx(ctx)
+	if err != nil {
+		return
+	}
+	for _, ca := range cas {
+		var items []*CertificateListItem
+		if items, err = c.ListCtx(ctx, ca.Id, AnyStatus); err != nil {
+			return
+		}
+		for _, item := range items {
+			if item.Id == certId {
+				return ca.Id, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: certificate %d", ErrUnknownLineage, certId)
+}
+
+// History returns the ids of every certificate in certId's lineage, oldest
+// first, as recorded by this session's LineageStore.
+func (c *Certificate) History(certId int64) []int64 {
+	store := c.session.lineage
+
+	oldest := certId
+	seen := map[int64]bool{oldest: true}
+	for {
+		r, ok := store.Replaces(oldest)
+		if !ok || seen[r.OldCertId] {
+			break
+		}
+		oldest = r.OldCertId
+		seen[oldest] = true
+	}
+
+	history := []int64{oldest}
+	seen = map[int64]bool{oldest: true}
+	for id := oldest; ; {
+		r, ok := store.ReplacedBy(id)
+		if !ok || seen[r.NewCertId] {
+			break
+		}
+		id = r.NewCertId
+		seen[id] = true
+		history = append(history, id)
+	}
+	return history
+}
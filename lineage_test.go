@@ -0,0 +1,107 @@
+package tinycert_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_History(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	second, err := cert.Reissue(certId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := cert.ReissueWithOptions(*second, tinycert.ReissueOptions{
+		Reason:    "add SAN",
+		Request:   &tinycert.CertRequest{CommonName: "www.example.com", Alt: []tinycert.SAN{tinycert.DNSName("www.example.com"), tinycert.DNSName("example.com")}},
+		RevokeOld: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{certId, *second, *third}
+	for _, id := range want {
+		if got := cert.History(id); !reflect.DeepEqual(got, want) {
+			t.Errorf("History(%d) = %v, want %v", id, got, want)
+		}
+	}
+
+	info, err := cert.Details(*second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != tinycert.Revoked {
+		t.Errorf("replaced certificate status = %v, want revoked", info.Status)
+	}
+	if info, _ := cert.Details(*third); len(info.Alt) != 2 {
+		t.Errorf("new certificate SANs = %+v", info.Alt)
+	}
+}
+
+func TestCertificate_ReissueWithOptionsUnknownCA(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	newCAAndCert(t, sess)
+
+	_, err := tinycert.NewCertificate(sess).ReissueWithOptions(999, tinycert.ReissueOptions{
+		Request: &tinycert.CertRequest{CommonName: "www.example.com"},
+	})
+	if !errors.Is(err, tinycert.ErrUnknownLineage) {
+		t.Error("expected ErrUnknownLineage, got", err)
+	}
+	if errors.Is(err, tinycert.ErrNotFound) {
+		t.Error("a certificate missing from the CA lists was reported as a server 404")
+	}
+}
+
+// recordingLineage keeps every Record call on top of a memory store.
+type recordingLineage struct {
+	tinycert.LineageStore
+	records []tinycert.Replacement
+}
+
+func (rl *recordingLineage) Record(oldCertId, newCertId int64, reason string) {
+	rl.records = append(rl.records, tinycert.Replacement{OldCertId: oldCertId, NewCertId: newCertId, Reason: reason})
+	rl.LineageStore.Record(oldCertId, newCertId, reason)
+}
+
+func TestCertificate_ReissueRecordsOnce(t *testing.T) {
+	fs := newFakeServer(t)
+	lineage := &recordingLineage{LineageStore: tinycert.NewMemoryLineage()}
+	sess := fs.connectedSession().WithLineageStore(lineage)
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	second, err := cert.ReissueWithOptions(certId, tinycert.ReissueOptions{Reason: "key compromise"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := cert.ReissueWithOptions(*second, tinycert.ReissueOptions{
+		Reason:  "add SAN",
+		Request: &tinycert.CertRequest{CommonName: "www.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fourth, err := cert.Reissue(*third)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []tinycert.Replacement{
+		{OldCertId: certId, NewCertId: *second, Reason: "key compromise"},
+		{OldCertId: *second, NewCertId: *third, Reason: "add SAN"},
+		{OldCertId: *third, NewCertId: *fourth},
+	}
+	if !reflect.DeepEqual(lineage.records, want) {
+		t.Errorf("records = %+v, want %+v", lineage.records, want)
+	}
+}
@@ -0,0 +1,235 @@
+package tinycert_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_ListDecodesFixture(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/cert_list.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/list" {
+			return http.StatusOK, string(fixture)
+		}
+		return 0, ""
+	})
+
+	items, err := tinycert.NewCertificate(sess).List(1, tinycert.Good|tinycert.Revoked|tinycert.Expired|tinycert.Hold)
+	if err != nil {
+		t.Fatal("unable to list certificates", err)
+	}
+
+	want := []struct {
+		id      int64
+		name    string
+		status  tinycert.CertificateStatus
+		expires time.Time
+	}{
+		{2101, "www.example.com", tinycert.Good, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{2102, "api.example.com", tinycert.Revoked, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{2103, "old.example.com", tinycert.Expired, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{2104, "db.example.com", tinycert.Hold, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.Id != w.id || item.Name != w.name || item.Status != w.status || !item.ExpiresAt.Equal(w.expires) {
+			t.Errorf("item %d = %+v, want %+v", i, item, w)
+		}
+	}
+
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []*tinycert.CertificateListItem
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, items) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", decoded, items)
+	}
+}
+
+func TestCertificate_ListFiltered(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	body := readFixture(t, "cert_list.json")
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/list" {
+			return http.StatusOK, body
+		}
+		return 0, ""
+	})
+
+	tests := []struct {
+		name   string
+		filter tinycert.CertificateFilter
+		want   []int64
+	}{
+		{"zero", tinycert.CertificateFilter{}, []int64{2101, 2102, 2103, 2104}},
+		{"name", tinycert.CertificateFilter{Name: regexp.MustCompile(`^(www|api)\.`)}, []int64{2101, 2102}},
+		{"common name", tinycert.CertificateFilter{CommonName: "db.example.com"}, []int64{2104}},
+		{"status", tinycert.CertificateFilter{Status: tinycert.Good | tinycert.Hold}, []int64{2101, 2104}},
+		{"expiry window", tinycert.CertificateFilter{
+			ExpiresAfter:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			ExpiresBefore: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		}, []int64{2101, 2102}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := tinycert.NewCertificate(sess).ListFiltered(1, tt.filter)
+			if err != nil {
+				t.Fatal("unable to list certificates", err)
+			}
+			var got []int64
+			for _, item := range items {
+				got = append(got, item.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificate_ListPages(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	body := readFixture(t, "cert_list.json")
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/list" {
+			return http.StatusOK, body
+		}
+		return 0, ""
+	})
+
+	pages := tinycert.NewCertificate(sess).ListPages(1, tinycert.CertificateFilter{}, 3)
+	var sizes []int
+	for pages.Next() {
+		sizes = append(sizes, len(pages.Page()))
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal("unable to page certificates", err)
+	}
+	if !reflect.DeepEqual(sizes, []int{3, 1}) {
+		t.Errorf("page sizes = %v", sizes)
+	}
+	if got := fs.callCount("cert/list"); got != 1 {
+		t.Errorf("cert/list called %d times, want 1", got)
+	}
+}
+
+func TestCA_ListPages(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	ca := tinycert.NewCA(sess)
+	for _, name := range []string{"acme", "globex", "acme-dev"} {
+		if _, err := ca.Create(tinycert.CARequest{OrgName: name, Locality: "sj", StateCode: "CA", CountryCode: "US"}); err != nil {
+			t.Fatal("unable to create ca", err)
+		}
+	}
+
+	pages := ca.ListPages(tinycert.CAFilter{Name: regexp.MustCompile(`^acme`)}, 10)
+	var names []string
+	for pages.Next() {
+		for _, item := range pages.Page() {
+			names = append(names, item.Name)
+		}
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal("unable to page cas", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("names = %v", names)
+	}
+
+	fs.setFail(func(api string) (int, string) { return http.StatusServiceUnavailable, `{"code":"503","text":"down"}` })
+	pages = ca.ListPages(tinycert.CAFilter{}, 10)
+	if pages.Next() || pages.Err() == nil {
+		t.Error("expected paging to stop with an error")
+	}
+}
+
+func TestCertificateListItem_Expiry(t *testing.T) {
+	soon := &tinycert.CertificateListItem{Expires: time.Now().Add(48 * time.Hour).Unix()}
+	if left := soon.TimeUntilExpiry(); left <= 47*time.Hour || left > 48*time.Hour {
+		t.Errorf("TimeUntilExpiry() = %v, want about 48h", left)
+	}
+	if !soon.IsExpiringWithin(7*24*time.Hour) || soon.IsExpiringWithin(24*time.Hour) {
+		t.Error("IsExpiringWithin() disagrees with a 48h expiry")
+	}
+
+	expired := &tinycert.CertificateListItem{Expires: time.Now().Add(-time.Hour).Unix()}
+	if expired.TimeUntilExpiry() >= 0 || !expired.IsExpiringWithin(0) {
+		t.Error("an expired certificate should have negative time left")
+	}
+}
+
+func TestCertificate_ListDetailed(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess).WithParallelism(2)
+	for _, cn := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		if _, err := cert.Create(caId, tinycert.CertRequest{CommonName: cn, Alt: []tinycert.SAN{{DNS: cn}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	certs := cert.ListDetailed(caId, tinycert.Good)
+	if got := fs.callCount("cert/list"); got != 0 {
+		t.Errorf("cert/list called %d times before Next", got)
+	}
+	if !certs.Next() {
+		t.Fatal("Next() = false", certs.Err())
+	}
+	if got := fs.callCount("cert/details"); got != 2 {
+		t.Errorf("cert/details called %d times for the first batch, want 2", got)
+	}
+
+	var names []string
+	for ok := true; ok; ok = certs.Next() {
+		c := certs.Certificate()
+		if c.Info.Id != c.Item.Id || c.Info.CommonName != c.Item.Name {
+			t.Errorf("details %+v do not match item %+v", c.Info, c.Item)
+		}
+		names = append(names, c.Info.CommonName)
+	}
+	if err := certs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if want := []string{"a.example.com", "b.example.com", "c.example.com", "www.example.com"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/details" {
+			return http.StatusNotFound, `{"code":"404","text":"gone"}`
+		}
+		return 0, ""
+	})
+	certs = cert.ListDetailed(caId, tinycert.Good)
+	if certs.Next() || !errors.Is(certs.Err(), tinycert.ErrNotFound) {
+		t.Error("expected iteration to stop with ErrNotFound, got", certs.Err())
+	}
+}
@@ -0,0 +1,70 @@
+package tinycert
+
+import (
+	"fmt"
+	"log"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Logger receives diagnostic output from a Session. Sessions log nothing
+// unless a Logger is configured with WithLogger.
+type Logger interface {
+	Log(level Level, format string, args ...interface{})
+}
+
+// LoggerFunc adapts a printf-style function to the Logger interface,
+// prefixing each message with its level.
+type LoggerFunc func(format string, args ...interface{})
+
+func (f LoggerFunc) Log(level Level, format string, args ...interface{}) {
+	f("["+level.String()+"] "+format, args...)
+}
+
+// NewStdLogger returns a Logger writing messages at or above min to l.
+func NewStdLogger(l *log.Logger, min Level) Logger {
+	return &stdLogger{l: l, min: min}
+}
+
+type stdLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+func (sl *stdLogger) Log(level Level, format string, args ...interface{}) {
+	if level < sl.min {
+		return
+	}
+	sl.l.Printf("["+level.String()+"] "+format+"\n", args...)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...interface{}) {}
+
+var sensitiveFields = map[string]bool{
+	"passphrase": true,
+	"token":      true,
+	"digest":     true,
+}
@@ -0,0 +1,166 @@
+// Package migrate moves certificates between a TinyCert CA and a mount of
+// HashiCorp Vault's PKI secrets engine.
+//
+// Neither system hands out the private key of a certificate it has already
+// issued. Vault never stores the keys, and TinyCert will not export a CA key.
+// Migrating therefore reissues: each live certificate in the source is issued
+// again in the target with the same subject, SANs and remaining lifetime.
+// Clients then switch over to the new certificate. ImportCA additionally
+// makes a TinyCert CA known to Vault, so chains to it still verify while
+// services move over.
+package migrate
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// Migrated pairs a source certificate with the one issued in its place.
+type Migrated struct {
+	// Source and Target identify the certificates: TinyCert ids in decimal,
+	// Vault serial numbers as Vault prints them.
+	Source     string
+	Target     string
+	CommonName string
+	// CertPEM and KeyPEM are set when migrating to Vault, which returns the
+	// new key only once.
+	CertPEM string
+	KeyPEM  string
+}
+
+// ToVault issues every good certificate of the TinyCert CA again through the
+// Vault role, stopping at the first failure. It returns what was migrated so
+// far in either case.
+func ToVault(ctx context.Context, sess *tinycert.Session, caId int64, v Vault, role string) (migrated []Migrated, err error) {
+	cert := tinycert.NewCertificate(sess)
+	items, err := cert.ListCtx(ctx, caId, tinycert.Good)
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		var info *tinycert.CertificateInfo
+		if info, err = cert.DetailsCtx(ctx, item.Id); err != nil {
+			return
+		}
+		req := IssueRequest{CommonName: info.CommonName, TTL: time.Until(item.ExpiresAt)}
+		for _, san := range info.Alt {
+			switch {
+			case san.DNS != "":
+				req.AltNames = append(req.AltNames, san.DNS)
+			case san.Email != "":
+				req.AltNames = append(req.AltNames, san.Email)
+			case san.IP != "":
+				req.IPSans = append(req.IPSans, san.IP)
+			case san.URI != "":
+				req.URISans = append(req.URISans, san.URI)
+			}
+		}
+
+		var issued *Issued
+		if issued, err = v.Issue(ctx, role, req); err != nil {
+			return
+		}
+		migrated = append(migrated, Migrated{
+			Source:     fmt.Sprint(item.Id),
+			Target:     issued.Serial,
+			CommonName: info.CommonName,
+			CertPEM:    issued.Certificate,
+			KeyPEM:     issued.PrivateKey,
+		})
+	}
+	return
+}
+
+// FromVault issues every certificate in the Vault mount again under the
+// TinyCert CA. Revoked, expired and CA certificates are skipped. TinyCert
+// sets its own lifetime, so the remaining validity is not carried over.
+func FromVault(ctx context.Context, v Vault, sess *tinycert.Session, caId int64) (migrated []Migrated, err error) {
+	serials, err := v.ListCerts(ctx)
+	if err != nil {
+		return
+	}
+
+	cert := tinycert.NewCertificate(sess)
+	for _, serial := range serials {
+		var vc *VaultCertificate
+		if vc, err = v.ReadCert(ctx, serial); err != nil {
+			return
+		}
+		if vc.Revoked {
+			continue
+		}
+		var parsed *x509.Certificate
+		if parsed, err = parseCertificate(vc.Certificate); err != nil {
+			return migrated, fmt.Errorf("migrate: certificate %s: %w", serial, err)
+		}
+		if parsed.IsCA || time.Now().After(parsed.NotAfter) {
+			continue
+		}
+
+		var certId *int64
+		if certId, err = cert.CreateCtx(ctx, caId, requestFromCertificate(parsed)); err != nil {
+			return
+		}
+		migrated = append(migrated, Migrated{
+			Source:     serial,
+			Target:     fmt.Sprint(*certId),
+			CommonName: parsed.Subject.CommonName,
+		})
+	}
+	return
+}
+
+// ImportCA adds the TinyCert CA certificate to the Vault mount as an issuer
+// and returns the ids Vault gave it.
+func ImportCA(ctx context.Context, sess *tinycert.Session, caId int64, v Vault) (issuers []string, err error) {
+	caPEM, err := tinycert.NewCA(sess).GetCtx(ctx, caId)
+	if err != nil {
+		return
+	}
+	return v.ImportIssuer(ctx, *caPEM)
+}
+
+func parseCertificate(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate pem")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func requestFromCertificate(cert *x509.Certificate) tinycert.CertRequest {
+	first := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	req := tinycert.CertRequest{
+		CommonName:  cert.Subject.CommonName,
+		OrgUnit:     first(cert.Subject.OrganizationalUnit),
+		OrgName:     first(cert.Subject.Organization),
+		Locality:    first(cert.Subject.Locality),
+		StateCode:   first(cert.Subject.Province),
+		CountryCode: first(cert.Subject.Country),
+	}
+	for _, name := range cert.DNSNames {
+		req.Alt = append(req.Alt, tinycert.SAN{DNS: name})
+	}
+	for _, ip := range cert.IPAddresses {
+		req.Alt = append(req.Alt, tinycert.SAN{IP: ip.String()})
+	}
+	for _, email := range cert.EmailAddresses {
+		req.Alt = append(req.Alt, tinycert.SAN{Email: email})
+	}
+	for _, uri := range cert.URIs {
+		req.Alt = append(req.Alt, tinycert.SAN{URI: uri.String()})
+	}
+	return req
+}
@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault talks to one mount of Vault's PKI secrets engine over its HTTP API.
+// Address and Token default to VAULT_ADDR and VAULT_TOKEN, Mount to "pki".
+type Vault struct {
+	Address string
+	Token   string
+	Mount   string
+	Client  *http.Client
+}
+
+// VaultCertificate is a certificate stored by the PKI engine.
+type VaultCertificate struct {
+	Serial      string
+	Certificate string
+	Revoked     bool
+}
+
+// IssueRequest describes a certificate for Vault to issue. The role decides
+// which of the names it accepts; a zero TTL uses the role's default.
+type IssueRequest struct {
+	CommonName string
+	AltNames   []string
+	IPSans     []string
+	URISans    []string
+	TTL        time.Duration
+}
+
+// Issued is what the PKI engine returns for a new certificate. Vault keeps no
+// copy of PrivateKey; it must be stored by the caller.
+type Issued struct {
+	Serial      string   `json:"serial_number"`
+	Certificate string   `json:"certificate"`
+	PrivateKey  string   `json:"private_key"`
+	IssuingCA   string   `json:"issuing_ca"`
+	CAChain     []string `json:"ca_chain"`
+}
+
+// ListCerts returns the serial numbers of every certificate in the mount.
+func (v Vault) ListCerts(ctx context.Context) (serials []string, err error) {
+	var data struct {
+		Keys []string `json:"keys"`
+	}
+	err = v.do(ctx, "LIST", "certs", nil, &data)
+	return data.Keys, err
+}
+
+// ReadCert returns the certificate with the given serial number.
+func (v Vault) ReadCert(ctx context.Context, serial string) (cert *VaultCertificate, err error) {
+	var data struct {
+		Certificate    string `json:"certificate"`
+		RevocationTime int64  `json:"revocation_time"`
+	}
+	if err = v.do(ctx, http.MethodGet, "cert/"+url.PathEscape(serial), nil, &data); err != nil {
+		return
+	}
+	return &VaultCertificate{Serial: serial, Certificate: data.Certificate, Revoked: data.RevocationTime != 0}, nil
+}
+
+// Issue issues a certificate under role.
+func (v Vault) Issue(ctx context.Context, role string, req IssueRequest) (issued *Issued, err error) {
+	body := map[string]string{"common_name": req.CommonName}
+	if len(req.AltNames) > 0 {
+		body["alt_names"] = strings.Join(req.AltNames, ",")
+	}
+	if len(req.IPSans) > 0 {
+		body["ip_sans"] = strings.Join(req.IPSans, ",")
+	}
+	if len(req.URISans) > 0 {
+		body["uri_sans"] = strings.Join(req.URISans, ",")
+	}
+	if req.TTL > 0 {
+		body["ttl"] = fmt.Sprintf("%ds", int64(req.TTL/time.Second))
+	}
+
+	issued = &Issued{}
+	if err = v.do(ctx, http.MethodPost, "issue/"+url.PathEscape(role), body, issued); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// ImportIssuer adds PEM encoded CA certificates to the mount as issuers. Vault
+// can then verify and chain to them, but not sign with them without a key.
+func (v Vault) ImportIssuer(ctx context.Context, pemBundle string) (issuers []string, err error) {
+	var data struct {
+		ImportedIssuers []string `json:"imported_issuers"`
+	}
+	err = v.do(ctx, http.MethodPost, "issuers/import/cert", map[string]string{"pem_bundle": pemBundle}, &data)
+	return data.ImportedIssuers, err
+}
+
+// do calls path below the mount and decodes the "data" member of the reply
+// into out.
+func (v Vault) do(ctx context.Context, method, path string, in interface{}, out interface{}) (err error) {
+	addr, token, mount, clt := v.Address, v.Token, v.Mount, v.Client
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if mount == "" {
+		mount = "pki"
+	}
+	if clt == nil {
+		clt = http.DefaultClient
+	}
+
+	var body io.Reader
+	if in != nil {
+		var data []byte
+		if data, err = json.Marshal(in); err != nil {
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+
+	endpoint := strings.TrimRight(addr, "/") + "/v1/" + strings.Trim(mount, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := clt.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	reply, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(reply, &vaultErr)
+		return &VaultError{HTTPStatus: resp.StatusCode, Path: path, Errors: vaultErr.Errors}
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(reply, &envelope); err != nil {
+		return fmt.Errorf("migrate: unable to parse vault reply for %s: %w", path, err)
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// VaultError is returned when Vault rejects a call.
+type VaultError struct {
+	HTTPStatus int
+	Path       string
+	Errors     []string
+}
+
+func (e *VaultError) Error() string {
+	return fmt.Sprintf("migrate: vault returned %d for %s: %s", e.HTTPStatus, e.Path, strings.Join(e.Errors, "; "))
+}
@@ -0,0 +1,87 @@
+package migrate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert/migrate"
+)
+
+func fakeVault(t *testing.T) (migrate.Vault, *map[string]string) {
+	t.Helper()
+	var issueBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "LIST /v1/pki-int/certs":
+			w.Write([]byte(`{"data":{"keys":["01:02","03:04"]}}`))
+		case "GET /v1/pki-int/cert/01:02":
+			w.Write([]byte(`{"data":{"certificate":"PEM","revocation_time":0}}`))
+		case "GET /v1/pki-int/cert/03:04":
+			w.Write([]byte(`{"data":{"certificate":"PEM","revocation_time":1700000000}}`))
+		case "POST /v1/pki-int/issue/web":
+			json.NewDecoder(r.Body).Decode(&issueBody)
+			w.Write([]byte(`{"data":{"serial_number":"05:06","certificate":"CERT","private_key":"KEY"}}`))
+		case "POST /v1/pki-int/issuers/import/cert":
+			w.Write([]byte(`{"data":{"imported_issuers":["abc"]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":["no handler for route"]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return migrate.Vault{Address: srv.URL, Token: "root", Mount: "pki-int"}, &issueBody
+}
+
+func TestVault(t *testing.T) {
+	v, issueBody := fakeVault(t)
+	ctx := context.Background()
+
+	serials, err := v.ListCerts(ctx)
+	if err != nil || !reflect.DeepEqual(serials, []string{"01:02", "03:04"}) {
+		t.Errorf("ListCerts() = %v, %v", serials, err)
+	}
+	if cert, err := v.ReadCert(ctx, "03:04"); err != nil || !cert.Revoked || cert.Certificate != "PEM" {
+		t.Errorf("ReadCert() = %+v, %v", cert, err)
+	}
+
+	issued, err := v.Issue(ctx, "web", migrate.IssueRequest{
+		CommonName: "www.example.com",
+		AltNames:   []string{"www.example.com", "example.com"},
+		IPSans:     []string{"10.0.0.1"},
+		TTL:        90 * time.Minute,
+	})
+	if err != nil || issued.Serial != "05:06" || issued.PrivateKey != "KEY" {
+		t.Errorf("Issue() = %+v, %v", issued, err)
+	}
+	want := map[string]string{
+		"common_name": "www.example.com",
+		"alt_names":   "www.example.com,example.com",
+		"ip_sans":     "10.0.0.1",
+		"ttl":         "5400s",
+	}
+	if !reflect.DeepEqual(*issueBody, want) {
+		t.Errorf("issue body = %v, want %v", *issueBody, want)
+	}
+
+	if issuers, err := v.ImportIssuer(ctx, "CA PEM"); err != nil || len(issuers) != 1 {
+		t.Errorf("ImportIssuer() = %v, %v", issuers, err)
+	}
+
+	v.Token = "wrong"
+	_, err = v.ListCerts(ctx)
+	var vaultErr *migrate.VaultError
+	if !errors.As(err, &vaultErr) || vaultErr.HTTPStatus != http.StatusForbidden || vaultErr.Errors[0] != "permission denied" {
+		t.Errorf("ListCerts() with a bad token = %v", err)
+	}
+}
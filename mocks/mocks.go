@@ -0,0 +1,167 @@
+// Package mocks provides fakes of the tinycert service interfaces for unit
+// tests that should run without a network or a TinyCert server:
+//
+//	ca := &mocks.CAService{
+//		DetailsFunc: func(ctx context.Context, caId int64) (*tinycert.CAInfo, error) {
+//			return &tinycert.CAInfo{Id: caId, CommonName: "Test CA"}, nil
+//		},
+//	}
+//	runCodeUnderTest(ca)
+//	if calls := ca.Calls(); len(calls) != 1 { ... }
+//
+// Each method calls the matching function field and records the call. A
+// method whose function is nil fails with ErrNotMocked.
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/srohatgi/tinycert"
+)
+
+var ErrNotMocked = errors.New("mocks: method not mocked")
+
+// Call records one method call and its arguments, without the context.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *recorder) record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls made so far, in order.
+func (r *recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+func notMocked(method string) error {
+	return fmt.Errorf("%w: %s", ErrNotMocked, method)
+}
+
+// CAService is a tinycert.CAService whose methods call its function fields.
+type CAService struct {
+	CreateFunc      func(ctx context.Context, req tinycert.CARequest) (*int64, error)
+	ListFunc        func(ctx context.Context) ([]*tinycert.CAListItem, error)
+	DetailsFunc     func(ctx context.Context, caId int64) (*tinycert.CAInfo, error)
+	GetArtifactFunc func(ctx context.Context, caId int64, what tinycert.Artifact) (*string, error)
+	DeleteFunc      func(ctx context.Context, caId int64) error
+	recorder
+}
+
+var _ tinycert.CAService = (*CAService)(nil)
+
+func (m *CAService) CreateCtx(ctx context.Context, req tinycert.CARequest) (*int64, error) {
+	m.record("Create", req)
+	if m.CreateFunc == nil {
+		return nil, notMocked("CAService.Create")
+	}
+	return m.CreateFunc(ctx, req)
+}
+
+func (m *CAService) ListCtx(ctx context.Context) ([]*tinycert.CAListItem, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return nil, notMocked("CAService.List")
+	}
+	return m.ListFunc(ctx)
+}
+
+func (m *CAService) DetailsCtx(ctx context.Context, caId int64) (*tinycert.CAInfo, error) {
+	m.record("Details", caId)
+	if m.DetailsFunc == nil {
+		return nil, notMocked("CAService.Details")
+	}
+	return m.DetailsFunc(ctx, caId)
+}
+
+func (m *CAService) GetArtifactCtx(ctx context.Context, caId int64, what tinycert.Artifact) (*string, error) {
+	m.record("GetArtifact", caId, what)
+	if m.GetArtifactFunc == nil {
+		return nil, notMocked("CAService.GetArtifact")
+	}
+	return m.GetArtifactFunc(ctx, caId, what)
+}
+
+func (m *CAService) DeleteCtx(ctx context.Context, caId int64) error {
+	m.record("Delete", caId)
+	if m.DeleteFunc == nil {
+		return notMocked("CAService.Delete")
+	}
+	return m.DeleteFunc(ctx, caId)
+}
+
+// CertificateService is a tinycert.CertificateService whose methods call its
+// function fields.
+type CertificateService struct {
+	CreateFunc  func(ctx context.Context, caId int64, req tinycert.CertRequest) (*int64, error)
+	ListFunc    func(ctx context.Context, caId int64, status tinycert.CertificateStatus) ([]*tinycert.CertificateListItem, error)
+	DetailsFunc func(ctx context.Context, certId int64) (*tinycert.CertificateInfo, error)
+	GetFunc     func(ctx context.Context, certId int64, what tinycert.Artifact) (*string, error)
+	ReissueFunc func(ctx context.Context, certId int64, opts tinycert.ReissueOptions) (*int64, error)
+	StatusFunc  func(ctx context.Context, certId int64, status tinycert.CertificateStatus) error
+	recorder
+}
+
+var _ tinycert.CertificateService = (*CertificateService)(nil)
+
+func (m *CertificateService) CreateCtx(ctx context.Context, caId int64, req tinycert.CertRequest) (*int64, error) {
+	m.record("Create", caId, req)
+	if m.CreateFunc == nil {
+		return nil, notMocked("CertificateService.Create")
+	}
+	return m.CreateFunc(ctx, caId, req)
+}
+
+func (m *CertificateService) ListCtx(ctx context.Context, caId int64, status tinycert.CertificateStatus) ([]*tinycert.CertificateListItem, error) {
+	m.record("List", caId, status)
+	if m.ListFunc == nil {
+		return nil, notMocked("CertificateService.List")
+	}
+	return m.ListFunc(ctx, caId, status)
+}
+
+func (m *CertificateService) DetailsCtx(ctx context.Context, certId int64) (*tinycert.CertificateInfo, error) {
+	m.record("Details", certId)
+	if m.DetailsFunc == nil {
+		return nil, notMocked("CertificateService.Details")
+	}
+	return m.DetailsFunc(ctx, certId)
+}
+
+func (m *CertificateService) GetCtx(ctx context.Context, certId int64, what tinycert.Artifact) (*string, error) {
+	m.record("Get", certId, what)
+	if m.GetFunc == nil {
+		return nil, notMocked("CertificateService.Get")
+	}
+	return m.GetFunc(ctx, certId, what)
+}
+
+func (m *CertificateService) ReissueWithOptionsCtx(ctx context.Context, certId int64, opts tinycert.ReissueOptions) (*int64, error) {
+	m.record("Reissue", certId, opts)
+	if m.ReissueFunc == nil {
+		return nil, notMocked("CertificateService.Reissue")
+	}
+	return m.ReissueFunc(ctx, certId, opts)
+}
+
+func (m *CertificateService) StatusCtx(ctx context.Context, certId int64, status tinycert.CertificateStatus) error {
+	m.record("Status", certId, status)
+	if m.StatusFunc == nil {
+		return notMocked("CertificateService.Status")
+	}
+	return m.StatusFunc(ctx, certId, status)
+}
@@ -0,0 +1,61 @@
+package mocks_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/mocks"
+)
+
+// renew stands in for downstream code written against the interfaces.
+func renew(ctx context.Context, certs tinycert.CertificateService, certId int64) (*int64, error) {
+	info, err := certs.DetailsCtx(ctx, certId)
+	if err != nil {
+		return nil, err
+	}
+	if info.Status != tinycert.Good {
+		return nil, errors.New("not good")
+	}
+	return certs.ReissueWithOptionsCtx(ctx, certId, tinycert.ReissueOptions{Reason: "renew"})
+}
+
+func TestCertificateService(t *testing.T) {
+	certs := &mocks.CertificateService{
+		DetailsFunc: func(ctx context.Context, certId int64) (*tinycert.CertificateInfo, error) {
+			return &tinycert.CertificateInfo{Id: certId, Status: tinycert.Good}, nil
+		},
+		ReissueFunc: func(ctx context.Context, certId int64, opts tinycert.ReissueOptions) (*int64, error) {
+			newId := certId + 1
+			return &newId, nil
+		},
+	}
+
+	newId, err := renew(context.Background(), certs, 7)
+	if err != nil || *newId != 8 {
+		t.Fatalf("renew = %v, %v", newId, err)
+	}
+
+	want := []mocks.Call{
+		{Method: "Details", Args: []any{int64(7)}},
+		{Method: "Reissue", Args: []any{int64(7), tinycert.ReissueOptions{Reason: "renew"}}},
+	}
+	if got := certs.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %+v, want %+v", got, want)
+	}
+}
+
+func TestNotMocked(t *testing.T) {
+	var ca mocks.CAService
+	if err := ca.DeleteCtx(context.Background(), 1); !errors.Is(err, mocks.ErrNotMocked) {
+		t.Errorf("err = %v, want ErrNotMocked", err)
+	}
+	if _, err := ca.ListCtx(context.Background()); !errors.Is(err, mocks.ErrNotMocked) {
+		t.Errorf("err = %v, want ErrNotMocked", err)
+	}
+	if len(ca.Calls()) != 2 {
+		t.Errorf("got %d calls, want 2", len(ca.Calls()))
+	}
+}
@@ -0,0 +1,87 @@
+package tinycert
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+var errNoPEMBlock = errors.New("tinycert: no PEM block found")
+
+func (c *Certificate) GetParsed(certId int64) (cert *x509.Certificate, key crypto.PrivateKey, err error) {
+	return c.GetParsedCtx(context.Background(), certId)
+}
+
+// GetParsedCtx fetches the certificate and its decrypted private key and
+// decodes them, so callers can inspect NotAfter, SANs and key usage directly.
+func (c *Certificate) GetParsedCtx(ctx context.Context, certId int64) (cert *x509.Certificate, key crypto.PrivateKey, err error) {
+	certPem, err := c.GetCtx(ctx, certId, Cert)
+	if err != nil {
+		return
+	}
+	keyPem, err := c.GetCtx(ctx, certId, KeyDecrypted)
+	if err != nil {
+		return
+	}
+
+	if cert, err = parseCertificatePEM([]byte(*certPem)); err != nil {
+		return
+	}
+	key, err = parsePrivateKeyPEM([]byte(*keyPem))
+	return
+}
+
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	certs, err := parseCertificatesPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+func parseCertificatesPEM(data []byte) (certs []*x509.Certificate, err error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errNoPEMBlock
+	}
+	return certs, nil
+}
+
+func parsePrivateKeyPEM(data []byte) (crypto.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errNoPEMBlock
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, fmt.Errorf("tinycert: private key is encrypted")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package tinycert_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_GetParsed(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert, key, err := tinycert.NewCertificate(sess).GetParsed(certId)
+	if err != nil {
+		t.Fatal("unable to fetch parsed certificate", err)
+	}
+
+	if cert.Subject.CommonName != "www.example.com" {
+		t.Errorf("common name = %q", cert.Subject.CommonName)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "www.example.com" {
+		t.Errorf("dns names = %v", cert.DNSNames)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		t.Fatalf("key type = %T", key)
+	}
+	if !ecKey.PublicKey.Equal(cert.PublicKey) {
+		t.Error("private key does not match certificate")
+	}
+}
+
+func TestCertificate_GetInvalidArtifact(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+
+	if _, err := tinycert.NewCertificate(sess).Get(certId, tinycert.Artifact(42)); !errors.Is(err, tinycert.ErrInvalidArtifact) {
+		t.Error("expected ErrInvalidArtifact, got", err)
+	}
+	if _, err := tinycert.NewCA(sess).GetArtifact(caId, tinycert.PKCS12); !errors.Is(err, tinycert.ErrInvalidArtifact) {
+		t.Error("expected ErrInvalidArtifact for ca pkcs12, got", err)
+	}
+	if got := fs.callCount("cert/get") + fs.callCount("ca/get"); got != 0 {
+		t.Errorf("invalid artifacts reached the server %d times", got)
+	}
+}
+
+func TestCertificate_Describe(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	desc, err := cert.Describe(certId)
+	if err != nil {
+		t.Fatal("unable to describe certificate", err)
+	}
+	parsed, _, err := cert.GetParsed(certId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sha256Sum := sha256.Sum256(parsed.Raw)
+	wantSHA256 := strings.ToUpper(hex.EncodeToString(sha256Sum[:]))
+	if got := strings.ReplaceAll(desc.SHA256Fingerprint, ":", ""); got != wantSHA256 || len(desc.SHA256Fingerprint) != 95 {
+		t.Errorf("sha256 fingerprint = %s", desc.SHA256Fingerprint)
+	}
+	if len(desc.SHA1Fingerprint) != 59 {
+		t.Errorf("sha1 fingerprint = %s", desc.SHA1Fingerprint)
+	}
+	if desc.SerialNumber != strings.ToUpper(parsed.SerialNumber.Text(16)) {
+		t.Errorf("serial number = %s", desc.SerialNumber)
+	}
+	if desc.KeyAlgorithm != "ECDSA" || desc.KeySize != 256 {
+		t.Errorf("key = %s %d, want ECDSA 256", desc.KeyAlgorithm, desc.KeySize)
+	}
+	if !desc.NotBefore.Equal(parsed.NotBefore) || !desc.NotAfter.Equal(parsed.NotAfter) {
+		t.Errorf("validity = %v - %v", desc.NotBefore, desc.NotAfter)
+	}
+	if desc.Id != certId || desc.CommonName != "www.example.com" || desc.Status != tinycert.Good {
+		t.Errorf("details = %+v", desc.CertificateInfo)
+	}
+}
+
+func TestCA_Describe(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+
+	desc, err := tinycert.NewCA(sess).Describe(caId)
+	if err != nil {
+		t.Fatal("unable to describe ca", err)
+	}
+
+	if desc.Id != caId || desc.Certificate == nil || !desc.Certificate.IsCA {
+		t.Fatalf("description = %+v", desc)
+	}
+	if !desc.NotBefore.Equal(desc.Certificate.NotBefore) || !desc.NotAfter.After(desc.NotBefore) {
+		t.Errorf("validity = %v - %v", desc.NotBefore, desc.NotAfter)
+	}
+	if strings.Join(desc.KeyUsage, ",") != "cert_sign,crl_sign" {
+		t.Errorf("key usage = %v", desc.KeyUsage)
+	}
+	if want := strings.ToUpper(hex.EncodeToString(desc.Certificate.SubjectKeyId)); desc.SubjectKeyId == "" || strings.ReplaceAll(desc.SubjectKeyId, ":", "") != want {
+		t.Errorf("subject key id = %s, want %s", desc.SubjectKeyId, want)
+	}
+	if len(desc.SHA256Fingerprint) != 95 {
+		t.Errorf("sha256 fingerprint = %s", desc.SHA256Fingerprint)
+	}
+}
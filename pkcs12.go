@@ -0,0 +1,86 @@
+package tinycert
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// PKCS12Bundle is a decoded PKCS#12 archive as returned by TinyCert.
+type PKCS12Bundle struct {
+	// DER is the raw PKCS#12 archive, e.g. for writing a .p12 file.
+	DER         []byte
+	PrivateKey  crypto.PrivateKey
+	Certificate *x509.Certificate
+	CACerts     []*x509.Certificate
+}
+
+func (c *Certificate) GetPKCS12(certId int64, passphrase string) (bundle *PKCS12Bundle, err error) {
+	return c.GetPKCS12Ctx(context.Background(), certId, passphrase)
+}
+
+// GetPKCS12Ctx fetches the PKCS#12 archive of the certificate and decodes it
+// with passphrase, the export passphrase the archive was protected with.
+func (c *Certificate) GetPKCS12Ctx(ctx context.Context, certId int64, passphrase string) (bundle *PKCS12Bundle, err error) {
+	der, err := c.GetPKCS12DERCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+
+	key, cert, caCerts, err := pkcs12.DecodeChain(der, passphrase)
+	if err != nil {
+		return
+	}
+
+	return &PKCS12Bundle{DER: der, PrivateKey: key, Certificate: cert, CACerts: caCerts}, nil
+}
+
+func (c *Certificate) GetPKCS12DER(certId int64) (der []byte, err error) {
+	return c.GetPKCS12DERCtx(context.Background(), certId)
+}
+
+// GetPKCS12DERCtx fetches the raw, still encrypted, PKCS#12 archive.
+func (c *Certificate) GetPKCS12DERCtx(ctx context.Context, certId int64) (der []byte, err error) {
+	encoded, err := c.GetCtx(ctx, certId, PKCS12)
+	if err != nil {
+		return
+	}
+	return base64.StdEncoding.DecodeString(*encoded)
+}
+
+func (c *Certificate) WritePKCS12To(certId int64, w io.Writer) (n int64, err error) {
+	return c.WritePKCS12ToCtx(context.Background(), certId, w)
+}
+
+// WritePKCS12ToCtx writes the raw PKCS#12 archive to w as it arrives,
+// decoding it from the response without holding either in memory, and
+// returns the number of bytes written. If ctx ends mid-download, w holds a
+// partial archive.
+func (c *Certificate) WritePKCS12ToCtx(ctx context.Context, certId int64, w io.Writer) (n int64, err error) {
+	resp := &streamResponse{consume: func(body io.Reader) (err error) {
+		n, err = copyBase64Field(w, body, "pkcs12")
+		return
+	}}
+	err = c.session.makeCall(ctx, "cert/get", Fields{}.SetInt("cert_id", certId).Set("what", PKCS12.String()).Values(), resp)
+	return
+}
+
+func (c *Certificate) GetPKCS12Reader(certId int64) io.ReadCloser {
+	return c.GetPKCS12ReaderCtx(context.Background(), certId)
+}
+
+// GetPKCS12ReaderCtx streams the raw PKCS#12 archive. The download runs
+// while the reader is read; its errors are returned by Read. Close the
+// reader to abandon the download.
+func (c *Certificate) GetPKCS12ReaderCtx(ctx context.Context, certId int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := c.WritePKCS12ToCtx(ctx, certId, pw)
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
@@ -0,0 +1,169 @@
+package tinycert_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestCertificate_GetPKCS12(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	bundle, err := cert.GetPKCS12(certId, fakePassphrase)
+	if err != nil {
+		t.Fatal("unable to decode pkcs12", err)
+	}
+
+	if bundle.Certificate.Subject.CommonName != "www.example.com" {
+		t.Errorf("common name = %q", bundle.Certificate.Subject.CommonName)
+	}
+	if len(bundle.CACerts) != 1 {
+		t.Errorf("got %d ca certs, want 1", len(bundle.CACerts))
+	}
+	key, ok := bundle.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !key.PublicKey.Equal(bundle.Certificate.PublicKey) {
+		t.Error("private key does not match certificate")
+	}
+	if len(bundle.DER) == 0 {
+		t.Error("expected raw DER")
+	}
+
+	if _, err := cert.GetPKCS12(certId, "wrong"); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+}
+
+func TestCertificate_WritePKCS12To(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	var buf bytes.Buffer
+	n, err := cert.WritePKCS12To(certId, &buf)
+	if err != nil {
+		t.Fatal("unable to stream pkcs12", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("n = %d, wrote %d bytes", n, buf.Len())
+	}
+	if _, leaf, _, err := pkcs12.DecodeChain(buf.Bytes(), fakePassphrase); err != nil || leaf.Subject.CommonName != "www.example.com" {
+		t.Errorf("streamed archive does not decode: %v", err)
+	}
+
+	r := cert.GetPKCS12Reader(certId)
+	defer r.Close()
+	streamed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal("unable to read pkcs12", err)
+	}
+	if _, _, _, err := pkcs12.DecodeChain(streamed, fakePassphrase); err != nil {
+		t.Errorf("read archive does not decode: %v", err)
+	}
+}
+
+func TestCertificate_WritePKCS12ToEscapes(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	der := bytes.Repeat([]byte{0xff, 0xfe, 0xfd}, 100)
+	encoded := base64.StdEncoding.EncodeToString(der)
+	fs.setFail(func(api string) (int, string) {
+		if api != "cert/get" {
+			return 0, ""
+		}
+		escaped := strings.ReplaceAll(encoded[:200], "/", `\/`) + `\n` + encoded[200:]
+		return http.StatusOK, `{"status": "ok", "meta": {"n": [1, "}"]}, "pkcs12": "` + escaped + `"}`
+	})
+
+	var buf bytes.Buffer
+	if _, err := tinycert.NewCertificate(sess).WritePKCS12To(1, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), der) {
+		t.Error("escaped archive decoded wrongly")
+	}
+}
+
+func TestCertificate_WritePKCS12ToNested(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	der := bytes.Repeat([]byte{0x01, 0x02, 0x03}, 50)
+	encoded := `{"meta": {"k": "v", "list": ["a", {"b": "c\"d"}]}, "pkcs12": "` + base64.StdEncoding.EncodeToString(der) + `"}`
+	fs.setFail(func(api string) (int, string) {
+		if api != "cert/get" {
+			return 0, ""
+		}
+		return http.StatusOK, encoded
+	})
+
+	cert := tinycert.NewCertificate(sess)
+	var buf bytes.Buffer
+	if _, err := cert.WritePKCS12To(1, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), der) {
+		t.Error("archive after nested members decoded wrongly")
+	}
+
+	fs.setFail(func(api string) (int, string) {
+		if api != "cert/get" {
+			return 0, ""
+		}
+		return http.StatusOK, `{"code": "404", "text": "gone", "extra": {"k": "v", "list": ["a", {"b": "c"}]}}`
+	})
+	if _, err := cert.WritePKCS12To(1, &buf); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCertificate_WritePKCS12ToErrors(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	var buf bytes.Buffer
+	small := fs.connectedSession().WithMaxResponseSize(64)
+	if _, err := tinycert.NewCertificate(small).WritePKCS12To(certId, &buf); !errors.Is(err, tinycert.ErrResponseTooLarge) {
+		t.Errorf("err = %v, want ErrResponseTooLarge", err)
+	}
+
+	fs.setFail(func(api string) (int, string) {
+		if api != "cert/get" {
+			return 0, ""
+		}
+		return http.StatusOK, `{"code": 404, "text": "no such certificate"}`
+	})
+	_, err := tinycert.NewCertificate(sess).WritePKCS12To(certId, &buf)
+	if !errors.Is(err, tinycert.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if _, err := io.ReadAll(tinycert.NewCertificate(sess).GetPKCS12Reader(certId)); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Errorf("reader err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSession_WithMaxResponseSize(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	if _, err := tinycert.NewCertificate(sess.WithMaxResponseSize(16)).Get(certId, tinycert.Cert); !errors.Is(err, tinycert.ErrResponseTooLarge) {
+		t.Errorf("err = %v, want ErrResponseTooLarge", err)
+	}
+	if _, err := tinycert.NewCertificate(sess.WithMaxResponseSize(0)).Get(certId, tinycert.Cert); err != nil {
+		t.Errorf("unlimited session failed: %v", err)
+	}
+}
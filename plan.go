@@ -0,0 +1,326 @@
+package tinycert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PlanAction is what Apply will do to a CA or certificate.
+type PlanAction int
+
+const (
+	NoChange PlanAction = iota
+	Create
+	Reissue
+	Revoke
+)
+
+func (a PlanAction) String() string {
+	switch a {
+	case NoChange:
+		return "no change"
+	case Create:
+		return "create"
+	case Reissue:
+		return "reissue"
+	case Revoke:
+		return "revoke"
+	}
+	return fmt.Sprintf("PlanAction(%d)", int(a))
+}
+
+func (a PlanAction) symbol() string {
+	switch a {
+	case Create:
+		return "+"
+	case Reissue:
+		return "~"
+	case Revoke:
+		return "-"
+	}
+	return " "
+}
+
+// Plan is what it takes to make an account match a Config. It is computed
+// without changing anything, so it can be reviewed before it is applied.
+type Plan struct {
+	CAs []*CAPlan
+}
+
+// CAPlan is the plan for one CA of the config; Action is Create or
+// NoChange.
+type CAPlan struct {
+	Name   string
+	Action PlanAction
+	// CAId is the id of the existing CA, zero if it is to be created.
+	CAId         int64
+	Certificates []*CertPlan
+
+	config CAConfig
+}
+
+// CertPlan is the plan for one certificate: one in the config, or one to be
+// revoked because it is not.
+type CertPlan struct {
+	CommonName string
+	Action     PlanAction
+	// CertId is the id of the existing certificate, zero if it is to be
+	// created.
+	CertId int64
+	// Reason says why a certificate is reissued or revoked.
+	Reason string
+
+	config *CertConfig
+}
+
+func (c *Config) Plan(session *Session) (plan *Plan, err error) {
+	return c.PlanCtx(context.Background(), session)
+}
+
+// PlanCtx compares the config with the account. CAs and certificates that do
+// not exist are to be created. Certificates that do and expire within
+// RenewBefore are to be reissued, and, for CAs with Prune set, certificates
+// that are not in the config are to be revoked. Everything else is left
+// untouched.
+func (c *Config) PlanCtx(ctx context.Context, session *Session) (plan *Plan, err error) {
+	if err = c.Validate(); err != nil {
+		return
+	}
+
+	caClient, certClient := NewCA(session), NewCertificate(session)
+	plan = &Plan{}
+	for i, ca := range c.CAs {
+		caPlan := &CAPlan{Name: ca.label(i), Action: Create, config: ca}
+		plan.CAs = append(plan.CAs, caPlan)
+
+		req, _ := ca.request()
+		var caId *int64
+		if caId, err = caClient.find(ctx, req); err != nil {
+			return nil, err
+		}
+		if caId == nil {
+			for j := range ca.Certificates {
+				cert := &ca.Certificates[j]
+				caPlan.Certificates = append(caPlan.Certificates, &CertPlan{CommonName: cert.CommonName, Action: Create, config: cert})
+			}
+			continue
+		}
+		caPlan.Action, caPlan.CAId = NoChange, *caId
+
+		var items []*CertificateListItem
+		if items, err = certClient.ListCtx(ctx, *caId, Good|Hold); err != nil {
+			return nil, err
+		}
+		matched := map[int64]bool{}
+		for j := range ca.Certificates {
+			cert := &ca.Certificates[j]
+			certPlan := &CertPlan{CommonName: cert.CommonName, Action: Create, config: cert}
+			caPlan.Certificates = append(caPlan.Certificates, certPlan)
+
+			req, _ := cert.request(ca)
+			req, _ = req.Normalize()
+			var item *CertificateListItem
+			if item, err = certClient.match(ctx, items, req); err != nil {
+				return nil, err
+			}
+			if item == nil {
+				continue
+			}
+			matched[item.Id] = true
+			certPlan.Action, certPlan.CertId = NoChange, item.Id
+			if c.RenewBefore > 0 && item.ExpiresAt.Sub(session.clock.Now()) < c.RenewBefore {
+				certPlan.Action, certPlan.Reason = Reissue, "expires "+item.ExpiresAt.Format(time.DateOnly)
+			}
+		}
+
+		if ca.Prune {
+			for _, item := range items {
+				if !matched[item.Id] {
+					caPlan.Certificates = append(caPlan.Certificates, &CertPlan{CommonName: item.Name, Action: Revoke, CertId: item.Id, Reason: "not in config"})
+				}
+			}
+		}
+	}
+	return
+}
+
+// Counts returns how many CAs and certificates the plan takes each action
+// on.
+func (p *Plan) Counts() map[PlanAction]int {
+	counts := map[PlanAction]int{}
+	for _, ca := range p.CAs {
+		counts[ca.Action]++
+		for _, cert := range ca.Certificates {
+			counts[cert.Action]++
+		}
+	}
+	return counts
+}
+
+// HasChanges reports whether applying the plan changes anything in the
+// account. Output files are written either way.
+func (p *Plan) HasChanges() bool {
+	counts := p.Counts()
+	return counts[Create]+counts[Reissue]+counts[Revoke] > 0
+}
+
+// WriteTo writes the plan for people to read, in the style of terraform
+// plan: one line per CA and certificate, marked + to create, ~ to reissue, -
+// to revoke or left blank, followed by a summary.
+func (p *Plan) WriteTo(w io.Writer) (n int64, err error) {
+	var buf bytes.Buffer
+	for _, ca := range p.CAs {
+		fmt.Fprintf(&buf, "%s ca %s", ca.Action.symbol(), ca.Name)
+		if ca.Action == Create {
+			fmt.Fprintf(&buf, " (O=%s, L=%s, ST=%s, C=%s)\n", ca.config.OrgName, ca.config.Locality, ca.config.StateCode, ca.config.CountryCode)
+		} else {
+			fmt.Fprintf(&buf, " (id %d)\n", ca.CAId)
+		}
+		for _, cert := range ca.Certificates {
+			fmt.Fprintf(&buf, "%s   certificate %s", cert.Action.symbol(), cert.CommonName)
+			if cert.CertId != 0 {
+				fmt.Fprintf(&buf, " (id %d)", cert.CertId)
+			}
+			if cert.Reason != "" {
+				fmt.Fprintf(&buf, ": %s", cert.Reason)
+			}
+			buf.WriteByte('\n')
+		}
+	}
+
+	if p.HasChanges() {
+		counts := p.Counts()
+		fmt.Fprintf(&buf, "\nPlan: %d to create, %d to reissue, %d to revoke, %d unchanged.\n",
+			counts[Create], counts[Reissue], counts[Revoke], counts[NoChange])
+	} else {
+		buf.WriteString("\nNo changes.\n")
+	}
+	return buf.WriteTo(w)
+}
+
+func (p *Plan) String() string {
+	var buf bytes.Buffer
+	p.WriteTo(&buf)
+	return buf.String()
+}
+
+// AppliedCA is the outcome of Apply for one CA.
+type AppliedCA struct {
+	Name         string
+	CAId         int64
+	Created      bool
+	Certificates []AppliedCertificate
+}
+
+// AppliedCertificate is the outcome of Apply for one certificate. CertId is
+// the certificate now in use or, for a revoked certificate, the one revoked.
+type AppliedCertificate struct {
+	CommonName string
+	CertId     int64
+	Created    bool
+	Reissued   bool
+	Revoked    bool
+}
+
+func (c *Config) Apply(session *Session) (applied []AppliedCA, err error) {
+	return c.ApplyCtx(context.Background(), session)
+}
+
+// ApplyCtx plans the config and applies the plan at once.
+func (c *Config) ApplyCtx(ctx context.Context, session *Session) (applied []AppliedCA, err error) {
+	plan, err := c.PlanCtx(ctx, session)
+	if err != nil {
+		return
+	}
+	return plan.ApplyCtx(ctx, session)
+}
+
+func (p *Plan) Apply(session *Session) (applied []AppliedCA, err error) {
+	return p.ApplyCtx(context.Background(), session)
+}
+
+// ApplyCtx carries out the plan, then writes every configured output file.
+// It stops at the first failure, returning what was applied so far; planning
+// again picks up where it stopped. A plan should be applied soon after it is
+// made: it does not notice changes made to the account since.
+func (p *Plan) ApplyCtx(ctx context.Context, session *Session) (applied []AppliedCA, err error) {
+	caClient, certClient := NewCA(session), NewCertificate(session)
+	for _, caPlan := range p.CAs {
+		result := AppliedCA{Name: caPlan.Name, CAId: caPlan.CAId}
+		err = caPlan.apply(ctx, caClient, certClient, &result)
+		applied = append(applied, result)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (caPlan *CAPlan) apply(ctx context.Context, caClient *CA, certClient *Certificate, result *AppliedCA) (err error) {
+	ca := caPlan.config
+	if caPlan.Action == Create {
+		req, _ := ca.request()
+		var caId *int64
+		if caId, err = caClient.CreateCtx(ctx, req); err != nil {
+			return
+		}
+		result.CAId, result.Created = *caId, true
+	}
+	if ca.Output != "" {
+		if err = makeParentDir(ca.Output); err != nil {
+			return
+		}
+		if err = caClient.DownloadCtx(ctx, result.CAId, ca.Output); err != nil {
+			return
+		}
+	}
+
+	for _, certPlan := range caPlan.Certificates {
+		certResult := AppliedCertificate{CommonName: certPlan.CommonName, CertId: certPlan.CertId}
+		var certId *int64
+		switch certPlan.Action {
+		case Create:
+			req, _ := certPlan.config.request(ca)
+			if certId, err = certClient.CreateCtx(ctx, result.CAId, req); err == nil {
+				certResult.CertId, certResult.Created = *certId, true
+			}
+		case Reissue:
+			if certId, err = certClient.ReissueCtx(ctx, certPlan.CertId); err == nil {
+				certResult.CertId, certResult.Reissued = *certId, true
+			}
+		case Revoke:
+			if err = certClient.RevokeCtx(ctx, certPlan.CertId); err == nil {
+				certResult.Revoked = true
+			}
+		}
+		if err != nil {
+			return
+		}
+		result.Certificates = append(result.Certificates, certResult)
+
+		if certPlan.config == nil {
+			continue
+		}
+		out := certPlan.config.Output
+		for _, path := range []string{out.Cert, out.Chain, out.Key} {
+			if path != "" {
+				if err = makeParentDir(path); err != nil {
+					return
+				}
+			}
+		}
+		if err = certClient.DownloadLayoutCtx(ctx, certResult.CertId, FileLayout{CertPath: out.Cert, ChainPath: out.Chain, KeyPath: out.Key}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func makeParentDir(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0755)
+}
@@ -0,0 +1,87 @@
+package tinycert_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestConfig_Plan(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	config, err := tinycert.LoadConfig(writeConfig(t, "certs.yaml", yamlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.CAs[0].Certificates = append(config.CAs[0].Certificates, tinycert.CertConfig{CommonName: "api.example.com"})
+
+	plan, err := config.Plan(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"+ ca internal (O=acme, L=sj, ST=CA, C=US)\n",
+		"+   certificate www.example.com\n",
+		"+   certificate api.example.com\n",
+		"Plan: 3 to create, 0 to reissue, 0 to revoke, 0 unchanged.\n",
+	} {
+		if !strings.Contains(plan.String(), want) {
+			t.Errorf("plan does not contain %q:\n%s", want, plan)
+		}
+	}
+	if fs.callCount("ca/new") != 0 || fs.callCount("cert/new") != 0 {
+		t.Error("planning changed the account")
+	}
+
+	applied, err := plan.Apply(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caId, wwwId := applied[0].CAId, applied[0].Certificates[0].CertId
+
+	if plan, err = config.Plan(sess); err != nil {
+		t.Fatal(err)
+	}
+	if plan.HasChanges() || !strings.HasSuffix(plan.String(), "\nNo changes.\n") {
+		t.Errorf("plan after apply:\n%s", plan)
+	}
+
+	// A certificate outside the config is revoked when pruning, and ones
+	// about to expire are reissued.
+	strayId, err := tinycert.NewCertificate(sess).Create(caId, tinycert.CertRequest{CommonName: "stray.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.CAs[0].Prune = true
+	config.RenewBefore = 400 * 24 * time.Hour
+	if plan, err = config.Plan(sess); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  ca internal (id ",
+		"~   certificate www.example.com (id ",
+		"-   certificate stray.example.com (id ",
+		": not in config\n",
+		"Plan: 0 to create, 2 to reissue, 1 to revoke, 1 unchanged.\n",
+	} {
+		if !strings.Contains(plan.String(), want) {
+			t.Errorf("plan does not contain %q:\n%s", want, plan)
+		}
+	}
+
+	applied, err = plan.Apply(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs := applied[0].Certificates
+	if len(certs) != 3 || !certs[0].Reissued || certs[0].CertId == wwwId || !certs[2].Revoked || certs[2].CertId != *strayId {
+		t.Errorf("applied = %+v", certs)
+	}
+	info, err := tinycert.NewCertificate(sess).Details(*strayId)
+	if err != nil || info.Status != tinycert.Revoked {
+		t.Errorf("stray certificate is %v, %v", info, err)
+	}
+}
@@ -0,0 +1,149 @@
+package tinycert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownAccount = errors.New("tinycert: unknown account")
+
+// SessionPool manages one session per TinyCert account, keyed by email, for
+// processes that work on behalf of many accounts. Sessions connect on first
+// use, reconnect when their token expires and are rate limited per account.
+// It is safe for concurrent use; accounts connect independently of each
+// other.
+type SessionPool struct {
+	// NewSession builds the session for an account. It defaults to NewSession
+	// configured with the account's credentials; the pool then adds auto
+	// reconnect and the per-account rate limit.
+	NewSession func(creds Credentials) *Session
+
+	rps   float64
+	burst int
+
+	mu       sync.Mutex
+	accounts map[string]*poolEntry
+}
+
+type poolEntry struct {
+	mu      sync.Mutex
+	creds   Credentials
+	session *Session
+}
+
+// NewSessionPool returns an empty pool whose sessions each make at most rps
+// requests per second with bursts of up to burst; rps of 0 means no limit.
+func NewSessionPool(rps float64, burst int) *SessionPool {
+	return &SessionPool{rps: rps, burst: burst, accounts: map[string]*poolEntry{}}
+}
+
+// Add registers an account. Adding an account again with different
+// credentials closes its current session; the next Get connects with the new
+// ones.
+func (p *SessionPool) Add(creds Credentials) error {
+	if creds.Email == "" {
+		return fmt.Errorf("%w: account has no email", ErrNoCredentials)
+	}
+
+	p.mu.Lock()
+	old, ok := p.accounts[creds.Email]
+	if ok && old.creds == creds {
+		p.mu.Unlock()
+		return nil
+	}
+	p.accounts[creds.Email] = &poolEntry{creds: creds}
+	p.mu.Unlock()
+
+	if ok {
+		return old.close()
+	}
+	return nil
+}
+
+func (p *SessionPool) Get(email string) (*Session, error) {
+	return p.GetCtx(context.Background(), email)
+}
+
+// GetCtx returns the connected session of an account added with Add.
+func (p *SessionPool) GetCtx(ctx context.Context, email string) (*Session, error) {
+	p.mu.Lock()
+	entry, ok := p.accounts[email]
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, email)
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.session != nil {
+		return entry.session, nil
+	}
+
+	var session *Session
+	if p.NewSession != nil {
+		session = p.NewSession(entry.creds)
+	} else {
+		session = NewSession().WithEmail(entry.creds.Email).WithPassphrase(entry.creds.Passphrase).WithApiKey(entry.creds.ApiKey)
+	}
+	session.WithAutoReconnect(true)
+	if p.rps > 0 {
+		session.WithRateLimit(p.rps, p.burst)
+	}
+	if err := session.ConnectCtx(ctx); err != nil {
+		return nil, err
+	}
+	entry.session = session
+	return session, nil
+}
+
+// Emails returns the registered accounts in order.
+func (p *SessionPool) Emails() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	emails := make([]string, 0, len(p.accounts))
+	for email := range p.accounts {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	return emails
+}
+
+// Remove closes the account's session and forgets the account.
+func (p *SessionPool) Remove(email string) error {
+	p.mu.Lock()
+	entry, ok := p.accounts[email]
+	delete(p.accounts, email)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return entry.close()
+}
+
+// Close closes every session and empties the pool.
+func (p *SessionPool) Close() error {
+	p.mu.Lock()
+	accounts := p.accounts
+	p.accounts = map[string]*poolEntry{}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, entry := range accounts {
+		errs = append(errs, entry.close())
+	}
+	return errors.Join(errs...)
+}
+
+func (e *poolEntry) close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		return nil
+	}
+	return e.session.Close()
+}
@@ -0,0 +1,85 @@
+package tinycert_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSessionPool(t *testing.T) {
+	servers := map[string]*fakeServer{
+		"a@example.com": newFakeServer(t),
+		"b@example.com": newFakeServer(t),
+	}
+	pool := tinycert.NewSessionPool(100, 10)
+	// Each fake server knows one account, so send each customer to its own.
+	pool.NewSession = func(creds tinycert.Credentials) *tinycert.Session {
+		return servers[creds.Email].session()
+	}
+	defer pool.Close()
+
+	for email := range servers {
+		if err := pool.Add(tinycert.Credentials{Email: email, Passphrase: "p", ApiKey: "k"}); err != nil {
+			t.Fatal("Add()", err)
+		}
+	}
+	if got := pool.Emails(); !reflect.DeepEqual(got, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("Emails() = %v", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		for email := range servers {
+			wg.Add(1)
+			go func(email string) {
+				defer wg.Done()
+				sess, err := pool.Get(email)
+				if err != nil {
+					t.Error("Get()", err)
+					return
+				}
+				if _, err := tinycert.NewCA(sess).List(); err != nil {
+					t.Error("List()", err)
+				}
+			}(email)
+		}
+	}
+	wg.Wait()
+
+	for email, fs := range servers {
+		if got := fs.callCount("connect"); got != 1 {
+			t.Errorf("%s: connect called %d times, want 1", email, got)
+		}
+		if got := fs.callCount("ca/list"); got != 8 {
+			t.Errorf("%s: ca/list called %d times, want 8", email, got)
+		}
+	}
+
+	// New credentials replace the session.
+	if err := pool.Add(tinycert.Credentials{Email: "a@example.com", Passphrase: "rotated", ApiKey: "k"}); err != nil {
+		t.Fatal("Add() with rotated credentials", err)
+	}
+	if got := servers["a@example.com"].callCount("disconnect"); got != 1 {
+		t.Errorf("old session disconnected %d times, want 1", got)
+	}
+	if _, err := pool.Get("a@example.com"); err != nil || servers["a@example.com"].callCount("connect") != 2 {
+		t.Errorf("Get() after rotation = %v", err)
+	}
+
+	if _, err := pool.Get("c@example.com"); !errors.Is(err, tinycert.ErrUnknownAccount) {
+		t.Errorf("Get(unknown) = %v, want ErrUnknownAccount", err)
+	}
+
+	if err := pool.Remove("b@example.com"); err != nil || servers["b@example.com"].callCount("disconnect") != 1 {
+		t.Errorf("Remove() = %v", err)
+	}
+	if err := pool.Close(); err != nil || servers["a@example.com"].callCount("disconnect") != 2 {
+		t.Errorf("Close() = %v", err)
+	}
+	if len(pool.Emails()) != 0 {
+		t.Error("Close() left accounts in the pool")
+	}
+}
@@ -0,0 +1,99 @@
+package tinycert
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Progress follows a bulk operation such as CreateBatch, a Rotation or
+// GenerateReport. Calls are serialized, so implementations need no locking.
+type Progress interface {
+	// OnStart is called once, with the number of items to process.
+	OnStart(total int)
+	// OnItem is called as each item finishes, in completion order, with a
+	// short name for the item and its error, if any.
+	OnItem(name string, err error)
+	// OnDone is called once every item has finished.
+	OnDone()
+}
+
+// progressTracker reports to a Progress that may be nil.
+type progressTracker struct {
+	mu sync.Mutex
+	p  Progress
+}
+
+func startProgress(p Progress, total int) *progressTracker {
+	if p != nil {
+		p.OnStart(total)
+	}
+	return &progressTracker{p: p}
+}
+
+func (t *progressTracker) item(name string, err error) {
+	if t.p == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.OnItem(name, err)
+}
+
+func (t *progressTracker) done() {
+	if t.p != nil {
+		t.p.OnDone()
+	}
+}
+
+// ProgressBar is a Progress drawing a single-line bar on a terminal:
+//
+//	issuing [==========>               ] 12/30, 1 failed
+type ProgressBar struct {
+	w      io.Writer
+	label  string
+	width  int
+	total  int
+	done   int
+	failed int
+}
+
+// NewProgressBar returns a ProgressBar writing to w, usually os.Stderr.
+func NewProgressBar(w io.Writer, label string) *ProgressBar {
+	return &ProgressBar{w: w, label: label, width: 30}
+}
+
+func (b *ProgressBar) OnStart(total int) {
+	b.total, b.done, b.failed = total, 0, 0
+	b.draw()
+}
+
+func (b *ProgressBar) OnItem(name string, err error) {
+	b.done++
+	if err != nil {
+		b.failed++
+	}
+	b.draw()
+}
+
+func (b *ProgressBar) OnDone() {
+	fmt.Fprintln(b.w)
+}
+
+func (b *ProgressBar) draw() {
+	filled := b.width
+	if b.total > 0 {
+		filled = b.width * b.done / b.total
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < b.width {
+		bar += ">" + strings.Repeat(" ", b.width-filled-1)
+	}
+
+	line := fmt.Sprintf("\r%s [%s] %d/%d", b.label, bar, b.done, b.total)
+	if b.failed > 0 {
+		line += fmt.Sprintf(", %d failed", b.failed)
+	}
+	io.WriteString(b.w, line)
+}
@@ -0,0 +1,80 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+type recordingProgress struct {
+	total  int
+	items  []string
+	failed int
+	done   bool
+}
+
+func (p *recordingProgress) OnStart(total int) { p.total = total }
+func (p *recordingProgress) OnDone()           { p.done = true }
+
+func (p *recordingProgress) OnItem(name string, err error) {
+	p.items = append(p.items, name)
+	if err != nil {
+		p.failed++
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var out strings.Builder
+	bar := tinycert.NewProgressBar(&out, "issuing")
+	bar.OnStart(4)
+	bar.OnItem("a", nil)
+	bar.OnItem("b", errors.New("failed"))
+	bar.OnDone()
+
+	frames := strings.Split(out.String(), "\r")
+	want := []string{
+		"",
+		"issuing [>                             ] 0/4",
+		"issuing [=======>                      ] 1/4",
+		"issuing [===============>              ] 2/4, 1 failed\n",
+	}
+	if strings.Join(frames, "|") != strings.Join(want, "|") {
+		t.Errorf("frames = %q, want %q", frames, want)
+	}
+}
+
+func TestCertificate_CreateBatchProgress(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+
+	progress := &recordingProgress{}
+	cert := tinycert.NewCertificate(sess).WithProgress(progress)
+	cert.CreateBatch(caId, []tinycert.CertRequest{{CommonName: "a.example.com"}, {CommonName: "b.example.com", CountryCode: "USA"}, {CommonName: "c.example.com"}})
+
+	sort.Strings(progress.items)
+	if progress.total != 3 || !progress.done || progress.failed != 1 ||
+		strings.Join(progress.items, ",") != "a.example.com,b.example.com,c.example.com" {
+		t.Errorf("progress = %+v", progress)
+	}
+}
+
+func TestGenerateReportWithProgress(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	newCAAndCert(t, sess)
+	newCAAndCert(t, sess)
+
+	progress := &recordingProgress{}
+	report, err := tinycert.GenerateReportWithProgress(context.Background(), sess, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 2 || progress.total != 2 || len(progress.items) != 2 || !progress.done {
+		t.Errorf("progress = %+v", progress)
+	}
+}
@@ -0,0 +1,174 @@
+package tinycert
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter serves certificate expiry and API call metrics in the Prometheus
+// text exposition format. It needs no Prometheus client library; mount it on
+// any mux, e.g. http.Handle("/metrics", exporter).
+type Exporter struct {
+	sess *Session
+
+	mu          sync.Mutex
+	report      *Report
+	lastSuccess time.Time
+	refreshErrs int
+	requests    map[string]int
+	errors      map[string]int
+}
+
+// NewExporter returns an Exporter for sess and has sess report its API calls
+// to it. A call is counted once, however often it was retried, and is an
+// error if it failed in the end, including with an error envelope sent with
+// a 200 status.
+func NewExporter(sess *Session) *Exporter {
+	e := &Exporter{
+		sess:     sess,
+		requests: map[string]int{},
+		errors:   map[string]int{},
+	}
+	sess.observers = append(sess.observers, e.count)
+	return e
+}
+
+func (e *Exporter) count(api string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests[api]++
+	if err != nil {
+		e.errors[api]++
+	}
+}
+
+// Refresh regenerates the certificate report behind the expiry gauges.
+func (e *Exporter) Refresh(ctx context.Context) error {
+	report, err := GenerateReportCtx(ctx, e.sess)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.refreshErrs++
+		return err
+	}
+	e.report, e.lastSuccess = report, e.sess.clock.Now()
+	return nil
+}
+
+// Run refreshes every interval until ctx is done. Refresh failures are logged
+// and keep the previous report.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Refresh(ctx); err != nil && ctx.Err() == nil {
+			e.sess.logger.Log(LevelWarn, "unable to refresh metrics: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(e.metrics()))
+}
+
+// WriteTextfile writes the metrics to path for node_exporter's textfile
+// collector, for hosts that cannot expose a scrape endpoint. The path must be
+// in the collector's directory and end in .prom. The file is replaced
+// atomically, so the collector never reads it half written.
+func (e *Exporter) WriteTextfile(path string) error {
+	return writeFileAtomic(path, []byte(e.metrics()), 0644)
+}
+
+// RunTextfile refreshes and writes the metrics to path every interval until
+// ctx is done. Failures are logged; a failed refresh still writes the
+// previous report, with tinycert_refresh_errors_total increased.
+func (e *Exporter) RunTextfile(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Refresh(ctx); err != nil && ctx.Err() == nil {
+			e.sess.logger.Log(LevelWarn, "unable to refresh metrics: %v", err)
+		}
+		if ctx.Err() == nil {
+			if err := e.WriteTextfile(path); err != nil {
+				e.sess.logger.Log(LevelError, "unable to write metrics to %s: %v", path, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// metrics returns the metrics in the Prometheus text exposition format.
+func (e *Exporter) metrics() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var b strings.Builder
+
+	writeMetricHeader(&b, "tinycert_certificate_expiry_seconds", "gauge", "Seconds until the certificate expires; negative once expired.")
+	if e.report != nil {
+		now := e.sess.clock.Now()
+		for _, entry := range e.report.Entries {
+			fmt.Fprintf(&b, "tinycert_certificate_expiry_seconds{ca=%s,cert_id=\"%d\",cn=%s,status=%s} %s\n",
+				quoteLabel(entry.CAName), entry.CertId, quoteLabel(entry.Name), quoteLabel(entry.Status.String()),
+				formatFloat(entry.ExpiresAt.Sub(now).Seconds()))
+		}
+	}
+
+	writeMetricHeader(&b, "tinycert_api_requests_total", "counter", "TinyCert API calls by endpoint.")
+	writeCounts(&b, "tinycert_api_requests_total", e.requests)
+	writeMetricHeader(&b, "tinycert_api_errors_total", "counter", "Failed TinyCert API calls by endpoint.")
+	writeCounts(&b, "tinycert_api_errors_total", e.errors)
+
+	writeMetricHeader(&b, "tinycert_refresh_errors_total", "counter", "Failed certificate report refreshes.")
+	fmt.Fprintf(&b, "tinycert_refresh_errors_total %d\n", e.refreshErrs)
+	if !e.lastSuccess.IsZero() {
+		writeMetricHeader(&b, "tinycert_last_refresh_timestamp_seconds", "gauge", "Time of the last successful refresh.")
+		fmt.Fprintf(&b, "tinycert_last_refresh_timestamp_seconds %d\n", e.lastSuccess.Unix())
+	}
+	return b.String()
+}
+
+func writeMetricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeCounts(b *strings.Builder, name string, counts map[string]int) {
+	endpoints := make([]string, 0, len(counts))
+	for api := range counts {
+		endpoints = append(endpoints, api)
+	}
+	sort.Strings(endpoints)
+	for _, api := range endpoints {
+		fmt.Fprintf(b, "%s{endpoint=%s} %d\n", name, quoteLabel(api), counts[api])
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
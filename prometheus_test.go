@@ -0,0 +1,136 @@
+package tinycert_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestExporter(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	newCAAndCert(t, sess)
+
+	exporter := tinycert.NewExporter(sess)
+	if err := exporter.Refresh(context.Background()); err != nil {
+		t.Fatal("unable to refresh", err)
+	}
+
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/details" {
+			return http.StatusNotFound, `{"code":"404","text":"CA not found"}`
+		}
+		return 0, ""
+	})
+	tinycert.NewCA(sess).Details(42)
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, pattern := range []string{
+		`(?m)^# TYPE tinycert_certificate_expiry_seconds gauge$`,
+		`(?m)^tinycert_certificate_expiry_seconds\{ca="acme Root CA",cert_id="\d+",cn="www.example.com",status="good"\} 3\d{7}$`,
+		`(?m)^tinycert_api_requests_total\{endpoint="ca/list"\} 1$`,
+		`(?m)^tinycert_api_requests_total\{endpoint="cert/list"\} 1$`,
+		`(?m)^tinycert_api_errors_total\{endpoint="ca/details"\} 1$`,
+		`(?m)^tinycert_refresh_errors_total 0$`,
+		`(?m)^tinycert_last_refresh_timestamp_seconds \d+$`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(body) {
+			t.Errorf("metrics do not match %s:\n%s", pattern, body)
+		}
+	}
+	if strings.Contains(body, `endpoint="cert/new"`) {
+		t.Error("calls made before the exporter was installed were counted")
+	}
+}
+
+func TestExporter_CountsCalls(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithRetryPolicy(tinycert.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	})
+	exporter := tinycert.NewExporter(sess)
+
+	var lists atomic.Int32
+	fs.setFail(func(api string) (int, string) {
+		switch {
+		case api == "ca/list" && lists.Add(1) == 1:
+			return http.StatusServiceUnavailable, `{"code":"503","text":"try again"}`
+		case api == "ca/details":
+			return http.StatusOK, `{"code":"404","text":"CA not found"}`
+		}
+		return 0, ""
+	})
+	ca := tinycert.NewCA(sess)
+	if _, err := ca.List(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ca.Details(42); err == nil {
+		t.Fatal("expected the error envelope to fail ca/details")
+	}
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, pattern := range []string{
+		`(?m)^tinycert_api_requests_total\{endpoint="ca/list"\} 1$`,
+		`(?m)^tinycert_api_requests_total\{endpoint="ca/details"\} 1$`,
+		`(?m)^tinycert_api_errors_total\{endpoint="ca/details"\} 1$`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(body) {
+			t.Errorf("metrics do not match %s:\n%s", pattern, body)
+		}
+	}
+	if strings.Contains(body, `tinycert_api_errors_total{endpoint="ca/list"}`) {
+		t.Errorf("a call that succeeded on retry was counted as an error:\n%s", body)
+	}
+}
+
+func TestExporter_Textfile(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	newCAAndCert(t, sess)
+	exporter := tinycert.NewExporter(sess)
+	path := filepath.Join(t.TempDir(), "tinycert.prom")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		exporter.RunTextfile(ctx, path, time.Hour)
+		close(done)
+	}()
+
+	var data []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var err error
+		if data, err = os.ReadFile(path); err == nil {
+			break
+		}
+	}
+	cancel()
+	<-done
+
+	if !regexp.MustCompile(`(?m)^tinycert_certificate_expiry_seconds\{ca="acme Root CA",cert_id="\d+",cn="www.example.com",status="good"\} 3\d{7}$`).Match(data) {
+		t.Errorf("textfile has no expiry metric:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("textfile mode = %v, %v", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("textfile directory has %d entries, want only the .prom file", len(entries))
+	}
+}
@@ -0,0 +1,108 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestRateLimiter_Burst(t *testing.T) {
+	limiter := tinycert.NewRateLimiter(20, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("4 requests at 20rps with burst 2 took %s, expected at least 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tinycert.NewRateLimiter(0.001, 1).Wait(ctx); err != nil {
+		t.Error("first token should be available immediately, got", err)
+	}
+}
+
+func TestSession_RetryAfter(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithRateLimit(1000, 10)
+
+	limited := true
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" && limited {
+			limited = false
+			return http.StatusTooManyRequests, `{"code":"429","text":"slow down"}`
+		}
+		return 0, ""
+	})
+
+	fs.setHeader("Retry-After", "1")
+
+	ca := tinycert.NewCA(sess)
+	_, err := ca.List()
+	var apiErr *tinycert.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, tinycert.ErrRateLimited) || apiErr.RetryAfter != time.Second {
+		t.Fatal("expected rate limited error with retry after, got", err)
+	}
+
+	start := time.Now()
+	if _, err := ca.List(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("call after Retry-After went out after %s, expected to wait ~1s", elapsed)
+	}
+}
+
+func TestSession_RetryOnTooManyRequests(t *testing.T) {
+	fs := newFakeServer(t)
+
+	limited := 0
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" && limited > 0 {
+			limited--
+			return http.StatusTooManyRequests, `{"code":"429","text":"slow down"}`
+		}
+		return 0, ""
+	})
+	fs.setHeader("Retry-After", "1")
+
+	policy := tinycert.DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond
+	sess := fs.connectedSession().WithRetryPolicy(policy)
+
+	limited = 1
+	start := time.Now()
+	if _, err := tinycert.NewCA(sess).List(); err != nil {
+		t.Fatal("expected the 429 to be retried, got", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("retried after %s, expected to honour Retry-After", elapsed)
+	}
+
+	policy.MaxRetryAfter = 500 * time.Millisecond
+	sess.WithRetryPolicy(policy)
+
+	limited = 1
+	start = time.Now()
+	_, err := tinycert.NewCA(sess).List()
+	if !errors.Is(err, tinycert.ErrRateLimited) {
+		t.Fatal("expected ErrRateLimited, got", err)
+	}
+	if delay, ok := tinycert.RetryAfter(err); !ok || delay != time.Second {
+		t.Errorf("RetryAfter() = %s, %v", delay, ok)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("returned after %s, expected no wait beyond the cap", elapsed)
+	}
+	if got := fs.callCount("ca/list"); got != 3 {
+		t.Errorf("ca/list called %d times, want 3", got)
+	}
+}
@@ -0,0 +1,60 @@
+package tinycert
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	pemBlockPattern = regexp.MustCompile(`-----BEGIN ([A-Z0-9 ]+)-----[\s\S]*?-----END [A-Z0-9 ]+-----`)
+	formPattern     = regexp.MustCompile(`(^|[&?\s])(passphrase|token|digest)=[^&\s]*`)
+	jsonPattern     = regexp.MustCompile(`"(passphrase|token|digest|pkcs12)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// Redact scrubs PEM blocks, form-encoded passphrase, token and digest values,
+// and the matching JSON fields from s. Every message a Session logs passes
+// through it.
+func Redact(s string) string {
+	s = pemBlockPattern.ReplaceAllString(s, "-----BEGIN $1----- REDACTED -----END $1-----")
+	s = formPattern.ReplaceAllString(s, "${1}${2}=REDACTED")
+	return jsonPattern.ReplaceAllString(s, `"$1"${2}"REDACTED"`)
+}
+
+type redactingLogger struct {
+	next Logger
+}
+
+func (rl redactingLogger) Log(level Level, format string, args ...interface{}) {
+	rl.next.Log(level, "%s", Redact(fmt.Sprintf(format, args...)))
+}
+
+// SecureString holds a secret, such as a private key, that must not end up
+// in logs by accident: it prints and marshals as REDACTED. Call Reveal to
+// get the value.
+type SecureString struct {
+	value string
+}
+
+func NewSecureString(value string) SecureString {
+	return SecureString{value: value}
+}
+
+func (ss SecureString) Reveal() string {
+	return ss.value
+}
+
+func (ss SecureString) IsZero() bool {
+	return ss.value == ""
+}
+
+func (ss SecureString) String() string {
+	return "REDACTED"
+}
+
+func (ss SecureString) GoString() string {
+	return "tinycert.SecureString(REDACTED)"
+}
+
+func (ss SecureString) MarshalText() ([]byte, error) {
+	return []byte("REDACTED"), nil
+}
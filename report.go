@@ -0,0 +1,185 @@
+package tinycert
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// ExpiryBucket groups certificates by how soon they expire.
+type ExpiryBucket int
+
+const (
+	BucketExpired ExpiryBucket = iota
+	BucketWithin7Days
+	BucketWithin30Days
+	BucketOK
+)
+
+func (b ExpiryBucket) String() string {
+	switch b {
+	case BucketExpired:
+		return "expired"
+	case BucketWithin7Days:
+		return "<7d"
+	case BucketWithin30Days:
+		return "<30d"
+	case BucketOK:
+		return "ok"
+	}
+	return fmt.Sprintf("ExpiryBucket(%d)", int(b))
+}
+
+func (b ExpiryBucket) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func bucketFor(expiresAt, now time.Time) ExpiryBucket {
+	switch left := expiresAt.Sub(now); {
+	case left <= 0:
+		return BucketExpired
+	case left < 7*24*time.Hour:
+		return BucketWithin7Days
+	case left < 30*24*time.Hour:
+		return BucketWithin30Days
+	}
+	return BucketOK
+}
+
+type ReportEntry struct {
+	CAId      int64             `json:"ca_id"`
+	CAName    string            `json:"ca_name"`
+	CertId    int64             `json:"cert_id"`
+	Name      string            `json:"name"`
+	Status    CertificateStatus `json:"status"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	Bucket    ExpiryBucket      `json:"bucket"`
+}
+
+// Report lists every certificate of every CA in the account, soonest expiry
+// first.
+type Report struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Entries     []ReportEntry `json:"entries"`
+}
+
+type ReportFormat int
+
+const (
+	FormatTable ReportFormat = iota
+	FormatJSON
+	FormatCSV
+)
+
+func GenerateReport(sess *Session) (report *Report, err error) {
+	return GenerateReportCtx(context.Background(), sess)
+}
+
+// GenerateReportCtx lists the certificates of all CAs, fetching up to
+// DefaultParallelism CAs at once.
+func GenerateReportCtx(ctx context.Context, sess *Session) (report *Report, err error) {
+	return GenerateReportWithProgress(ctx, sess, nil)
+}
+
+// GenerateReportWithProgress is GenerateReportCtx reporting each CA to p, by
+// name, as its certificates are listed.
+func GenerateReportWithProgress(ctx context.Context, sess *Session, p Progress) (report *Report, err error) {
+	cas, err := NewCA(sess).ListCtx(ctx)
+	if err != nil {
+		return
+	}
+
+	now := sess.clock.Now()
+	lists := make([][]*CertificateListItem, len(cas))
+	errs := make([]error, len(cas))
+	cert := NewCertificate(sess)
+	progress := startProgress(p, len(cas))
+	cert.forEach(ctx, len(cas), func(ctx context.Context, i int) {
+		lists[i], errs[i] = cert.ListCtx(ctx, cas[i].Id, AnyStatus)
+		progress.item(cas[i].Name, errs[i])
+	})
+	progress.done()
+
+	report = &Report{GeneratedAt: now}
+	for i, ca := range cas {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("tinycert: unable to list certificates of ca %d: %w", ca.Id, errs[i])
+		}
+		for _, item := range lists[i] {
+			bucket := bucketFor(item.ExpiresAt, now)
+			if item.Status == Expired {
+				bucket = BucketExpired
+			}
+			report.Entries = append(report.Entries, ReportEntry{
+				CAId:      ca.Id,
+				CAName:    ca.Name,
+				CertId:    item.Id,
+				Name:      item.Name,
+				Status:    item.Status,
+				ExpiresAt: item.ExpiresAt,
+				Bucket:    bucket,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].ExpiresAt.Before(report.Entries[j].ExpiresAt)
+	})
+	return
+}
+
+// Counts returns the number of certificates in each bucket.
+func (r *Report) Counts() map[ExpiryBucket]int {
+	counts := map[ExpiryBucket]int{}
+	for _, e := range r.Entries {
+		counts[e.Bucket]++
+	}
+	return counts
+}
+
+func (r *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"ca_id", "ca_name", "cert_id", "name", "status", "expires_at", "bucket"})
+		for _, e := range r.Entries {
+			cw.Write([]string{
+				strconv.FormatInt(e.CAId, 10), e.CAName, strconv.FormatInt(e.CertId, 10),
+				e.Name, e.Status.String(), e.ExpiresAt.Format(time.RFC3339), e.Bucket.String(),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "BUCKET\tEXPIRES\tCERT\tNAME\tSTATUS\tCA")
+		for _, e := range r.Entries {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n", e.Bucket, e.ExpiresAt.Format("2006-01-02"), e.CertId, e.Name, e.Status, e.CAName)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("tinycert: unknown report format %d", int(format))
+}
+
+// ParseReportFormat parses "table", "json" or "csv".
+func ParseReportFormat(name string) (ReportFormat, error) {
+	switch name {
+	case "table":
+		return FormatTable, nil
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	}
+	return 0, fmt.Errorf("tinycert: unknown report format %q", name)
+}
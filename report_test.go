@@ -0,0 +1,92 @@
+package tinycert_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestGenerateReport(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	if _, err := tinycert.NewCA(sess).Create(tinycert.CARequest{OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"}); err != nil {
+		t.Fatal("unable to create ca", err)
+	}
+
+	now := time.Now()
+	list := fmt.Sprintf(`[
+		{"id": 1, "name": "ok.example.com", "status": "good", "expires": %d},
+		{"id": 2, "name": "soon.example.com", "status": "good", "expires": %d},
+		{"id": 3, "name": "month.example.com", "status": "good", "expires": %d},
+		{"id": 4, "name": "old.example.com", "status": "expired", "expires": %d}
+	]`, now.Add(90*24*time.Hour).Unix(), now.Add(3*24*time.Hour).Unix(), now.Add(20*24*time.Hour).Unix(), now.Add(-24*time.Hour).Unix())
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/list" {
+			return http.StatusOK, list
+		}
+		return 0, ""
+	})
+
+	report, err := tinycert.GenerateReport(sess)
+	if err != nil {
+		t.Fatal("unable to generate report", err)
+	}
+
+	var order []int64
+	for _, e := range report.Entries {
+		order = append(order, e.CertId)
+		if e.CAName != "acme Root CA" {
+			t.Errorf("cert %d ca name = %q", e.CertId, e.CAName)
+		}
+	}
+	if fmt.Sprint(order) != "[4 2 3 1]" {
+		t.Errorf("entries not sorted by expiry: %v", order)
+	}
+
+	want := map[tinycert.ExpiryBucket]int{
+		tinycert.BucketExpired:      1,
+		tinycert.BucketWithin7Days:  1,
+		tinycert.BucketWithin30Days: 1,
+		tinycert.BucketOK:           1,
+	}
+	if got := report.Counts(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, tinycert.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Entries []struct {
+			Bucket string `json:"bucket"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Entries[1].Bucket != "<7d" {
+		t.Errorf("json report = %s, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, tinycert.FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 5 || records[0][0] != "ca_id" || records[1][6] != "expired" {
+		t.Errorf("csv report = %v, %v", records, err)
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, tinycert.FormatTable); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 5 || !strings.HasPrefix(lines[0], "BUCKET") {
+		t.Errorf("table report:\n%s", buf.String())
+	}
+}
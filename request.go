@@ -0,0 +1,133 @@
+package tinycert
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+var ErrInvalidRequest = errors.New("tinycert: invalid request")
+
+// CertRequest describes a certificate to issue: its subject and subject
+// alternative names. Only CommonName is required. A CommonName that is a host
+// name may be a wildcard such as *.example.com or an internationalized name,
+// which is sent in punycode.
+type CertRequest struct {
+	CommonName  string
+	OrgUnit     string
+	OrgName     string
+	Locality    string
+	StateCode   string
+	CountryCode string
+	Alt         []SAN
+}
+
+// Validate reports every problem with the request in one ErrInvalidRequest
+// error, before any call is made to the API.
+func (r CertRequest) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(r.CommonName) == "" {
+		problems = append(problems, "common name is required")
+	} else if _, err := normalizeCommonName(r.CommonName); err != nil {
+		problems = append(problems, "common name: "+err.Error())
+	}
+	if r.CountryCode != "" && !isCountryCode(r.CountryCode) {
+		problems = append(problems, fmt.Sprintf("country code %q must be two letters", r.CountryCode))
+	}
+	for i, san := range r.Alt {
+		if _, err := san.Normalize(); err != nil {
+			problems = append(problems, fmt.Sprintf("SAN %d: %v", i, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Normalize validates the request and returns it with the common name and
+// SANs in the canonical form Certificate.Create sends; see SAN.Normalize.
+func (r CertRequest) Normalize() (CertRequest, error) {
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+	r.CommonName, _ = normalizeCommonName(r.CommonName)
+	alt, err := NormalizeSANs(r.Alt)
+	if err != nil {
+		return r, err
+	}
+	r.Alt = alt
+	return r, nil
+}
+
+// normalizeCommonName treats a common name that looks like a host name, one
+// without spaces that has a dot or a wildcard, as a DNS name. Anything else,
+// such as "Build Server", is sent as is.
+func normalizeCommonName(cn string) (string, error) {
+	cn = strings.TrimSpace(cn)
+	if strings.Contains(cn, " ") || !strings.ContainsAny(cn, ".*") {
+		return cn, nil
+	}
+	return normalizeDNSName(cn, true)
+}
+
+// CARequest describes a certificate authority to create. OrgName, Locality,
+// StateCode and CountryCode are required.
+type CARequest struct {
+	OrgName       string
+	OrgUnit       string
+	CommonName    string
+	Email         string
+	Locality      string
+	StateCode     string
+	CountryCode   string
+	HashAlgorithm HashAlgorithm
+}
+
+// Validate reports every problem with the request in one ErrInvalidRequest
+// error, before any call is made to the API.
+func (r CARequest) Validate() error {
+	var problems []string
+
+	for _, field := range []struct{ name, value string }{
+		{"organization", r.OrgName},
+		{"locality", r.Locality},
+		{"state code", r.StateCode},
+		{"country code", r.CountryCode},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			problems = append(problems, field.name+" is required")
+		}
+	}
+	if r.CountryCode != "" && !isCountryCode(r.CountryCode) {
+		problems = append(problems, fmt.Sprintf("country code %q must be two letters", r.CountryCode))
+	}
+	if r.Email != "" {
+		if _, err := mail.ParseAddress(r.Email); err != nil {
+			problems = append(problems, fmt.Sprintf("email %q: %v", r.Email, err))
+		}
+	}
+	if !r.HashAlgorithm.valid() {
+		problems = append(problems, fmt.Sprintf("%v: %s", ErrInvalidHashAlgorithm, r.HashAlgorithm))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package tinycert_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  tinycert.CertRequest
+		ok   bool
+	}{
+		{"minimal", tinycert.CertRequest{CommonName: "www.example.com"}, true},
+		{"full", tinycert.CertRequest{CommonName: "www.example.com", OrgName: "acme", CountryCode: "US", Alt: []tinycert.SAN{{DNS: "example.com"}}}, true},
+		{"missing cn", tinycert.CertRequest{OrgName: "acme"}, false},
+		{"bad country", tinycert.CertRequest{CommonName: "x", CountryCode: "USA"}, false},
+		{"empty san", tinycert.CertRequest{CommonName: "x", Alt: []tinycert.SAN{{}}}, false},
+		{"wildcard cn", tinycert.CertRequest{CommonName: "*.example.com", Alt: []tinycert.SAN{{DNS: "*.example.com"}}}, true},
+		{"idn cn", tinycert.CertRequest{CommonName: "bücher.example"}, true},
+		{"plain text cn", tinycert.CertRequest{CommonName: "Build Server"}, true},
+		{"partial wildcard cn", tinycert.CertRequest{CommonName: "www*.example.com"}, false},
+		{"inner wildcard cn", tinycert.CertRequest{CommonName: "www.*.example.com"}, false},
+		{"double wildcard cn", tinycert.CertRequest{CommonName: "*.*.example.com"}, false},
+		{"tld wildcard cn", tinycert.CertRequest{CommonName: "*.com"}, false},
+		{"bare wildcard san", tinycert.CertRequest{CommonName: "x", Alt: []tinycert.SAN{{DNS: "*"}}}, false},
+		{"wildcard email", tinycert.CertRequest{CommonName: "x", Alt: []tinycert.SAN{{Email: "ops@*.example.com"}}}, false},
+	}
+	for _, tt := range tests {
+		err := tt.req.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+		if err != nil && !errors.Is(err, tinycert.ErrInvalidRequest) {
+			t.Errorf("%s: expected ErrInvalidRequest, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestCertificate_CreateValidatesBeforeCall(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	_, err := tinycert.NewCertificate(sess).Create(1, tinycert.CertRequest{})
+	if !errors.Is(err, tinycert.ErrInvalidRequest) {
+		t.Fatal("expected ErrInvalidRequest, got", err)
+	}
+	if got := fs.callCount("cert/new"); got != 0 {
+		t.Errorf("cert/new called %d times, want 0", got)
+	}
+}
+
+func TestCARequest_Validate(t *testing.T) {
+	base := tinycert.CARequest{OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"}
+	with := func(fn func(r *tinycert.CARequest)) tinycert.CARequest {
+		r := base
+		fn(&r)
+		return r
+	}
+
+	tests := []struct {
+		name string
+		req  tinycert.CARequest
+		ok   bool
+	}{
+		{"minimal", base, true},
+		{"full", with(func(r *tinycert.CARequest) {
+			r.OrgUnit, r.CommonName, r.Email, r.HashAlgorithm = "pki", "Acme Root", "pki@acme.example", tinycert.SHA512
+		}), true},
+		{"missing org", with(func(r *tinycert.CARequest) { r.OrgName = "" }), false},
+		{"bad country", with(func(r *tinycert.CARequest) { r.CountryCode = "USA" }), false},
+		{"bad email", with(func(r *tinycert.CARequest) { r.Email = "not an email" }), false},
+		{"bad hash", with(func(r *tinycert.CARequest) { r.HashAlgorithm = tinycert.HashAlgorithm(9) }), false},
+	}
+	for _, tt := range tests {
+		err := tt.req.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+		if err != nil && !errors.Is(err, tinycert.ErrInvalidRequest) {
+			t.Errorf("%s: expected ErrInvalidRequest, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestCA_CreateWithFullSubject(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	ca := tinycert.NewCA(sess)
+
+	caId, err := ca.Create(tinycert.CARequest{
+		OrgName:       "acme",
+		OrgUnit:       "pki",
+		CommonName:    "Acme Issuing CA",
+		Email:         "pki@acme.example",
+		Locality:      "sj",
+		StateCode:     "CA",
+		CountryCode:   "US",
+		HashAlgorithm: tinycert.SHA384,
+	})
+	if err != nil {
+		t.Fatal("unable to create ca", err)
+	}
+
+	info, err := ca.Details(*caId)
+	if err != nil {
+		t.Fatal("unable to fetch ca details", err)
+	}
+	want := tinycert.CAInfo{Id: *caId, CountryCode: "US", StateCode: "CA", Locality: "sj", OrgName: "acme", OrgUnit: "pki",
+		CommonName: "Acme Issuing CA", Email: "pki@acme.example", HashAlgorithm: "sha384"}
+	if *info != want {
+		t.Errorf("details = %+v, want %+v", *info, want)
+	}
+
+	if _, err := ca.Create(tinycert.CARequest{OrgName: "acme"}); !errors.Is(err, tinycert.ErrInvalidRequest) {
+		t.Error("expected ErrInvalidRequest, got", err)
+	}
+	if got := fs.callCount("ca/new"); got != 1 {
+		t.Errorf("ca/new called %d times, want 1", got)
+	}
+}
+
+func TestCertRequest_Normalize(t *testing.T) {
+	req, err := tinycert.CertRequest{
+		CommonName: "*.Bücher.example",
+		Alt:        []tinycert.SAN{{DNS: "*.bücher.example"}, {DNS: "*.xn--bcher-kva.example"}},
+	}.Normalize()
+	if err != nil {
+		t.Fatal("Normalize()", err)
+	}
+	if req.CommonName != "*.xn--bcher-kva.example" || len(req.Alt) != 1 || req.Alt[0].DNS != req.CommonName {
+		t.Errorf("Normalize() = %+v", req)
+	}
+
+	_, err = tinycert.CertRequest{CommonName: "*.com"}.Normalize()
+	if !errors.Is(err, tinycert.ErrInvalidRequest) || !strings.Contains(err.Error(), "top-level domain") {
+		t.Errorf("Normalize(*.com) = %v", err)
+	}
+}
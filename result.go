@@ -0,0 +1,116 @@
+package tinycert
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is the value of a call together with what happened on the wire, so
+// a single call can be logged or debugged without WithDebug dumping every
+// call of the session. Get one with Capture.
+type Result[T any] struct {
+	Value T
+	// Endpoint is the API endpoint of the last call made, e.g. "cert/get".
+	Endpoint string
+	// HTTPStatus is the status of the last response; zero if no response
+	// was received.
+	HTTPStatus int
+	// RequestID is the RequestIDHeader sent with the last attempt.
+	RequestID string
+	// Latency is the time the last call took, retries and their waits
+	// included.
+	Latency time.Duration
+	// Retries is how many times the last call was retried.
+	Retries int
+
+	body []byte
+}
+
+// RawBody returns the body of the last response, before decoding, with
+// passphrases and tokens redacted as in WithDebug's dumps. It is nil for
+// streamed responses and failed requests.
+func (r *Result[T]) RawBody() []byte {
+	return r.body
+}
+
+// callMeta collects what a Result reports about the calls made with a
+// context.
+type callMeta struct {
+	mu         sync.Mutex
+	endpoint   string
+	httpStatus int
+	requestID  string
+	latency    time.Duration
+	retries    int
+	body       []byte
+}
+
+type callMetaKey struct{}
+
+// Capture runs fn with a context that records the API calls it makes and
+// returns fn's value along with the metadata of the last of them:
+//
+//	res, err := tinycert.Capture(ctx, func(ctx context.Context) (*int64, error) {
+//		return cert.CreateCtx(ctx, caId, req)
+//	})
+//	log.Printf("%s: HTTP %d in %s after %d retries", res.Endpoint, res.HTTPStatus, res.Latency, res.Retries)
+//
+// The Result is returned even when fn fails, to show how the failing call
+// went. Methods that make several calls, such as ones reconnecting an
+// expired session, report the last.
+func Capture[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) (*Result[T], error) {
+	meta := &callMeta{}
+	value, err := fn(context.WithValue(ctx, callMetaKey{}, meta))
+
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	return &Result[T]{
+		Value:      value,
+		Endpoint:   meta.endpoint,
+		HTTPStatus: meta.httpStatus,
+		RequestID:  meta.requestID,
+		Latency:    meta.latency,
+		Retries:    meta.retries,
+		body:       meta.body,
+	}, err
+}
+
+func callMetaFrom(ctx context.Context) *callMeta {
+	meta, _ := ctx.Value(callMetaKey{}).(*callMeta)
+	return meta
+}
+
+// startCall resets the metadata for a new call to api.
+func (m *callMeta) startCall(api string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoint, m.httpStatus, m.requestID, m.latency, m.retries, m.body = api, 0, "", 0, 0, nil
+}
+
+// attempt records a request sent; status and body are those of its response,
+// if any.
+func (m *callMeta) attempt(requestID string, status int, body []byte) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requestID, m.httpStatus, m.body = requestID, status, nil
+	if body != nil {
+		m.body = redactJSON(append([]byte(nil), body...))
+	}
+}
+
+// finishCall records the retries and latency of the call.
+func (m *callMeta) finishCall(retries int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries, m.latency = retries, latency
+}
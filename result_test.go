@@ -0,0 +1,79 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCapture(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithRetryPolicy(tinycert.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	})
+	ca := tinycert.NewCA(sess)
+	caId, err := ca.Create(tinycert.CARequest{CommonName: "acme", OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failures := 1
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" && failures > 0 {
+			failures--
+			return http.StatusServiceUnavailable, `{"code":"503","text":"try again"}`
+		}
+		return 0, ""
+	})
+	res, err := tinycert.Capture(context.Background(), ca.ListCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Value) != 1 || res.Value[0].Id != *caId {
+		t.Errorf("unexpected value %+v", res.Value)
+	}
+	if res.Endpoint != "ca/list" || res.HTTPStatus != http.StatusOK || res.Retries != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if res.Latency <= 0 || res.RequestID == "" {
+		t.Errorf("missing latency or request id: %+v", res)
+	}
+	if !strings.Contains(string(res.RawBody()), `"acme"`) {
+		t.Errorf("unexpected raw body %s", res.RawBody())
+	}
+
+	res2, err := tinycert.Capture(context.Background(), func(ctx context.Context) (*tinycert.CAInfo, error) {
+		return ca.DetailsCtx(ctx, 42)
+	})
+	if !errors.Is(err, tinycert.ErrNotFound) {
+		t.Fatal("expected ErrNotFound, got", err)
+	}
+	if res2.Value != nil || res2.Endpoint != "ca/details" || res2.HTTPStatus != http.StatusNotFound || res2.Retries != 0 {
+		t.Errorf("unexpected result of failed call %+v", res2)
+	}
+	if !strings.Contains(string(res2.RawBody()), "no such ca") {
+		t.Errorf("unexpected raw body %s", res2.RawBody())
+	}
+}
+
+func TestCapture_RedactsToken(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.session()
+
+	res, err := tinycert.Capture(context.Background(), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, sess.ConnectCtx(ctx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Endpoint != "connect" || strings.Contains(string(res.RawBody()), sess.Token()) {
+		t.Errorf("token not redacted from %s", res.RawBody())
+	}
+}
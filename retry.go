@@ -0,0 +1,164 @@
+package tinycert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how a Session retries failed API calls. The zero value
+// disables retries. Network errors are retryable, API errors only when their
+// HTTP status is listed in RetryableStatusCodes.
+//
+// A network error, such as a timeout, can come after the server acted on the
+// call, so the calls creating CAs and certificates are not retried after one
+// unless RetryNonIdempotent is set: a retry could create a duplicate.
+//
+// A retry waits at least as long as the server's Retry-After header asks. If
+// that is longer than MaxRetryAfter the error is returned at once instead, so
+// the caller can decide how to back off; zero means no cap.
+type RetryPolicy struct {
+	MaxAttempts          int
+	InitialBackoff       time.Duration
+	MaxBackoff           time.Duration
+	Multiplier           float64
+	Jitter               float64
+	RetryableStatusCodes []int
+	MaxRetryAfter        time.Duration
+	RetryNonIdempotent   bool
+}
+
+// nonIdempotent are the endpoints that create something each time they are
+// called.
+var nonIdempotent = map[string]bool{
+	"ca/new":       true,
+	"cert/new":     true,
+	"cert/reissue": true,
+}
+
+// RetryAttempt is one failed attempt of a call.
+type RetryAttempt struct {
+	// HTTPStatus is the status of the response; zero if none was received,
+	// e.g. after a network error.
+	HTTPStatus int
+	Err        error
+	// Delay is how long the call waited before the next attempt.
+	Delay time.Duration
+}
+
+func newRetryAttempt(err error) RetryAttempt {
+	attempt := RetryAttempt{Err: err}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		attempt.HTTPStatus = apiErr.status()
+	}
+	return attempt
+}
+
+// RetryError is returned when a call was retried and still failed. It lists
+// every attempt, so a persistent 401 can be told from a flaky 503 by the
+// error alone. It unwraps to the error of the last attempt, so errors.Is and
+// errors.As see through it.
+type RetryError struct {
+	Endpoint string
+	Attempts []RetryAttempt
+}
+
+func (e *RetryError) Error() string {
+	statuses := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		statuses[i] = "error"
+		if attempt.HTTPStatus != 0 {
+			statuses[i] = strconv.Itoa(attempt.HTTPStatus)
+		}
+		if attempt.Delay > 0 {
+			statuses[i] += " (waited " + attempt.Delay.Round(time.Millisecond).String() + ")"
+		}
+	}
+	return fmt.Sprintf("tinycert: %s failed after %d attempts (%s): %v", e.Endpoint, len(e.Attempts), strings.Join(statuses, ", "), e.Unwrap())
+}
+
+func (e *RetryError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	InitialBackoff:       250 * time.Millisecond,
+	MaxBackoff:           5 * time.Second,
+	Multiplier:           2,
+	Jitter:               0.2,
+	RetryableStatusCodes: []int{429, 500, 502, 503, 504},
+	MaxRetryAfter:        30 * time.Second,
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryable(ctx context.Context, api string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var streamErr *streamError
+	if errors.As(err, &streamErr) || errors.Is(err, ErrResponseTooLarge) {
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return p.RetryNonIdempotent || !nonIdempotent[api]
+	}
+
+	status := apiErr.status()
+	for _, code := range p.RetryableStatusCodes {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns how long to wait before retrying after err, and false if the
+// server asked for longer than MaxRetryAfter.
+func (p RetryPolicy) wait(attempt int, err error) (time.Duration, bool) {
+	wait := p.backoff(attempt)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if p.MaxRetryAfter > 0 && apiErr.RetryAfter > p.MaxRetryAfter {
+			return 0, false
+		}
+		wait = max(wait, apiErr.RetryAfter)
+	}
+	return wait, true
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		wait += wait * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(wait)
+}
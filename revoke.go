@@ -0,0 +1,129 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+func (c *Certificate) Revoke(certId int64) (err error) {
+	return c.RevokeCtx(context.Background(), certId)
+}
+
+func (c *Certificate) RevokeCtx(ctx context.Context, certId int64) (err error) {
+	return c.StatusCtx(ctx, certId, Revoked)
+}
+
+func (c *Certificate) Hold(certId int64) (err error) {
+	return c.HoldCtx(context.Background(), certId)
+}
+
+func (c *Certificate) HoldCtx(ctx context.Context, certId int64) (err error) {
+	return c.StatusCtx(ctx, certId, Hold)
+}
+
+// TimedHold is a hold placed by HoldFor, released when Until is reached.
+type TimedHold struct {
+	CertId int64
+	Until  time.Time
+
+	timer *time.Timer
+	done  chan struct{}
+	err   error
+}
+
+// Cancel stops the scheduled release, leaving the certificate on hold. It
+// returns false if the release has already started.
+func (h *TimedHold) Cancel() bool {
+	if !h.timer.Stop() {
+		return false
+	}
+	close(h.done)
+	return true
+}
+
+// Done is closed once the certificate has been released, or the release was
+// cancelled.
+func (h *TimedHold) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err returns the error of the release, once Done is closed.
+func (h *TimedHold) Err() error {
+	<-h.done
+	return h.err
+}
+
+func (c *Certificate) HoldFor(certId int64, d time.Duration) (hold *TimedHold, err error) {
+	return c.HoldForCtx(context.Background(), certId, d)
+}
+
+// HoldForCtx puts a certificate on hold, e.g. while an incident is
+// investigated, and releases it again after d. The release runs in this
+// process, with ctx's values but not its cancellation; it is lost if the
+// process exits first, so long-running services should record Until and
+// release the certificate themselves after a restart. A failed release is
+// logged and reported by Err.
+func (c *Certificate) HoldForCtx(ctx context.Context, certId int64, d time.Duration) (hold *TimedHold, err error) {
+	if err = c.HoldCtx(ctx, certId); err != nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	hold = &TimedHold{CertId: certId, Until: c.session.clock.Now().Add(d), done: make(chan struct{})}
+	hold.timer = time.AfterFunc(d, func() {
+		defer close(hold.done)
+		if hold.err = c.ReleaseCtx(ctx, certId); hold.err != nil {
+			c.session.logger.Log(LevelError, "unable to release certificate %d from hold: %v", certId, hold.err)
+		}
+	})
+	return
+}
+
+// Release puts a certificate on hold back in good standing.
+func (c *Certificate) Release(certId int64) (err error) {
+	return c.ReleaseCtx(context.Background(), certId)
+}
+
+func (c *Certificate) ReleaseCtx(ctx context.Context, certId int64) (err error) {
+	return c.StatusCtx(ctx, certId, Good)
+}
+
+func (ca *CA) CRL(caId int64) (crl *x509.RevocationList, err error) {
+	return ca.CRLCtx(context.Background(), caId)
+}
+
+// CRLCtx fetches and parses the CA's certificate revocation list. The CRL is
+// not checked against the CA; use CheckRevocation for that.
+func (ca *CA) CRLCtx(ctx context.Context, caId int64) (crl *x509.RevocationList, err error) {
+	data, err := ca.GetArtifactCtx(ctx, caId, CRL)
+	if err != nil {
+		return
+	}
+
+	der := []byte(*data)
+	if block, _ := pem.Decode(der); block != nil {
+		der = block.Bytes
+	}
+	return x509.ParseRevocationList(der)
+}
+
+// CheckRevocation verifies that crl was signed by issuer and reports whether
+// leaf is listed in it.
+func CheckRevocation(leaf *x509.Certificate, crl *x509.RevocationList, issuer *x509.Certificate) (revoked bool, err error) {
+	if err = crl.CheckSignatureFrom(issuer); err != nil {
+		return false, fmt.Errorf("tinycert: CRL not signed by issuer: %w", err)
+	}
+	if err = leaf.CheckSignatureFrom(issuer); err != nil {
+		return false, fmt.Errorf("tinycert: certificate not issued by issuer: %w", err)
+	}
+
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
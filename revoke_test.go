@@ -0,0 +1,144 @@
+package tinycert_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestRevocation(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	ca := tinycert.NewCA(sess)
+
+	leaf, _, err := cert.GetParsed(certId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuerPem, err := ca.Get(caId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := parseCert(*issuerPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want bool) {
+		t.Helper()
+		crl, err := ca.CRL(caId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revoked, err := tinycert.CheckRevocation(leaf, crl, issuer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != want {
+			t.Errorf("revoked = %v, want %v", revoked, want)
+		}
+	}
+
+	check(false)
+
+	if err := cert.Hold(certId); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Release(certId); err != nil {
+		t.Fatal(err)
+	}
+	check(false)
+
+	if err := cert.Revoke(certId); err != nil {
+		t.Fatal(err)
+	}
+	check(true)
+}
+
+func TestCertificate_HoldFor(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+
+	status := func() tinycert.CertificateStatus {
+		t.Helper()
+		info, err := cert.Details(certId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Status
+	}
+
+	hold, err := cert.HoldFor(certId, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != tinycert.Hold {
+		t.Fatalf("status = %s, want hold", got)
+	}
+	select {
+	case <-hold.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("hold was not released")
+	}
+	if err := hold.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != tinycert.Good {
+		t.Errorf("status = %s after release, want good", got)
+	}
+
+	hold, err = cert.HoldFor(certId, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hold.Cancel() {
+		t.Fatal("Cancel() = false before the release")
+	}
+	<-hold.Done()
+	if got := status(); got != tinycert.Hold {
+		t.Errorf("status = %s after Cancel, want hold", got)
+	}
+}
+
+func TestRevocation_WithCache(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithCache(tinycert.NewMemoryCache(0))
+	caId, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	ca := tinycert.NewCA(sess)
+	leaf, _, err := cert.GetParsed(certId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := func() bool {
+		t.Helper()
+		crl, err := ca.CRL(caId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	if listed() {
+		t.Fatal("certificate listed before it was revoked")
+	}
+	if err := cert.Revoke(certId); err != nil {
+		t.Fatal(err)
+	}
+	if !listed() {
+		t.Error("cached CRL does not list the revoked certificate")
+	}
+}
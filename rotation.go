@@ -0,0 +1,152 @@
+package tinycert
+
+import (
+	"context"
+	"fmt"
+)
+
+// Rotation replaces a CA: it creates a new CA with the same subject, issues
+// every good certificate of the old CA again under the new one, and can then
+// delete the old CA.
+type Rotation struct {
+	ca   *CA
+	cert *Certificate
+	// DeleteOld deletes the old CA once all of its certificates have moved.
+	// It is left alone if any certificate failed to.
+	DeleteOld bool
+	// Progress, if set, is told about each certificate as it is moved, by
+	// its old id.
+	Progress Progress
+}
+
+// RotationResult is the outcome of moving one certificate; exactly one of
+// NewCertId and Err is set.
+type RotationResult struct {
+	OldCertId int64
+	NewCertId *int64
+	Err       error
+}
+
+// RotationReport describes a finished rotation.
+type RotationReport struct {
+	OldCAId int64
+	NewCAId int64
+	// Results are in the order the old CA lists its certificates.
+	Results      []*RotationResult
+	OldCADeleted bool
+}
+
+// Failed returns the results of the certificates that did not move.
+func (r *RotationReport) Failed() (failed []*RotationResult) {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return
+}
+
+// NewRotation returns a Rotation moving DefaultParallelism certificates at a
+// time.
+func NewRotation(session *Session) *Rotation {
+	return &Rotation{ca: NewCA(session), cert: NewCertificate(session)}
+}
+
+func (r *Rotation) WithParallelism(parallelism int) *Rotation {
+	r.cert.WithParallelism(parallelism)
+	return r
+}
+
+func (r *Rotation) Run(oldCAId int64) (report *RotationReport, err error) {
+	return r.RunCtx(context.Background(), oldCAId)
+}
+
+// RunCtx rotates the CA. An error is returned if the new CA could not be
+// created or the old one could not be listed or deleted; certificates that
+// failed to move are reported in the report's results instead. The old
+// certificates are left valid, so services keep working until they pick up
+// the new ones.
+func (r *Rotation) RunCtx(ctx context.Context, oldCAId int64) (report *RotationReport, err error) {
+	info, err := r.ca.DetailsCtx(ctx, oldCAId)
+	if err != nil {
+		return
+	}
+	req, err := caRequestFrom(info)
+	if err != nil {
+		return
+	}
+	items, err := r.cert.ListCtx(ctx, oldCAId, Good)
+	if err != nil {
+		return
+	}
+
+	newCAId, err := r.ca.CreateCtx(ctx, req)
+	if err != nil {
+		return
+	}
+	report = &RotationReport{OldCAId: oldCAId, NewCAId: *newCAId, Results: make([]*RotationResult, len(items))}
+
+	progress := startProgress(r.Progress, len(items))
+	r.cert.forEach(ctx, len(items), func(ctx context.Context, i int) {
+		result := &RotationResult{OldCertId: items[i].Id}
+		result.NewCertId, result.Err = r.move(ctx, items[i].Id, *newCAId)
+		report.Results[i] = result
+		progress.item(formatInt(items[i].Id), result.Err)
+	})
+	progress.done()
+
+	if r.DeleteOld && len(report.Failed()) == 0 {
+		if err = r.ca.DeleteCtx(ctx, oldCAId); err != nil {
+			return
+		}
+		report.OldCADeleted = true
+	}
+	return
+}
+
+// move issues a certificate like certId under the new CA and records the
+// new one as its replacement.
+func (r *Rotation) move(ctx context.Context, certId, newCAId int64) (newCertId *int64, err error) {
+	info, err := r.cert.DetailsCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+	if newCertId, err = r.cert.CreateCtx(ctx, newCAId, certRequestFrom(info)); err != nil {
+		return
+	}
+
+	session := r.cert.session
+	session.lineage.Record(certId, *newCertId, "ca rotation")
+	session.emit(Event{Type: CertificateReissued, CAId: newCAId, CertId: *newCertId, PreviousCertId: certId})
+	return
+}
+
+func caRequestFrom(info *CAInfo) (req CARequest, err error) {
+	req = CARequest{
+		CommonName:  info.CommonName,
+		OrgUnit:     info.OrgUnit,
+		OrgName:     info.OrgName,
+		Locality:    info.Locality,
+		StateCode:   info.StateCode,
+		CountryCode: info.CountryCode,
+		Email:       info.Email,
+	}
+	if info.HashAlgorithm != "" {
+		if req.HashAlgorithm, err = ParseHashAlgorithm(info.HashAlgorithm); err != nil {
+			err = fmt.Errorf("tinycert: ca %d: %w", info.Id, err)
+		}
+	}
+	return
+}
+
+func certRequestFrom(info *CertificateInfo) CertRequest {
+	return CertRequest{
+		CommonName:  info.CommonName,
+		OrgUnit:     info.OrgUnit,
+		OrgName:     info.OrgName,
+		Locality:    info.Locality,
+		StateCode:   info.StateCode,
+		CountryCode: info.CountryCode,
+		Alt:         info.Alt,
+	}
+}
@@ -0,0 +1,92 @@
+package tinycert_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestRotation(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	oldCAId, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	revokedId, err := cert.Create(oldCAId, tinycert.CertRequest{CommonName: "old.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Revoke(*revokedId); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress strings.Builder
+	rotation := tinycert.NewRotation(sess)
+	rotation.DeleteOld = true
+	rotation.Progress = tinycert.NewProgressBar(&progress, "rotating")
+
+	report, err := rotation.Run(oldCAId)
+	if err != nil {
+		t.Fatal("rotation failed", err)
+	}
+	if len(report.Results) != 1 || report.Results[0].OldCertId != certId || report.Results[0].Err != nil {
+		t.Fatalf("results = %+v", report.Results)
+	}
+	if !report.OldCADeleted || report.NewCAId == oldCAId {
+		t.Errorf("report = %+v", report)
+	}
+	if !strings.HasSuffix(progress.String(), "] 1/1\n") {
+		t.Errorf("progress = %q", progress.String())
+	}
+
+	newInfo, err := tinycert.NewCA(sess).Details(report.NewCAId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newInfo.OrgName != "acme" || newInfo.Locality != "sj" || newInfo.CountryCode != "US" {
+		t.Errorf("new ca subject = %+v", newInfo)
+	}
+
+	moved, err := cert.Details(*report.Results[0].NewCertId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.CommonName != "www.example.com" || len(moved.Alt) != 1 || moved.Alt[0].DNS != "www.example.com" {
+		t.Errorf("moved certificate = %+v", moved)
+	}
+	if history := cert.History(*report.Results[0].NewCertId); len(history) != 2 || history[0] != certId {
+		t.Errorf("history = %v", history)
+	}
+	if _, err := tinycert.NewCA(sess).Details(oldCAId); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Errorf("old ca still exists: %v", err)
+	}
+}
+
+func TestRotation_KeepsOldCAOnFailure(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	oldCAId, _ := newCAAndCert(t, sess)
+
+	fs.setFail(func(api string) (int, string) {
+		if api == "cert/new" {
+			return http.StatusInternalServerError, `{"code": 500, "text": "down"}`
+		}
+		return 0, ""
+	})
+
+	rotation := tinycert.NewRotation(sess.WithRetryPolicy(tinycert.RetryPolicy{}))
+	rotation.DeleteOld = true
+	report, err := rotation.Run(oldCAId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed()) != 1 || report.OldCADeleted {
+		t.Errorf("report = %+v", report)
+	}
+	if fs.callCount("ca/delete") != 0 {
+		t.Error("old ca deleted despite failures")
+	}
+}
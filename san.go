@@ -0,0 +1,167 @@
+package tinycert
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// SAN is a subject alternative name. Exactly one field must be set; use the
+// DNSName, IPAddress, EmailAddress and URISAN constructors to build one.
+type SAN struct {
+	DNS   string
+	Email string
+	IP    string
+	URI   string
+}
+
+func DNSName(name string) SAN {
+	return SAN{DNS: name}
+}
+
+func IPAddress(ip net.IP) SAN {
+	return SAN{IP: ip.String()}
+}
+
+func EmailAddress(email string) SAN {
+	return SAN{Email: email}
+}
+
+func URISAN(uri *url.URL) SAN {
+	return SAN{URI: uri.String()}
+}
+
+// Validate checks that exactly one field is set and that it is well formed.
+func (san SAN) Validate() error {
+	set := 0
+	for _, value := range []string{san.DNS, san.Email, san.IP, san.URI} {
+		if value != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return errors.New("empty SAN")
+	case set > 1:
+		return fmt.Errorf("SAN %+v sets more than one field", san)
+	}
+
+	switch {
+	case san.IP != "":
+		if net.ParseIP(san.IP) == nil {
+			return fmt.Errorf("invalid IP address %q", san.IP)
+		}
+	case san.URI != "":
+		u, err := url.Parse(san.URI)
+		if err != nil {
+			return fmt.Errorf("invalid URI %q: %v", san.URI, err)
+		}
+		if u.Scheme == "" {
+			return fmt.Errorf("URI %q has no scheme", san.URI)
+		}
+	case san.Email != "":
+		if _, err := mail.ParseAddress(san.Email); err != nil {
+			return fmt.Errorf("invalid email address %q", san.Email)
+		}
+	}
+	return nil
+}
+
+// Normalize validates the SAN and returns it in canonical form: DNS names
+// lowercased, without a trailing dot and with IDNs in punycode; IPs in their
+// shortest text form; email addresses without a display name and with the
+// domain normalized like a DNS name; URIs with lowercase scheme and host.
+func (san SAN) Normalize() (SAN, error) {
+	if err := san.Validate(); err != nil {
+		return SAN{}, err
+	}
+
+	switch {
+	case san.DNS != "":
+		name, err := normalizeDNSName(san.DNS, true)
+		if err != nil {
+			return SAN{}, err
+		}
+		if net.ParseIP(name) != nil {
+			return SAN{}, fmt.Errorf("DNS name %q is an IP address; use an IP SAN", san.DNS)
+		}
+		return SAN{DNS: name}, nil
+	case san.IP != "":
+		return SAN{IP: net.ParseIP(san.IP).String()}, nil
+	case san.Email != "":
+		addr, _ := mail.ParseAddress(san.Email)
+		at := strings.LastIndex(addr.Address, "@")
+		domain, err := normalizeDNSName(addr.Address[at+1:], false)
+		if err != nil {
+			return SAN{}, fmt.Errorf("email address %q: %v", san.Email, err)
+		}
+		return SAN{Email: addr.Address[:at+1] + domain}, nil
+	default:
+		u, _ := url.Parse(san.URI)
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+		return SAN{URI: u.String()}, nil
+	}
+}
+
+// dnsProfile is idna.Lookup plus a check for empty and overlong labels.
+var dnsProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
+
+// normalizeDNSName converts name to its lowercase ASCII form. When
+// allowWildcard is set, a "*" may stand for the whole leftmost label of a
+// name with at least two further labels, the only wildcard form certificates
+// can carry.
+func normalizeDNSName(name string, allowWildcard bool) (string, error) {
+	orig := name
+	name = strings.TrimSuffix(name, ".")
+	wildcard := strings.HasPrefix(name, "*.")
+	if wildcard {
+		name = name[2:]
+	}
+	switch {
+	case strings.Contains(name, "*"):
+		return "", fmt.Errorf("wildcard in %q must be the whole leftmost label, as in *.example.com", orig)
+	case wildcard && !allowWildcard:
+		return "", fmt.Errorf("wildcard not allowed in %q", orig)
+	case wildcard && !strings.Contains(name, "."):
+		return "", fmt.Errorf("wildcard %q would cover a top-level domain", orig)
+	}
+	ascii, err := dnsProfile.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid DNS name %q: %v", orig, err)
+	}
+	if wildcard {
+		ascii = "*." + ascii
+	}
+	return ascii, nil
+}
+
+// NormalizeSANs normalizes every SAN and drops the ones that duplicate an
+// earlier entry once normalized. All invalid entries are reported together in
+// one ErrInvalidRequest error.
+func NormalizeSANs(sans []SAN) (normalized []SAN, err error) {
+	var problems []string
+	seen := map[SAN]bool{}
+	for i, san := range sans {
+		n, err := san.Normalize()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("SAN %d: %v", i, err))
+			continue
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		normalized = append(normalized, n)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRequest, strings.Join(problems, "; "))
+	}
+	return
+}
@@ -0,0 +1,93 @@
+package tinycert_test
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSAN_Validate(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/web")
+
+	tests := []struct {
+		san tinycert.SAN
+		ok  bool
+	}{
+		{tinycert.DNSName("www.example.com"), true},
+		{tinycert.IPAddress(net.ParseIP("10.0.0.1")), true},
+		{tinycert.IPAddress(net.ParseIP("::1")), true},
+		{tinycert.EmailAddress("ops@example.com"), true},
+		{tinycert.URISAN(spiffe), true},
+		{tinycert.SAN{}, false},
+		{tinycert.SAN{IP: "10.0.0.256"}, false},
+		{tinycert.SAN{URI: "not a uri"}, false},
+		{tinycert.SAN{URI: "http://[::1"}, false},
+		{tinycert.SAN{Email: "not-an-email"}, false},
+		{tinycert.SAN{DNS: "www.example.com", IP: "10.0.0.1"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.san.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v.Validate() = %v", tt.san, err)
+		}
+	}
+}
+
+func TestNormalizeSANs(t *testing.T) {
+	got, err := tinycert.NormalizeSANs([]tinycert.SAN{
+		{DNS: "WWW.Example.COM."},
+		{DNS: "www.example.com"},
+		{DNS: "Bücher.example"},
+		{DNS: "*.Example.com"},
+		{IP: "::ffff:10.0.0.1"},
+		{IP: "10.0.0.1"},
+		{IP: "2001:DB8:0:0:0:0:0:1"},
+		{Email: "Ops <ops@Example.COM>"},
+		{URI: "SPIFFE://Example.org/Web"},
+	})
+	if err != nil {
+		t.Fatal("NormalizeSANs()", err)
+	}
+	want := []tinycert.SAN{
+		{DNS: "www.example.com"},
+		{DNS: "xn--bcher-kva.example"},
+		{DNS: "*.example.com"},
+		{IP: "10.0.0.1"},
+		{IP: "2001:db8::1"},
+		{Email: "ops@example.com"},
+		{URI: "spiffe://example.org/Web"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeSANs() = %v, want %v", got, want)
+	}
+
+	_, err = tinycert.NormalizeSANs([]tinycert.SAN{{DNS: "ok.example.com"}, {DNS: "10.0.0.1"}, {DNS: "bad..example"}})
+	if !errors.Is(err, tinycert.ErrInvalidRequest) || !strings.Contains(err.Error(), "SAN 1: DNS name \"10.0.0.1\" is an IP address") || !strings.Contains(err.Error(), "SAN 2:") {
+		t.Errorf("NormalizeSANs() = %v", err)
+	}
+}
+
+func TestCertificate_CreateNormalizesSANs(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, _ := newCAAndCert(t, sess)
+
+	certId, err := tinycert.NewCertificate(sess).Create(caId, tinycert.CertRequest{
+		CommonName: "www.example.com",
+		Alt:        []tinycert.SAN{{DNS: "WWW.example.com"}, {DNS: "www.example.com."}, {IP: "10.0.0.1"}},
+	})
+	if err != nil {
+		t.Fatal("Create()", err)
+	}
+	info, err := tinycert.NewCertificate(sess).Details(*certId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Alt) != 2 {
+		t.Errorf("issued SANs = %v, want the duplicate DNS name dropped", info.Alt)
+	}
+}
@@ -0,0 +1,217 @@
+package tinycert
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+// SecretLayout names the files SecretsEmitter writes for one service. Each
+// name is a text/template executed with a SecretData and resolved under the
+// emitter's directory; empty names are skipped.
+type SecretLayout struct {
+	// Cert is the leaf certificate alone.
+	Cert string
+	// FullChain is the leaf followed by its CA.
+	FullChain string
+	// Key is the decrypted private key.
+	Key string
+	// CA is the issuing CA certificate.
+	CA string
+	// Keystore is a JKS holding the key and chain under the alias of the
+	// service name; Truststore is a JKS holding the CA.
+	Keystore   string
+	Truststore string
+	// Credentials holds the JKS password, for images such as Confluent's
+	// Kafka that read it from a file.
+	Credentials string
+}
+
+// Layouts for the file names common server images expect. Each service gets
+// a directory of its own, ready to be mounted into its container.
+var (
+	NginxLayout    = SecretLayout{FullChain: "{{.Name}}/fullchain.pem", Key: "{{.Name}}/privkey.pem"}
+	PostgresLayout = SecretLayout{FullChain: "{{.Name}}/server.crt", Key: "{{.Name}}/server.key", CA: "{{.Name}}/root.crt"}
+	RedisLayout    = SecretLayout{Cert: "{{.Name}}/redis.crt", Key: "{{.Name}}/redis.key", CA: "{{.Name}}/ca.crt"}
+	KafkaLayout    = SecretLayout{
+		Keystore:    "{{.Name}}/kafka.keystore.jks",
+		Truststore:  "{{.Name}}/kafka.truststore.jks",
+		Credentials: "{{.Name}}/kafka_creds",
+	}
+)
+
+// SecretData is what SecretLayout templates are executed with.
+type SecretData struct {
+	// Name is the service name from the SecretSpec.
+	Name       string
+	CertId     int64
+	CommonName string
+}
+
+// SecretSpec asks for the secrets of one service.
+type SecretSpec struct {
+	Name   string
+	CertId int64
+	Layout SecretLayout
+}
+
+// SecretsEmitter materialises certificates as files under Dir, e.g. for the
+// secrets of a docker-compose stack.
+type SecretsEmitter struct {
+	cert *Certificate
+	Dir  string
+	// Password protects keystores and truststores; it defaults to
+	// DefaultTruststorePassword.
+	Password string
+}
+
+func NewSecretsEmitter(session *Session, dir string) *SecretsEmitter {
+	return &SecretsEmitter{cert: NewCertificate(session), Dir: dir}
+}
+
+func (e *SecretsEmitter) Emit(specs ...SecretSpec) (paths []string, err error) {
+	return e.EmitCtx(context.Background(), specs...)
+}
+
+// EmitCtx writes the files of every spec and returns their paths. Keys,
+// keystores and credentials are written 0600 and certificates 0644; all
+// writes are atomic. Files written before a failure are left in place.
+func (e *SecretsEmitter) EmitCtx(ctx context.Context, specs ...SecretSpec) (paths []string, err error) {
+	for _, spec := range specs {
+		var written []string
+		if written, err = e.emit(ctx, spec); err != nil {
+			return
+		}
+		paths = append(paths, written...)
+	}
+	return
+}
+
+func (e *SecretsEmitter) emit(ctx context.Context, spec SecretSpec) (paths []string, err error) {
+	chainPEM, err := e.cert.GetCtx(ctx, spec.CertId, Chain)
+	if err != nil {
+		return
+	}
+	keyPEM, err := e.cert.GetCtx(ctx, spec.CertId, KeyDecrypted)
+	if err != nil {
+		return
+	}
+	chain, err := parseCertificatesPEM([]byte(*chainPEM))
+	if err != nil {
+		return
+	}
+	if len(chain) < 2 {
+		return nil, fmt.Errorf("tinycert: chain of certificate %d has no CA", spec.CertId)
+	}
+	leaf, cas := chain[0], chain[1:]
+
+	password := e.Password
+	if password == "" {
+		password = DefaultTruststorePassword
+	}
+
+	files := []struct {
+		name    string
+		perm    os.FileMode
+		content func() ([]byte, error)
+	}{
+		{spec.Layout.Cert, 0644, func() ([]byte, error) { return encodeCertificates(chain[:1]), nil }},
+		{spec.Layout.FullChain, 0644, func() ([]byte, error) { return encodeCertificates(chain), nil }},
+		{spec.Layout.Key, 0600, func() ([]byte, error) { return []byte(*keyPEM), nil }},
+		{spec.Layout.CA, 0644, func() ([]byte, error) { return encodeCertificates(cas), nil }},
+		{spec.Layout.Keystore, 0600, func() ([]byte, error) { return keystoreJKS(spec.Name, chain, []byte(*keyPEM), password) }},
+		{spec.Layout.Truststore, 0600, func() ([]byte, error) {
+			ders := make([][]byte, len(cas))
+			for i, ca := range cas {
+				ders[i] = ca.Raw
+			}
+			return truststore("tinycert-ca", ders, password)
+		}},
+		{spec.Layout.Credentials, 0600, func() ([]byte, error) { return []byte(password), nil }},
+	}
+
+	data := SecretData{Name: spec.Name, CertId: spec.CertId, CommonName: leaf.Subject.CommonName}
+	for _, f := range files {
+		if f.name == "" {
+			continue
+		}
+		var path string
+		if path, err = e.path(f.name, data); err != nil {
+			return
+		}
+		var content []byte
+		if content, err = f.content(); err != nil {
+			return
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return
+		}
+		if err = writeFileAtomic(path, content, f.perm); err != nil {
+			return
+		}
+		paths = append(paths, path)
+	}
+	return
+}
+
+// path renders a layout name and keeps the result inside e.Dir.
+func (e *SecretsEmitter) path(name string, data SecretData) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(name)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err = tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	rel := filepath.FromSlash(b.String())
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("tinycert: secret path %q escapes %s", b.String(), e.Dir)
+	}
+	return filepath.Join(e.Dir, rel), nil
+}
+
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
+
+// keystoreJKS builds a JKS holding the key and chain as a private key entry
+// named alias; JKS wants the key in PKCS#8 form whatever TinyCert returned.
+func keystoreJKS(alias string, chain []*x509.Certificate, keyPEM []byte, password string) ([]byte, error) {
+	key, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := keystore.PrivateKeyEntry{CreationTime: time.Now(), PrivateKey: pkcs8}
+	for _, cert := range chain {
+		entry.CertificateChain = append(entry.CertificateChain, keystore.Certificate{Type: "X509", Content: cert.Raw})
+	}
+
+	ks := keystore.New()
+	if err = ks.SetPrivateKeyEntry(alias, entry, []byte(password)); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = ks.Store(&buf, []byte(password)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,30 @@
+package tinycert
+
+import "context"
+
+// CAService is the part of CA that calls the TinyCert API, for code that
+// wants to accept a fake in its tests; see the mocks package. The helpers
+// built on these calls, such as Ensure and Export, stay on CA.
+type CAService interface {
+	CreateCtx(ctx context.Context, req CARequest) (caId *int64, err error)
+	ListCtx(ctx context.Context) (items []*CAListItem, err error)
+	DetailsCtx(ctx context.Context, caId int64) (caInfo *CAInfo, err error)
+	GetArtifactCtx(ctx context.Context, caId int64, what Artifact) (pem *string, err error)
+	DeleteCtx(ctx context.Context, caId int64) (err error)
+}
+
+// CertificateService is the part of Certificate that calls the TinyCert API;
+// see CAService.
+type CertificateService interface {
+	CreateCtx(ctx context.Context, caId int64, req CertRequest) (certId *int64, err error)
+	ListCtx(ctx context.Context, caId int64, status CertificateStatus) (list []*CertificateListItem, err error)
+	DetailsCtx(ctx context.Context, certId int64) (certInfo *CertificateInfo, err error)
+	GetCtx(ctx context.Context, certId int64, what Artifact) (result *string, err error)
+	ReissueWithOptionsCtx(ctx context.Context, certId int64, opts ReissueOptions) (newCertId *int64, err error)
+	StatusCtx(ctx context.Context, certId int64, status CertificateStatus) (err error)
+}
+
+var (
+	_ CAService          = (*CA)(nil)
+	_ CertificateService = (*Certificate)(nil)
+)
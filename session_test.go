@@ -0,0 +1,571 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func newCAAndCert(t *testing.T, sess *tinycert.Session) (caId, certId int64) {
+	t.Helper()
+
+	ca := tinycert.NewCA(sess)
+	id, err := ca.Create(tinycert.CARequest{OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"})
+	if err != nil {
+		t.Fatal("unable to create ca", err)
+	}
+
+	cert := tinycert.NewCertificate(sess)
+	newCertId, err := cert.Create(*id, tinycert.CertRequest{
+		CommonName:  "www.example.com",
+		OrgUnit:     "ou",
+		OrgName:     "acme",
+		Locality:    "sj",
+		StateCode:   "CA",
+		CountryCode: "US",
+		Alt:         []tinycert.SAN{{DNS: "www.example.com"}},
+	})
+	if err != nil {
+		t.Fatal("unable to create certificate", err)
+	}
+
+	return *id, *newCertId
+}
+
+func TestSession_ConcurrentGets(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pem, err := cert.Get(certId, tinycert.Cert)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(*pem) == 0 {
+				errs <- errors.New("empty pem")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error("concurrent fetch failed:", err)
+	}
+}
+
+func TestSession_AutoReconnect(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	ca := tinycert.NewCA(sess)
+
+	fs.expireToken()
+	if _, err := ca.List(); !errors.Is(err, tinycert.ErrUnauthorized) {
+		t.Fatal("expected unauthorized error, got", err)
+	}
+
+	sess.WithAutoReconnect(true)
+	if _, err := ca.List(); err != nil {
+		t.Fatal("expected reconnect to succeed", err)
+	}
+	if got := fs.callCount("connect"); got != 2 {
+		t.Errorf("connect called %d times, want 2", got)
+	}
+}
+
+// slowConnects serves fs, holding connect calls long enough for concurrent
+// callers to pile up behind them.
+func slowConnects(t *testing.T, fs *fakeServer) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/connect") {
+			time.Sleep(50 * time.Millisecond)
+		}
+		fs.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSession_ConcurrentConnect(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.session().WithBaseURL(slowConnects(t, fs).URL + "/api")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- sess.Connect()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error("concurrent connect failed:", err)
+		}
+	}
+	if got := fs.callCount("connect"); got != 1 {
+		t.Errorf("connect called %d times, want 1", got)
+	}
+	if err := sess.Validate(); err != nil {
+		t.Error("shared token is not valid:", err)
+	}
+}
+
+func TestSession_ConcurrentAutoReconnect(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.session().WithBaseURL(slowConnects(t, fs).URL + "/api").WithAutoReconnect(true)
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ca := tinycert.NewCA(sess)
+
+	fs.expireToken()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ca.List()
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error("list after reconnect failed:", err)
+		}
+	}
+	if got := fs.callCount("connect"); got != 2 {
+		t.Errorf("connect called %d times, want 2", got)
+	}
+}
+
+func TestSession_ConnectCancelWhileShared(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.session().WithBaseURL(slowConnects(t, fs).URL + "/api")
+
+	done := make(chan error, 1)
+	go func() { done <- sess.Connect() }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := sess.ConnectCtx(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected the waiting caller to give up, got", err)
+	}
+	if err := <-done; err != nil {
+		t.Error("shared connect failed:", err)
+	}
+	if got := fs.callCount("connect"); got != 1 {
+		t.Errorf("connect called %d times, want 1", got)
+	}
+}
+
+func TestSession_RetryPolicy(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithRetryPolicy(tinycert.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	})
+
+	failures := 2
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" && failures > 0 {
+			failures--
+			return http.StatusServiceUnavailable, `{"code":"503","text":"try again"}`
+		}
+		return 0, ""
+	})
+
+	if _, err := tinycert.NewCA(sess).List(); err != nil {
+		t.Fatal("expected call to succeed after retries", err)
+	}
+	if got := fs.callCount("ca/list"); got != 3 {
+		t.Errorf("ca/list called %d times, want 3", got)
+	}
+}
+
+func TestSession_RetryNetworkErrors(t *testing.T) {
+	fs := newFakeServer(t)
+	// Act on every call, then drop the connection before responding, as a
+	// timeout after the server committed the call would.
+	dropping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/connect") {
+			fs.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		fs.Config.Handler.ServeHTTP(httptest.NewRecorder(), r)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer dropping.Close()
+
+	policy := tinycert.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	sess := fs.session().WithBaseURL(dropping.URL + "/api").WithRetryPolicy(policy)
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ca := tinycert.NewCA(sess)
+	req := tinycert.CARequest{CommonName: "acme", OrgName: "acme", Locality: "sj", StateCode: "CA", CountryCode: "US"}
+
+	if _, err := ca.List(); err == nil {
+		t.Fatal("expected the dropped connections to fail ca/list")
+	}
+	if got := fs.callCount("ca/list"); got != 3 {
+		t.Errorf("ca/list called %d times, want 3", got)
+	}
+	if _, err := ca.Create(req); err == nil {
+		t.Fatal("expected the dropped connection to fail ca/new")
+	}
+	if got := fs.callCount("ca/new"); got != 1 {
+		t.Errorf("ca/new called %d times, want 1", got)
+	}
+
+	policy.RetryNonIdempotent = true
+	sess.WithRetryPolicy(policy)
+	if _, err := ca.Create(req); err == nil {
+		t.Fatal("expected the dropped connections to fail ca/new")
+	}
+	if got := fs.callCount("ca/new"); got != 4 {
+		t.Errorf("ca/new called %d times, want 4", got)
+	}
+}
+
+func TestSession_RetryErrorEnvelope(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithRetryPolicy(tinycert.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	})
+
+	var failures atomic.Int32
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" && failures.Add(1) == 1 {
+			return http.StatusOK, `{"code":"503","text":"try again"}`
+		}
+		return 0, ""
+	})
+	if _, err := tinycert.NewCA(sess).List(); err != nil {
+		t.Fatal("expected a 503 error envelope to be retried", err)
+	}
+	if got := fs.callCount("ca/list"); got != 2 {
+		t.Errorf("ca/list called %d times, want 2", got)
+	}
+}
+
+func TestSession_RetryError(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithRetryPolicy(tinycert.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		Multiplier:           2,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable, http.StatusInternalServerError},
+	})
+
+	statuses := []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusServiceUnavailable}
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/list" && len(statuses) > 0 {
+			status := statuses[0]
+			statuses = statuses[1:]
+			return status, `{"code":"` + strconv.Itoa(status) + `","text":"try again"}`
+		}
+		return 0, ""
+	})
+
+	_, err := tinycert.NewCA(sess).List()
+	var retryErr *tinycert.RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatal("expected RetryError, got", err)
+	}
+	if retryErr.Endpoint != "ca/list" || len(retryErr.Attempts) != 3 {
+		t.Fatalf("unexpected RetryError %+v", retryErr)
+	}
+	for i, want := range []struct {
+		status int
+		delay  time.Duration
+	}{{503, time.Millisecond}, {500, 2 * time.Millisecond}, {503, 0}} {
+		if got := retryErr.Attempts[i]; got.HTTPStatus != want.status || got.Delay != want.delay || got.Err == nil {
+			t.Errorf("attempt %d = %+v, want status %d delay %s", i, got, want.status, want.delay)
+		}
+	}
+	if want := "tinycert: ca/list failed after 3 attempts (503 (waited 1ms), 500 (waited 2ms), 503): "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Error() = %q", err)
+	}
+
+	var apiErr *tinycert.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusServiceUnavailable {
+		t.Error("expected RetryError to unwrap to the last APIError, got", err)
+	}
+}
+
+func TestSession_RetryError_SingleAttempt(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	_, err := tinycert.NewCA(sess).Details(42)
+	var retryErr *tinycert.RetryError
+	if errors.As(err, &retryErr) {
+		t.Error("a call that was not retried returned a RetryError", err)
+	}
+}
+
+func TestSession_APIError(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	_, err := tinycert.NewCA(sess).Details(42)
+
+	var apiErr *tinycert.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatal("expected APIError, got", err)
+	}
+	if apiErr.HTTPStatus != http.StatusNotFound || apiErr.Code != "404" || apiErr.Message != "no such ca" {
+		t.Errorf("unexpected error fields: %+v", apiErr)
+	}
+	if !errors.Is(err, tinycert.ErrNotFound) {
+		t.Error("expected errors.Is(err, ErrNotFound)")
+	}
+}
+
+func TestSession_ErrorEnvelopeOn200(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	fs.setFail(func(api string) (int, string) {
+		if api == "ca/details" {
+			return http.StatusOK, `{"code":"404","text":"CA not found"}`
+		}
+		return 0, ""
+	})
+
+	info, err := tinycert.NewCA(sess).Details(42)
+	if info != nil {
+		t.Errorf("expected no details, got %+v", info)
+	}
+
+	var apiErr *tinycert.APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "CA not found" {
+		t.Fatal("expected APIError from 200 envelope, got", err)
+	}
+	if !errors.Is(err, tinycert.ErrNotFound) {
+		t.Error("expected errors.Is(err, ErrNotFound)")
+	}
+}
+
+func TestSession_BaseURL(t *testing.T) {
+	fs := newFakeServer(t)
+
+	for _, base := range []string{fs.URL + "/api", fs.URL + "/api/", fs.URL + "/api//"} {
+		sess := fs.session().WithBaseURL(base)
+		if err := sess.Connect(); err != nil {
+			t.Errorf("unable to connect via %q: %v", base, err)
+		}
+	}
+
+	sess := fs.session().WithBaseURL(fs.URL + "/api/v1").WithAPIVersion("")
+	if err := sess.Connect(); err != nil {
+		t.Error("unable to connect with versioned base url", err)
+	}
+
+	sess = fs.session().WithAPIVersion("v2")
+	if err := sess.Connect(); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Error("expected not found for unknown api version, got", err)
+	}
+
+	for _, base := range []string{"", "ftp://example.com/api", "/api", "https://example.com/api?x=1", "://bad"} {
+		before := fs.callCount("connect")
+		sess := fs.session().WithBaseURL(base)
+		if err := sess.Connect(); !errors.Is(err, tinycert.ErrInvalidBaseURL) {
+			t.Errorf("base url %q: expected ErrInvalidBaseURL, got %v", base, err)
+		}
+		if fs.callCount("connect") != before {
+			t.Errorf("base url %q reached the server", base)
+		}
+	}
+}
+
+func TestSession_Resume(t *testing.T) {
+	fs := newFakeServer(t)
+
+	if err := fs.session().Validate(); !errors.Is(err, tinycert.ErrNotConnected) {
+		t.Error("expected ErrNotConnected, got", err)
+	}
+
+	token := fs.connectedSession().Token()
+	if token == "" {
+		t.Fatal("connected session has no token")
+	}
+
+	sess := fs.session().Resume(token)
+	if err := sess.Validate(); err != nil {
+		t.Fatal("resumed token rejected", err)
+	}
+	if _, err := tinycert.NewCA(sess).List(); err != nil {
+		t.Error("unable to list with resumed session", err)
+	}
+	if got := fs.callCount("connect"); got != 1 {
+		t.Errorf("connect called %d times, want 1", got)
+	}
+
+	fs.expireToken()
+	sess.WithAutoReconnect(true)
+	if err := sess.Validate(); !errors.Is(err, tinycert.ErrUnauthorized) {
+		t.Error("expected ErrUnauthorized for expired token, got", err)
+	}
+	if got := fs.callCount("connect"); got != 1 {
+		t.Errorf("validate reconnected, connect called %d times", got)
+	}
+}
+
+func TestSession_KeepAlive(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession().WithAutoReconnect(true)
+	fs.expireToken()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sess.KeepAlive(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for fs.callCount("ca/list") < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("KeepAlive() did not return after cancel")
+	}
+
+	if got := fs.callCount("ca/list"); got < 3 {
+		t.Errorf("ca/list called %d times, want at least 3", got)
+	}
+	// The first ping found the token expired and connected again.
+	if got := fs.callCount("connect"); got != 2 {
+		t.Errorf("connect called %d times, want 2", got)
+	}
+	if err := sess.Validate(); err != nil {
+		t.Error("session not valid after keep-alive", err)
+	}
+}
+
+func TestSession_Close(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+
+	var closer io.Closer = sess
+	if err := closer.Close(); err != nil {
+		t.Fatal("Close()", err)
+	}
+	if err := sess.Close(); err != nil {
+		t.Error("second Close()", err)
+	}
+	if got := fs.callCount("disconnect"); got != 1 {
+		t.Errorf("disconnect called %d times, want 1", got)
+	}
+	if sess.Token() != "" {
+		t.Error("token kept after Close()")
+	}
+
+	if _, err := tinycert.NewCA(sess).List(); !errors.Is(err, tinycert.ErrClosed) {
+		t.Errorf("List() after Close() = %v, want ErrClosed", err)
+	}
+	if err := sess.Connect(); !errors.Is(err, tinycert.ErrClosed) {
+		t.Errorf("Connect() after Close() = %v, want ErrClosed", err)
+	}
+	if got := fs.callCount("connect"); got != 1 {
+		t.Errorf("connect called %d times, want 1", got)
+	}
+
+	// A session that never connected closes without calling the API.
+	if err := fs.session().Close(); err != nil || fs.callCount("disconnect") != 1 {
+		t.Errorf("Close() of an unconnected session = %v", err)
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	fs := newFakeServer(t)
+	ctx := context.Background()
+
+	var inside *tinycert.Session
+	err := tinycert.WithSession(ctx, fs.session(), func(s *tinycert.Session) error {
+		inside = s
+		_, err := tinycert.NewCA(s).ListCtx(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal("WithSession()", err)
+	}
+	if fs.callCount("connect") != 1 || fs.callCount("disconnect") != 1 {
+		t.Errorf("connect/disconnect called %d/%d times, want 1/1", fs.callCount("connect"), fs.callCount("disconnect"))
+	}
+	if _, err := tinycert.NewCA(inside).List(); !errors.Is(err, tinycert.ErrClosed) {
+		t.Errorf("session usable after WithSession: %v", err)
+	}
+
+	failed := errors.New("failed")
+	if err := tinycert.WithSession(ctx, fs.session(), func(*tinycert.Session) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("err = %v, want fn's error", err)
+	}
+	if got := fs.callCount("disconnect"); got != 2 {
+		t.Errorf("disconnect called %d times after fn failed, want 2", got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was swallowed")
+			}
+		}()
+		tinycert.WithSession(ctx, fs.session(), func(*tinycert.Session) error { panic("boom") })
+	}()
+	if got := fs.callCount("disconnect"); got != 3 {
+		t.Errorf("disconnect called %d times after a panic, want 3", got)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	called := false
+	err = tinycert.WithSession(cancelled, fs.session(), func(*tinycert.Session) error { called = true; return nil })
+	if !errors.Is(err, context.Canceled) || called {
+		t.Errorf("WithSession(cancelled) = %v, fn called: %v", err, called)
+	}
+
+	bad := fs.session().WithPassphrase("wrong")
+	if err := tinycert.WithSession(ctx, bad, func(*tinycert.Session) error { return nil }); !errors.Is(err, tinycert.ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+}
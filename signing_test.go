@@ -0,0 +1,118 @@
+package tinycert_test
+
+import (
+	"math"
+	"net/url"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+// The digests below were computed independently with
+// printf '%s' "$payload" | openssl dgst -sha256 -hmac apikey.
+func TestSigner_Encode(t *testing.T) {
+	tests := []struct {
+		name   string
+		params url.Values
+		want   string
+	}{
+		{
+			"connect",
+			url.Values{"passphrase": {"secret"}, "email": {"user@example.com"}},
+			"email=user%40example.com&passphrase=secret&digest=541fc6ae2cdfb34585b7511e1eb5dd355df536806c02f4512daf1819e04bbee8",
+		},
+		{
+			"ca/details",
+			url.Values{"token": {"abc"}, "ca_id": {"123"}},
+			"ca_id=123&token=abc&digest=10b9d0b1068c9d759af6c022c50e900c6bd1ecf5dbaf2eac7d5e5659784291c4",
+		},
+		{
+			"cert/new with escaping",
+			url.Values{
+				"token": {"t0k"}, "ca_id": {"7"}, "ST": {"CA"}, "SANs[0][DNS]": {"www.example.com"},
+				"OU": {""}, "O": {"Acme & Co"}, "L": {"San Jose"}, "CN": {"www.example.com"}, "C": {"US"},
+			},
+			"C=US&CN=www.example.com&L=San+Jose&O=Acme+%26+Co&OU=&SANs%5B0%5D%5BDNS%5D=www.example.com&ST=CA&ca_id=7&token=t0k" +
+				"&digest=393643e45e91f3b4ab2dd486f788914556fdcf55c85046dce083c584b8596a35",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tinycert.NewSigner("apikey").Encode(tt.params); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSigner_Verify(t *testing.T) {
+	signer := tinycert.NewSigner("apikey")
+	params := url.Values{"ca_id": {"123"}, "token": {"abc"}}
+
+	digest := signer.Sign(params)
+	if digest != "10b9d0b1068c9d759af6c022c50e900c6bd1ecf5dbaf2eac7d5e5659784291c4" {
+		t.Errorf("Sign() = %s", digest)
+	}
+
+	body, err := url.ParseQuery(signer.Encode(params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signer.Verify(body) {
+		t.Error("signed body does not verify")
+	}
+	if signer.Sign(body) != digest {
+		t.Error("digest parameter was included in the signed input")
+	}
+
+	body.Set("ca_id", "124")
+	if signer.Verify(body) {
+		t.Error("tampered body verifies")
+	}
+	if tinycert.NewSigner("other").Verify(params) {
+		t.Error("body without digest verifies")
+	}
+}
+
+// Each typed setter has one spelling; the digests were computed with openssl
+// as above.
+func TestFields_Encode(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields tinycert.Fields
+		want   string
+	}{
+		{
+			"small ints",
+			tinycert.Fields{}.SetInt("ca_id", 0).SetInt("neg", -1).Set("token", "t"),
+			"ca_id=0&neg=-1&token=t&digest=b632a02e3685a11c2e918d751ecdc5b8100bfefbd86fff31449ba10a082378cd",
+		},
+		{
+			"int64 limits",
+			tinycert.Fields{}.SetInt("max", math.MaxInt64).SetInt("min", math.MinInt64),
+			"max=9223372036854775807&min=-9223372036854775808&digest=97604cfc68ccb104d93217add23f7632f5d5d897c1269ae33ef6821f8eea0209",
+		},
+		{
+			"bools",
+			tinycert.Fields{}.SetBool("on", true).SetBool("off", false),
+			"off=0&on=1&digest=fabe664ffffa097902afc5c998ad62cae6631b42edbd90877b96d8915f957071",
+		},
+		{
+			"strings needing escapes",
+			tinycert.Fields{}.Set("space", "a b").Set("O", "a+b=c&d").Set("CN", "bücher.example").Set("empty", ""),
+			"CN=b%C3%BCcher.example&O=a%2Bb%3Dc%26d&empty=&space=a+b&digest=f11880f710b69afc21801e2f40549d15a6baba6bacbaef6e3db20e83bdfe969a",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tinycert.NewSigner("apikey").Encode(tt.fields.Values()); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFields_SetReplaces(t *testing.T) {
+	fields := tinycert.Fields{}.SetInt("ca_id", 1).SetInt("ca_id", 2)
+	if got := fields.Values()["ca_id"]; len(got) != 1 || got[0] != "2" {
+		t.Errorf("ca_id = %v, want [2]", got)
+	}
+}
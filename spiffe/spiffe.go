@@ -0,0 +1,279 @@
+// Package spiffe issues and checks SPIFFE workload identities with TinyCert,
+// for experimenting with SPIFFE without running SPIRE. A TinyCert CA plays
+// the part of a trust domain's signing authority: certificates issued with a
+// SPIFFE ID as their URI SAN are X.509-SVIDs, and the CA certificates are
+// published to workloads as a SPIFFE trust bundle.
+//
+// TinyCert does not enforce the SVID rules itself, so ValidateSVID checks
+// issued certificates against them.
+package spiffe
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+var (
+	ErrInvalidID   = errors.New("spiffe: invalid SPIFFE ID")
+	ErrInvalidSVID = errors.New("spiffe: invalid X.509-SVID")
+)
+
+// ID is a SPIFFE ID, spiffe://TrustDomain/Path.
+type ID struct {
+	TrustDomain string
+	// Path is empty or starts with a slash.
+	Path string
+}
+
+func (id ID) String() string {
+	return "spiffe://" + id.TrustDomain + id.Path
+}
+
+// NewID returns the ID of the path segments in the trust domain, e.g.
+// NewID("example.org", "ns", "prod", "sa", "web").
+func NewID(trustDomain string, segments ...string) (ID, error) {
+	path := ""
+	if len(segments) > 0 {
+		path = "/" + strings.Join(segments, "/")
+	}
+	return ParseID("spiffe://" + trustDomain + path)
+}
+
+// ParseID parses and validates a SPIFFE ID as the SPIFFE-ID specification
+// defines it: a lower-case trust domain of letters, digits, dots, dashes and
+// underscores, no port, user info, query or fragment, and a path of
+// non-empty segments other than "." and ".." made of letters, digits, dots,
+// dashes and underscores.
+func ParseID(s string) (id ID, err error) {
+	rest, ok := strings.CutPrefix(s, "spiffe://")
+	if !ok {
+		return id, fmt.Errorf("%w: %q does not start with spiffe://", ErrInvalidID, s)
+	}
+	id.TrustDomain, id.Path, _ = strings.Cut(rest, "/")
+	if id.Path != "" || strings.HasSuffix(rest, "/") {
+		id.Path = "/" + id.Path
+	}
+
+	if id.TrustDomain == "" {
+		return id, fmt.Errorf("%w: %q has no trust domain", ErrInvalidID, s)
+	}
+	for _, r := range id.TrustDomain {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return id, fmt.Errorf("%w: %q: trust domain has %q", ErrInvalidID, s, r)
+		}
+	}
+	if id.Path == "" {
+		return
+	}
+	for _, segment := range strings.Split(id.Path[1:], "/") {
+		switch segment {
+		case "":
+			return id, fmt.Errorf("%w: %q: empty path segment", ErrInvalidID, s)
+		case ".", "..":
+			return id, fmt.Errorf("%w: %q: relative path segment", ErrInvalidID, s)
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return id, fmt.Errorf("%w: %q: path has %q", ErrInvalidID, s, r)
+			}
+		}
+	}
+	return
+}
+
+// Issue creates a certificate under the CA with id as its only URI SAN,
+// making it an X.509-SVID. TinyCert requires a common name; SVIDs do not use
+// it, so any label will do.
+func Issue(ctx context.Context, session *tinycert.Session, caId int64, id ID, commonName string) (certId *int64, err error) {
+	return tinycert.NewCertificate(session).CreateCtx(ctx, caId, tinycert.CertRequest{
+		CommonName: commonName,
+		Alt:        []tinycert.SAN{{URI: id.String()}},
+	})
+}
+
+// SVID is an X.509-SVID with its key.
+type SVID struct {
+	ID ID
+	// Certificates is the leaf followed by its CA.
+	Certificates []*x509.Certificate
+	PrivateKey   crypto.PrivateKey
+}
+
+// FetchSVID fetches a certificate issued by Issue and checks it with
+// ValidateSVID.
+func FetchSVID(ctx context.Context, session *tinycert.Session, certId int64) (svid *SVID, err error) {
+	cert := tinycert.NewCertificate(session)
+	leaf, key, err := cert.GetParsedCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+	id, err := ValidateSVID(leaf)
+	if err != nil {
+		return
+	}
+	chainPEM, err := cert.GetChainPEMCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+	chain, err := parseCertificates([]byte(*chainPEM))
+	if err != nil {
+		return
+	}
+	return &SVID{ID: id, Certificates: chain, PrivateKey: key}, nil
+}
+
+// ValidateSVID checks a leaf certificate against the X.509-SVID
+// specification and returns its SPIFFE ID: it must have exactly one URI SAN,
+// a valid SPIFFE ID with a path; it must not be a CA; and its key usage must
+// include digital signature but not certificate or CRL signing.
+func ValidateSVID(cert *x509.Certificate) (id ID, err error) {
+	var problems []string
+	switch len(cert.URIs) {
+	case 0:
+		problems = append(problems, "no URI SAN")
+	case 1:
+		if id, err = ParseID(cert.URIs[0].String()); err != nil {
+			problems = append(problems, err.Error())
+		} else if id.Path == "" {
+			problems = append(problems, "SPIFFE ID of a leaf has no path")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%d URI SANs, want one", len(cert.URIs)))
+	}
+	if cert.IsCA {
+		problems = append(problems, "certificate is a CA")
+	}
+	if cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		problems = append(problems, "key usage lacks digital signature")
+	}
+	if cert.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+		problems = append(problems, "key usage includes certificate or CRL signing")
+	}
+
+	if len(problems) > 0 {
+		return id, fmt.Errorf("%w: %s", ErrInvalidSVID, strings.Join(problems, "; "))
+	}
+	return id, nil
+}
+
+// Bundle is a SPIFFE trust bundle: the X.509 authorities of a trust domain
+// as a JWK Set, as served by a SPIFFE bundle endpoint.
+type Bundle struct {
+	Keys []JWK `json:"keys"`
+	// Sequence is increased whenever the bundle changes.
+	Sequence uint64 `json:"spiffe_sequence,omitempty"`
+	// RefreshHint is how often, in seconds, consumers should refresh it.
+	RefreshHint int64 `json:"spiffe_refresh_hint,omitempty"`
+}
+
+// JWK is an X.509 authority in a Bundle.
+type JWK struct {
+	Use string `json:"use"`
+	Kty string `json:"kty"`
+	// Crv, X and Y describe EC keys; N and E RSA keys.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	// X5c holds the base64 DER of the CA certificate.
+	X5c []string `json:"x5c"`
+}
+
+// NewBundle returns a bundle of the CA certificates.
+func NewBundle(cas []*x509.Certificate, sequence uint64, refreshHint time.Duration) (bundle *Bundle, err error) {
+	bundle = &Bundle{Keys: []JWK{}, Sequence: sequence, RefreshHint: int64(refreshHint / time.Second)}
+	for _, ca := range cas {
+		key := JWK{Use: "x509-svid", X5c: []string{base64.StdEncoding.EncodeToString(ca.Raw)}}
+		switch pub := ca.PublicKey.(type) {
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			key.Kty, key.Crv = "EC", pub.Curve.Params().Name
+			key.X, key.Y = encodeInt(pub.X, size), encodeInt(pub.Y, size)
+		case *rsa.PublicKey:
+			key.Kty = "RSA"
+			key.N, key.E = encodeInt(pub.N, 0), encodeInt(big.NewInt(int64(pub.E)), 0)
+		default:
+			return nil, fmt.Errorf("spiffe: unsupported CA key %T", ca.PublicKey)
+		}
+		bundle.Keys = append(bundle.Keys, key)
+	}
+	return
+}
+
+// FetchBundle returns a bundle of the certificates of the CAs.
+func FetchBundle(ctx context.Context, session *tinycert.Session, caIds ...int64) (bundle *Bundle, err error) {
+	ca := tinycert.NewCA(session)
+	var cas []*x509.Certificate
+	for _, caId := range caIds {
+		var desc *tinycert.CADescription
+		if desc, err = ca.DescribeCtx(ctx, caId); err != nil {
+			return
+		}
+		cas = append(cas, desc.Certificate)
+	}
+	return NewBundle(cas, 0, 0)
+}
+
+// Marshal returns the bundle in the SPIFFE trust bundle JSON format.
+func (b *Bundle) Marshal() ([]byte, error) {
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// Authorities returns the CA certificates of the bundle.
+func (b *Bundle) Authorities() (cas []*x509.Certificate, err error) {
+	for _, key := range b.Keys {
+		if key.Use != "x509-svid" || len(key.X5c) == 0 {
+			continue
+		}
+		var der []byte
+		if der, err = base64.StdEncoding.DecodeString(key.X5c[0]); err != nil {
+			return
+		}
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(der); err != nil {
+			return
+		}
+		cas = append(cas, cert)
+	}
+	return
+}
+
+func parseCertificates(data []byte) (certs []*x509.Certificate, err error) {
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+			return
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// encodeInt encodes n as base64url, padded with zeros to size bytes.
+func encodeInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
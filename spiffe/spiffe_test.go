@@ -0,0 +1,152 @@
+package spiffe_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+	"github.com/srohatgi/tinycert/spiffe"
+)
+
+func TestParseID(t *testing.T) {
+	for s, want := range map[string]spiffe.ID{
+		"spiffe://example.org":             {TrustDomain: "example.org"},
+		"spiffe://example.org/ns/prod/web": {TrustDomain: "example.org", Path: "/ns/prod/web"},
+		"spiffe://my_domain-1/Web.v2":      {TrustDomain: "my_domain-1", Path: "/Web.v2"},
+	} {
+		id, err := spiffe.ParseID(s)
+		if err != nil || id != want || id.String() != s {
+			t.Errorf("ParseID(%q) = %+v, %v", s, id, err)
+		}
+	}
+
+	for _, s := range []string{
+		"https://example.org/web",
+		"spiffe://",
+		"spiffe:///web",
+		"spiffe://Example.org/web",
+		"spiffe://example.org:8443/web",
+		"spiffe://user@example.org/web",
+		"spiffe://example.org/",
+		"spiffe://example.org//web",
+		"spiffe://example.org/../web",
+		"spiffe://example.org/web?x=1",
+		"spiffe://example.org/web#frag",
+	} {
+		if _, err := spiffe.ParseID(s); !errors.Is(err, spiffe.ErrInvalidID) {
+			t.Errorf("ParseID(%q) err = %v, want ErrInvalidID", s, err)
+		}
+	}
+
+	if id, err := spiffe.NewID("example.org", "ns", "prod"); err != nil || id.String() != "spiffe://example.org/ns/prod" {
+		t.Errorf("NewID = %v, %v", id, err)
+	}
+}
+
+func newCert(t *testing.T, tmpl *x509.Certificate) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(1)
+	tmpl.NotBefore, tmpl.NotAfter = time.Now(), time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestValidateSVID(t *testing.T) {
+	web, _ := url.Parse("spiffe://example.org/web")
+	domain, _ := url.Parse("spiffe://example.org")
+
+	leaf := newCert(t, &x509.Certificate{URIs: []*url.URL{web}, KeyUsage: x509.KeyUsageDigitalSignature})
+	if id, err := spiffe.ValidateSVID(leaf); err != nil || id.Path != "/web" {
+		t.Errorf("ValidateSVID = %v, %v", id, err)
+	}
+
+	for name, tmpl := range map[string]*x509.Certificate{
+		"no URI SAN":         {KeyUsage: x509.KeyUsageDigitalSignature},
+		"want one":           {URIs: []*url.URL{web, web}, KeyUsage: x509.KeyUsageDigitalSignature},
+		"has no path":        {URIs: []*url.URL{domain}, KeyUsage: x509.KeyUsageDigitalSignature},
+		"is a CA":            {URIs: []*url.URL{web}, KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign, IsCA: true, BasicConstraintsValid: true},
+		"digital signature":  {URIs: []*url.URL{web}, KeyUsage: x509.KeyUsageKeyEncipherment},
+		"certificate or CRL": {URIs: []*url.URL{web}, KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageCRLSign},
+	} {
+		_, err := spiffe.ValidateSVID(newCert(t, tmpl))
+		if !errors.Is(err, spiffe.ErrInvalidSVID) || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	ca := newCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign})
+	bundle, err := spiffe.NewBundle([]*x509.Certificate{ca}, 3, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bundle.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["spiffe_sequence"] != 3.0 || decoded["spiffe_refresh_hint"] != 300.0 {
+		t.Errorf("bundle = %s", data)
+	}
+	key := decoded["keys"].([]interface{})[0].(map[string]interface{})
+	if key["use"] != "x509-svid" || key["kty"] != "EC" || key["crv"] != "P-256" || len(key["x"].(string)) != 43 {
+		t.Errorf("key = %v", key)
+	}
+
+	var parsed spiffe.Bundle
+	json.Unmarshal(data, &parsed)
+	cas, err := parsed.Authorities()
+	if err != nil || len(cas) != 1 || !cas[0].Equal(ca) {
+		t.Errorf("authorities = %v, %v", cas, err)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		json.NewEncoder(w).Encode(map[string]int64{"cert_id": 7})
+	}))
+	defer srv.Close()
+	sess := tinycert.NewSession().WithEmail("user@example.com").WithPassphrase("secret").WithApiKey("apikey").
+		WithBaseURL(srv.URL + "/api").Resume("token")
+
+	id, _ := spiffe.NewID("example.org", "web")
+	certId, err := spiffe.Issue(context.Background(), sess, 1, id, "web")
+	if err != nil || *certId != 7 {
+		t.Fatal(certId, err)
+	}
+	if got := form.Get("SANs[0][URI]"); got != "spiffe://example.org/web" {
+		t.Errorf("URI SAN = %q", got)
+	}
+}
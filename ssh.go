@@ -0,0 +1,96 @@
+package tinycert
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var ErrSSHUnsupported = errors.New("tinycert: key cannot be used with SSH")
+
+// SSHKeyPair is the key pair of a certificate in the forms OpenSSH uses, so
+// one issuance flow can provide both the TLS and the SSH identity of a host.
+type SSHKeyPair struct {
+	// PrivateKey is the key in OpenSSH form, for a file such as
+	// /etc/ssh/ssh_host_ecdsa_key.
+	PrivateKey SecureString
+	// AuthorizedKey is the public key as a line of a .pub or
+	// authorized_keys file, with the common name as comment.
+	AuthorizedKey string
+	// KnownHosts is a known_hosts line for the DNS names and IP addresses of
+	// the certificate, to pin the host key on clients. It is empty if the
+	// certificate names no hosts.
+	KnownHosts string
+	// Fingerprint is the SHA256 fingerprint ssh-keygen -l prints.
+	Fingerprint string
+	// Warnings say what of the certificate does not carry over to SSH.
+	Warnings []string
+}
+
+func (c *Certificate) AsSSHKeyPair(certId int64) (pair *SSHKeyPair, err error) {
+	return c.AsSSHKeyPairCtx(context.Background(), certId)
+}
+
+// AsSSHKeyPairCtx fetches the certificate and its key and converts them with
+// SSHKeyPairFrom.
+func (c *Certificate) AsSSHKeyPairCtx(ctx context.Context, certId int64) (pair *SSHKeyPair, err error) {
+	cert, key, err := c.GetParsedCtx(ctx, certId)
+	if err != nil {
+		return
+	}
+	return SSHKeyPairFrom(cert, key)
+}
+
+// SSHKeyPairFrom converts a certificate's key pair to SSH. Keys SSH has no
+// form for, such as ECDSA keys on curves other than P-256, P-384 and P-521,
+// fail with ErrSSHUnsupported. What SSH keys cannot express is reported in
+// Warnings: they do not expire, so the host keeps the key past the
+// certificate's NotAfter, and email and URI names are dropped.
+func SSHKeyPairFrom(cert *x509.Certificate, key crypto.PrivateKey) (pair *SSHKeyPair, err error) {
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSHUnsupported, err)
+	}
+	public := signer.PublicKey()
+	comment := cert.Subject.CommonName
+	block, err := ssh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSHUnsupported, err)
+	}
+
+	pair = &SSHKeyPair{
+		PrivateKey:    NewSecureString(string(pem.EncodeToMemory(block))),
+		AuthorizedKey: strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(public)), "\n"),
+		Fingerprint:   ssh.FingerprintSHA256(public),
+	}
+	if comment != "" {
+		pair.AuthorizedKey += " " + comment
+	}
+
+	var hosts []string
+	for _, name := range cert.DNSNames {
+		hosts = append(hosts, knownhosts.Normalize(name))
+	}
+	for _, ip := range cert.IPAddresses {
+		hosts = append(hosts, knownhosts.Normalize(ip.String()))
+	}
+	if len(hosts) > 0 {
+		pair.KnownHosts = knownhosts.Line(hosts, public)
+	} else {
+		pair.Warnings = append(pair.Warnings, "certificate has no DNS or IP names, so there is no known_hosts line")
+	}
+
+	pair.Warnings = append(pair.Warnings, fmt.Sprintf("SSH keys do not expire; the certificate expires %s", cert.NotAfter.UTC().Format(time.DateOnly)))
+	if len(cert.EmailAddresses) > 0 || len(cert.URIs) > 0 {
+		pair.Warnings = append(pair.Warnings, "email and URI names have no SSH equivalent and are dropped")
+	}
+	return
+}
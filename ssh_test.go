@@ -0,0 +1,69 @@
+package tinycert_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestCertificate_AsSSHKeyPair(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	pair, err := tinycert.NewCertificate(sess).AsSSHKeyPair(certId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(pair.PrivateKey.Reveal()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(pair.AuthorizedKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(public.Marshal(), signer.PublicKey().Marshal()) || comment != "www.example.com" {
+		t.Errorf("authorized key %q does not match the private key", pair.AuthorizedKey)
+	}
+	if pair.Fingerprint != ssh.FingerprintSHA256(public) {
+		t.Errorf("fingerprint = %s", pair.Fingerprint)
+	}
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(path, []byte(pair.KnownHosts+"\n"), 0600)
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = callback("www.example.com:22", &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}, public); err != nil {
+		t.Errorf("known_hosts line %q does not pin the host: %v", pair.KnownHosts, err)
+	}
+	if len(pair.Warnings) != 1 || !strings.Contains(pair.Warnings[0], "do not expire") {
+		t.Errorf("warnings = %q", pair.Warnings)
+	}
+
+	// A certificate without host names has no known_hosts line.
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if pair, err = tinycert.SSHKeyPairFrom(&x509.Certificate{}, key); err != nil || pair.KnownHosts != "" || len(pair.Warnings) != 2 {
+		t.Errorf("pair = %+v, %v", pair, err)
+	}
+
+	p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if _, err = tinycert.SSHKeyPairFrom(&x509.Certificate{}, p224); !errors.Is(err, tinycert.ErrSSHUnsupported) {
+		t.Errorf("err = %v, want ErrSSHUnsupported", err)
+	}
+}
@@ -0,0 +1,146 @@
+package tinycert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("tinycert: invalid certificate status")
+	ErrInvalidTransition = errors.New("tinycert: invalid certificate status transition")
+)
+
+// CertificateStatus is a bitmask; statuses can be ORed together, e.g.
+// Good|Expired, to list certificates in any of them.
+type CertificateStatus int
+
+const (
+	Expired CertificateStatus = 1 << iota
+	Good
+	Revoked
+	Hold
+)
+
+const AnyStatus = Expired | Good | Revoked | Hold
+
+// UnknownStatus is the zero status. Statuses the API reports that this
+// package does not know about, and missing ones, decode to it.
+const UnknownStatus CertificateStatus = 0
+
+var allStatuses = []CertificateStatus{Expired, Good, Revoked, Hold}
+
+func CombineStatuses(statuses ...CertificateStatus) (combined CertificateStatus) {
+	for _, cs := range statuses {
+		combined |= cs
+	}
+	return
+}
+
+func (cs CertificateStatus) Has(status CertificateStatus) bool {
+	return status != 0 && cs&status == status
+}
+
+// Statuses splits a combined status into its individual flags.
+func (cs CertificateStatus) Statuses() (statuses []CertificateStatus) {
+	for _, status := range allStatuses {
+		if cs.Has(status) {
+			statuses = append(statuses, status)
+		}
+	}
+	return
+}
+
+// transitions lists the statuses Certificate.Status can move a certificate
+// to from each status. Revocation is final, and expired certificates cannot
+// be changed.
+var transitions = map[CertificateStatus]CertificateStatus{
+	Good: Hold | Revoked,
+	Hold: Good | Revoked,
+}
+
+// CanTransitionTo reports whether a certificate with status cs can be given
+// status to: good and held certificates can be held, released or revoked.
+// Setting the status a certificate already has is not a transition.
+func (cs CertificateStatus) CanTransitionTo(to CertificateStatus) bool {
+	return transitions[cs].Has(to) && to.toString() != ""
+}
+
+func (cs CertificateStatus) String() string {
+	if cs == 0 {
+		return "none"
+	}
+
+	names := []string{}
+	for _, status := range cs.Statuses() {
+		names = append(names, status.toString())
+	}
+	if unknown := cs &^ AnyStatus; unknown != 0 {
+		names = append(names, fmt.Sprintf("0x%x", int(unknown)))
+	}
+	return strings.Join(names, "|")
+}
+
+func (cs CertificateStatus) toString() string {
+	switch cs {
+	case Expired:
+		return "expired"
+	case Good:
+		return "good"
+	case Hold:
+		return "hold"
+	case Revoked:
+		return "revoked"
+	}
+	return ""
+}
+
+// ParseCertificateStatus maps a status name as the API reports it, such as
+// "good", to its CertificateStatus. Names may be joined with "|", as String
+// writes them, to parse a combined status.
+func ParseCertificateStatus(value string) (status CertificateStatus, err error) {
+	for _, name := range strings.Split(value, "|") {
+		cs, ok := parseCertificateStatus(strings.TrimSpace(name))
+		if !ok {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidStatus, value)
+		}
+		status |= cs
+	}
+	return
+}
+
+func parseCertificateStatus(value string) (CertificateStatus, bool) {
+	for _, cs := range allStatuses {
+		if strings.EqualFold(cs.toString(), value) {
+			return cs, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalJSON writes the status as the API names it, e.g. "good", and
+// UnknownStatus as "".
+func (cs CertificateStatus) MarshalJSON() ([]byte, error) {
+	if cs == UnknownStatus {
+		return []byte(`""`), nil
+	}
+	if cs&^AnyStatus != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStatus, cs)
+	}
+	return json.Marshal(cs.String())
+}
+
+// UnmarshalJSON decodes a status name. A name this package does not know, an
+// empty one or null decode to UnknownStatus, so that a new status on the
+// server does not fail whole listings.
+func (cs *CertificateStatus) UnmarshalJSON(data []byte) (err error) {
+	var name string
+	if err = json.Unmarshal(data, &name); err != nil {
+		return
+	}
+	if *cs, err = ParseCertificateStatus(name); err != nil {
+		*cs = UnknownStatus
+	}
+	return nil
+}
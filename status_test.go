@@ -0,0 +1,167 @@
+package tinycert_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificateStatus_String(t *testing.T) {
+	tests := []struct {
+		status tinycert.CertificateStatus
+		want   string
+	}{
+		{0, "none"},
+		{tinycert.Good, "good"},
+		{tinycert.Good | tinycert.Expired, "expired|good"},
+		{tinycert.AnyStatus, "expired|good|revoked|hold"},
+		{tinycert.CombineStatuses(tinycert.Hold, tinycert.Revoked), "revoked|hold"},
+		{tinycert.Good | 32, "good|0x20"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.want {
+			t.Errorf("%d.String() = %q, want %q", int(tt.status), got, tt.want)
+		}
+	}
+}
+
+func TestCertificate_ListCombinedStatus(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	caId, certId := newCAAndCert(t, sess)
+
+	cert := tinycert.NewCertificate(sess)
+	if _, err := cert.Create(caId, tinycert.CertRequest{CommonName: "other.example.com", OrgName: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Status(certId, tinycert.Revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	for status, want := range map[tinycert.CertificateStatus]int{
+		tinycert.Good:                    1,
+		tinycert.Revoked:                 1,
+		tinycert.Good | tinycert.Revoked: 2,
+		tinycert.Hold | tinycert.Expired: 0,
+	} {
+		items, err := cert.List(caId, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != want {
+			t.Errorf("List(%s) returned %d items, want %d", status, len(items), want)
+		}
+	}
+
+	if _, err := cert.List(caId, 0); !errors.Is(err, tinycert.ErrInvalidStatus) {
+		t.Error("expected ErrInvalidStatus for empty mask, got", err)
+	}
+	if err := cert.Status(certId, tinycert.Good|tinycert.Hold); !errors.Is(err, tinycert.ErrInvalidStatus) {
+		t.Error("expected ErrInvalidStatus for combined status update, got", err)
+	}
+}
+
+func TestParseCertificateStatus(t *testing.T) {
+	tests := []struct {
+		value string
+		want  tinycert.CertificateStatus
+		ok    bool
+	}{
+		{"good", tinycert.Good, true},
+		{"Revoked", tinycert.Revoked, true},
+		{"good|hold", tinycert.Good | tinycert.Hold, true},
+		{"", 0, false},
+		{"valid", 0, false},
+		{"good|", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := tinycert.ParseCertificateStatus(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseCertificateStatus(%q) = %v, %v", tt.value, got, err)
+		}
+		if err != nil && !errors.Is(err, tinycert.ErrInvalidStatus) {
+			t.Errorf("ParseCertificateStatus(%q) error %v is not ErrInvalidStatus", tt.value, err)
+		}
+	}
+}
+
+func TestCertificateStatus_JSON(t *testing.T) {
+	var item tinycert.CertificateListItem
+	if err := json.Unmarshal([]byte(`{"id":1,"status":"hold"}`), &item); err != nil || item.Status != tinycert.Hold {
+		t.Errorf("Unmarshal() = %v, %v", item.Status, err)
+	}
+	for _, status := range []string{`"pending"`, `""`, `null`} {
+		item := tinycert.CertificateListItem{Status: tinycert.Good}
+		if err := json.Unmarshal([]byte(`{"id":1,"status":`+status+`}`), &item); err != nil || item.Status != tinycert.UnknownStatus {
+			t.Errorf("Unmarshal(%s) = %v, %v, want UnknownStatus", status, item.Status, err)
+		}
+	}
+
+	data, err := json.Marshal(tinycert.Revoked)
+	if err != nil || string(data) != `"revoked"` {
+		t.Errorf("Marshal(Revoked) = %s, %v", data, err)
+	}
+	data, err = json.Marshal(tinycert.CertificateInfo{})
+	if err != nil || !strings.Contains(string(data), `"status":""`) {
+		t.Errorf("Marshal(CertificateInfo{}) = %s, %v", data, err)
+	}
+	var info tinycert.CertificateInfo
+	if err := json.Unmarshal(data, &info); err != nil || info.Status != tinycert.UnknownStatus {
+		t.Errorf("round trip of the zero status = %v, %v", info.Status, err)
+	}
+}
+
+func TestCertificateStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to tinycert.CertificateStatus
+		want     bool
+	}{
+		{tinycert.Good, tinycert.Hold, true},
+		{tinycert.Good, tinycert.Revoked, true},
+		{tinycert.Hold, tinycert.Good, true},
+		{tinycert.Hold, tinycert.Revoked, true},
+		{tinycert.Good, tinycert.Good, false},
+		{tinycert.Revoked, tinycert.Good, false},
+		{tinycert.Revoked, tinycert.Hold, false},
+		{tinycert.Expired, tinycert.Revoked, false},
+		{tinycert.Good, tinycert.Expired, false},
+		{tinycert.Good, tinycert.Hold | tinycert.Revoked, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCertificate_StatusTransition(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+	cert := tinycert.NewCertificate(sess)
+
+	if err := cert.Release(certId); !errors.Is(err, tinycert.ErrInvalidTransition) {
+		t.Fatal("expected releasing a good certificate to fail with ErrInvalidTransition, got", err)
+	}
+	if got := fs.callCount("cert/status"); got != 0 {
+		t.Errorf("cert/status called %d times for an invalid transition", got)
+	}
+
+	if err := cert.Revoke(certId); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Hold(certId); !errors.Is(err, tinycert.ErrInvalidTransition) {
+		t.Fatal("expected holding a revoked certificate to fail with ErrInvalidTransition, got", err)
+	}
+
+	// Without the check the call goes to the API as it is.
+	if err := cert.WithTransitionCheck(false).Hold(certId); errors.Is(err, tinycert.ErrInvalidTransition) {
+		t.Error("WithTransitionCheck(false) still checked the transition")
+	}
+	if got := fs.callCount("cert/status"); got != 2 {
+		t.Errorf("cert/status called %d times, want 2", got)
+	}
+}
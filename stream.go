@@ -0,0 +1,279 @@
+package tinycert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxResponseSize bounds how much of a response body a session reads.
+const DefaultMaxResponseSize = 64 << 20
+
+// WithMaxResponseSize makes calls fail with ErrResponseTooLarge once a
+// response body exceeds n bytes, bounding the memory a call can take; n <= 0
+// removes the limit. Streamed downloads are bounded by it too.
+func (s *Session) WithMaxResponseSize(n int64) *Session {
+	if n <= 0 {
+		n = -1
+	}
+	s.maxResponse = n
+	return s
+}
+
+func (s *Session) maxResponseSize() int64 {
+	if s.maxResponse == 0 {
+		return DefaultMaxResponseSize
+	}
+	return s.maxResponse
+}
+
+// limitedReader reads at most n bytes from r and fails with
+// ErrResponseTooLarge if r has more; a negative n reads without limit.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return l.r.Read(p)
+	}
+	if l.n == 0 {
+		// Probe for one more byte to tell the end of the body from overflow.
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			err = ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// streamResponse, passed as the response of makeCall, hands the body of a
+// successful response to consume instead of decoding it.
+type streamResponse struct {
+	consume func(body io.Reader) error
+}
+
+// streamError marks a failure while consuming a streamed body. Part of the
+// body may already have been written out, so it is never retried.
+type streamError struct {
+	err error
+}
+
+func (e *streamError) Error() string { return e.err.Error() }
+func (e *streamError) Unwrap() error { return e.err }
+
+// copyBase64Field finds the member named field of the JSON object read from
+// r and writes its base64 decoded string value to w. Other members are
+// skipped; a "code" and "text" error envelope in place of the field is
+// returned as an APIError.
+func copyBase64Field(w io.Writer, r io.Reader, field string) (n int64, err error) {
+	br := bufio.NewReader(r)
+	if err = expectByte(br, '{'); err != nil {
+		return
+	}
+
+	members := map[string]json.RawMessage{}
+	for {
+		var c byte
+		if c, err = nextByte(br); err != nil {
+			return
+		}
+		switch c {
+		case '}':
+			body, _ := json.Marshal(members)
+			if apiErr := errorEnvelope(http.StatusOK, body); apiErr != nil {
+				return 0, apiErr
+			}
+			return 0, fmt.Errorf("tinycert: response has no %s", field)
+		case ',':
+			continue
+		case '"':
+		default:
+			return 0, fmt.Errorf("tinycert: unexpected %q in response", c)
+		}
+
+		var key []byte
+		if key, err = readString(br); err != nil {
+			return
+		}
+		var name string
+		if err = json.Unmarshal(key, &name); err != nil {
+			return
+		}
+		if err = expectByte(br, ':'); err != nil {
+			return
+		}
+
+		if name == field {
+			if err = expectByte(br, '"'); err != nil {
+				return
+			}
+			return io.Copy(w, base64.NewDecoder(base64.StdEncoding, &jsonStringReader{r: br}))
+		}
+		if members[name], err = readValue(br); err != nil {
+			return
+		}
+	}
+}
+
+// readByte reads one byte of a value; the body ending there is an error.
+func readByte(br *bufio.Reader) (byte, error) {
+	c, err := br.ReadByte()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return c, err
+}
+
+// nextByte returns the next byte that is not JSON whitespace.
+func nextByte(br *bufio.Reader) (byte, error) {
+	for {
+		c, err := readByte(br)
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return c, nil
+		}
+	}
+}
+
+func expectByte(br *bufio.Reader, want byte) error {
+	c, err := nextByte(br)
+	if err != nil {
+		return err
+	}
+	if c != want {
+		return fmt.Errorf("tinycert: expected %q in response, found %q", want, c)
+	}
+	return nil
+}
+
+// readString reads the rest of a JSON string whose opening quote has been
+// consumed, returning it quoted and still escaped.
+func readString(br *bufio.Reader) ([]byte, error) {
+	raw := []byte{'"'}
+	for escaped := false; ; {
+		c, err := readByte(br)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, c)
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return raw, nil
+		}
+	}
+}
+
+// readValue reads one JSON value and returns it raw.
+func readValue(br *bufio.Reader) ([]byte, error) {
+	c, err := nextByte(br)
+	if err != nil {
+		return nil, err
+	}
+	if c == '"' {
+		return readString(br)
+	}
+
+	var raw bytes.Buffer
+	raw.WriteByte(c)
+	depth := 0
+	if c == '{' || c == '[' {
+		depth = 1
+	}
+	for {
+		if depth == 0 {
+			// A scalar ends at the next delimiter, which belongs to the object.
+			next, err := br.Peek(1)
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, err
+			}
+			if bytes.ContainsAny(next, ",} \t\r\n") {
+				return raw.Bytes(), nil
+			}
+		}
+		c, err := readByte(br)
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '"':
+			s, err := readString(br)
+			if err != nil {
+				return nil, err
+			}
+			raw.Write(s)
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+		raw.WriteByte(c)
+		if depth == 0 && (c == '}' || c == ']') {
+			return raw.Bytes(), nil
+		}
+	}
+}
+
+// jsonStringReader reads the contents of a JSON string whose opening quote
+// has been consumed, up to the closing quote. It understands the escapes
+// that can appear in base64 text: "\/", which some servers emit for "/", and
+// escaped line breaks, which base64 decoding ignores.
+type jsonStringReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+func (j *jsonStringReader) Read(p []byte) (n int, err error) {
+	for n < len(p) && !j.done {
+		var c byte
+		if c, err = readByte(j.r); err != nil {
+			return
+		}
+		switch c {
+		case '"':
+			j.done = true
+			continue
+		case '\\':
+			if c, err = readByte(j.r); err != nil {
+				return
+			}
+			switch c {
+			case '/':
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			default:
+				return n, fmt.Errorf("tinycert: unexpected escape \\%c in base64 value", c)
+			}
+		}
+		p[n] = c
+		n++
+	}
+	if j.done && n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
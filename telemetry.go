@@ -0,0 +1,89 @@
+package tinycert
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/srohatgi/tinycert"
+
+type callMetrics struct {
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// WithTracerProvider records a span for every API call, named after the
+// endpoint, using a tracer from tp.
+func (s *Session) WithTracerProvider(tp trace.TracerProvider) *Session {
+	s.tracer = tp.Tracer(instrumentationName)
+	return s
+}
+
+// WithMeterProvider records request counts and durations for every API call
+// using a meter from mp.
+func (s *Session) WithMeterProvider(mp metric.MeterProvider) *Session {
+	meter := mp.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("tinycert.client.requests",
+		metric.WithDescription("Number of TinyCert API calls."))
+	if err != nil {
+		s.logger.Log(LevelWarn, "unable to create requests counter: %v", err)
+		return s
+	}
+	duration, err := meter.Float64Histogram("tinycert.client.duration",
+		metric.WithDescription("Duration of TinyCert API calls."), metric.WithUnit("s"))
+	if err != nil {
+		s.logger.Log(LevelWarn, "unable to create duration histogram: %v", err)
+		return s
+	}
+
+	s.metrics = &callMetrics{requests: requests, duration: duration}
+	return s
+}
+
+// instrument starts the span and metrics for a call; the returned function
+// must be called with the call's result, after any retries and reconnects.
+func (s *Session) instrument(ctx context.Context, api string, list url.Values) (context.Context, func(error)) {
+	if s.tracer == nil && s.metrics == nil && len(s.observers) == 0 {
+		return ctx, func(error) {}
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("tinycert.endpoint", api)}
+	for _, name := range []string{"ca_id", "cert_id"} {
+		if id, err := strconv.ParseInt(list.Get(name), 10, 64); err == nil {
+			attrs = append(attrs, attribute.Int64("tinycert."+name, id))
+		}
+	}
+
+	var span trace.Span
+	if s.tracer != nil {
+		ctx, span = s.tracer.Start(ctx, api, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	}
+	start := time.Now()
+
+	return ctx, func(err error) {
+		if span != nil {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+
+		if s.metrics != nil {
+			opt := metric.WithAttributes(attribute.String("tinycert.endpoint", api), attribute.Bool("error", err != nil))
+			s.metrics.requests.Add(ctx, 1, opt)
+			s.metrics.duration.Record(ctx, time.Since(start).Seconds(), opt)
+		}
+		for _, observe := range s.observers {
+			observe(api, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tinycert_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_WithTracerProvider(t *testing.T) {
+	fs := newFakeServer(t)
+	recorder := tracetest.NewSpanRecorder()
+	sess := fs.session().WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	caId, _ := newCAAndCert(t, sess)
+
+	if _, err := tinycert.NewCA(sess).Details(caId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tinycert.NewCA(sess).Details(42); !errors.Is(err, tinycert.ErrNotFound) {
+		t.Fatal("expected ErrNotFound, got", err)
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+		if span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("span %s has kind %s", span.Name(), span.SpanKind())
+		}
+	}
+	want := []string{"connect", "ca/new", "cert/new", "ca/details", "ca/details"}
+	if len(names) != len(want) {
+		t.Fatalf("spans = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("spans = %v, want %v", names, want)
+		}
+	}
+
+	ok, failed := spans[3], spans[4]
+	if !hasAttribute(ok.Attributes(), attribute.String("tinycert.endpoint", "ca/details")) || !hasAttribute(ok.Attributes(), attribute.Int64("tinycert.ca_id", caId)) {
+		t.Errorf("unexpected attributes %v", ok.Attributes())
+	}
+	if ok.Status().Code != codes.Unset {
+		t.Errorf("successful call has status %v", ok.Status())
+	}
+	if !hasAttribute(failed.Attributes(), attribute.Int64("tinycert.ca_id", 42)) {
+		t.Errorf("unexpected attributes %v", failed.Attributes())
+	}
+	if failed.Status().Code != codes.Error || len(failed.Events()) != 1 || failed.Events()[0].Name != "exception" {
+		t.Errorf("failed call has status %v and events %v", failed.Status(), failed.Events())
+	}
+}
+
+func TestSession_WithMeterProvider(t *testing.T) {
+	fs := newFakeServer(t)
+	reader := sdkmetric.NewManualReader()
+	sess := fs.session().WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ca := tinycert.NewCA(sess)
+	for i := 0; i < 2; i++ {
+		if _, err := ca.List(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ca.Details(42); err == nil {
+		t.Fatal("expected ca/details to fail")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	requests := map[string]int64{}
+	durations := map[string]uint64{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				if m.Name != "tinycert.client.requests" {
+					continue
+				}
+				for _, point := range data.DataPoints {
+					requests[pointKey(point.Attributes)] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				if m.Name != "tinycert.client.duration" || m.Unit != "s" {
+					continue
+				}
+				for _, point := range data.DataPoints {
+					durations[pointKey(point.Attributes)] += point.Count
+				}
+			}
+		}
+	}
+
+	want := map[string]int{"connect ok": 1, "ca/list ok": 2, "ca/details error": 1}
+	if len(requests) != len(want) || len(durations) != len(want) {
+		t.Errorf("requests = %v, durations = %v, want %v", requests, durations, want)
+	}
+	for key, n := range want {
+		if requests[key] != int64(n) || durations[key] != uint64(n) {
+			t.Errorf("%s: %d requests and %d durations, want %d", key, requests[key], durations[key], n)
+		}
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+// pointKey names a data point by its endpoint and error attributes, e.g.
+// "ca/list ok".
+func pointKey(set attribute.Set) string {
+	endpoint, _ := set.Value("tinycert.endpoint")
+	failed, _ := set.Value("error")
+	if failed.AsBool() {
+		return endpoint.AsString() + " error"
+	}
+	return endpoint.AsString() + " ok"
+}
@@ -0,0 +1,36 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+func (c *Certificate) AsTLSCertificate(certId int64) (tlsCert *tls.Certificate, err error) {
+	return c.AsTLSCertificateCtx(context.Background(), certId)
+}
+
+// AsTLSCertificateCtx fetches the certificate chain and decrypted private key
+// and assembles them into a tls.Certificate ready for use in a tls.Config.
+func (c *Certificate) AsTLSCertificateCtx(ctx context.Context, certId int64) (tlsCert *tls.Certificate, err error) {
+	chainPem, err := c.GetCtx(ctx, certId, Chain)
+	if err != nil {
+		return
+	}
+	keyPem, err := c.GetCtx(ctx, certId, KeyDecrypted)
+	if err != nil {
+		return
+	}
+
+	pair, err := tls.X509KeyPair([]byte(*chainPem), []byte(*keyPem))
+	if err != nil {
+		return
+	}
+	if pair.Leaf == nil {
+		if pair.Leaf, err = parseCertificatePEM([]byte(*chainPem)); err != nil {
+			return
+		}
+	}
+
+	tlsCert = &pair
+	return
+}
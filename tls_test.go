@@ -0,0 +1,31 @@
+package tinycert_test
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestCertificate_AsTLSCertificate(t *testing.T) {
+	fs := newFakeServer(t)
+	sess := fs.connectedSession()
+	_, certId := newCAAndCert(t, sess)
+
+	tlsCert, err := tinycert.NewCertificate(sess).AsTLSCertificate(certId)
+	if err != nil {
+		t.Fatal("unable to build tls certificate", err)
+	}
+
+	if len(tlsCert.Certificate) != 2 {
+		t.Errorf("chain length = %d, want 2", len(tlsCert.Certificate))
+	}
+	if tlsCert.Leaf == nil || tlsCert.Leaf.Subject.CommonName != "www.example.com" {
+		t.Errorf("unexpected leaf %v", tlsCert.Leaf)
+	}
+
+	hello := &tls.ClientHelloInfo{ServerName: "www.example.com", SupportedVersions: []uint16{tls.VersionTLS13}}
+	if err := hello.SupportsCertificate(tlsCert); err != nil {
+		t.Error("certificate not usable for tls", err)
+	}
+}
@@ -0,0 +1,197 @@
+package tinycert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidProxy = errors.New("tinycert: invalid proxy url")
+
+const (
+	// DefaultTimeout bounds a whole API call attempt, including reading the
+	// response.
+	DefaultTimeout = 30 * time.Second
+
+	defaultDialTimeout         = 10 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	// Keep enough idle connections for a batch running at a high parallelism.
+	// BenchmarkTransport finds concurrent calls over these pooled HTTP/1.1
+	// connections at least as fast as over one HTTP/2 connection; HTTP/2 is
+	// still attempted, as it saves the TLS handshakes of new connections.
+	defaultMaxIdleConnsPerHost = 32
+	// The session only talks to the API, so its idle connections are capped
+	// by defaultMaxIdleConnsPerHost; this leaves room for WithBaseURL
+	// switching hosts.
+	defaultMaxIdleConns = 100
+)
+
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout,
+		MaxIdleConns:          defaultMaxIdleConns,
+		MaxIdleConnsPerHost:   defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:       defaultIdleConnTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// WithTimeout bounds each API call attempt; zero means no timeout. Retries
+// each get the full timeout, so use a context deadline to bound the total.
+func (s *Session) WithTimeout(timeout time.Duration) *Session {
+	s.clt.Timeout = timeout
+	return s
+}
+
+// WithEndpointTimeout bounds each attempt of calls to api, such as
+// "cert/get", to timeout instead of the WithTimeout one, for endpoints slower
+// or faster than the rest; zero means no timeout.
+func (s *Session) WithEndpointTimeout(api string, timeout time.Duration) *Session {
+	if s.endpointTimeouts == nil {
+		s.endpointTimeouts = map[string]time.Duration{}
+	}
+	s.endpointTimeouts[api] = timeout
+	return s
+}
+
+type callTimeoutKey struct{}
+
+// WithCallTimeout bounds each attempt of the calls made with ctx to timeout,
+// overriding WithTimeout and WithEndpointTimeout; zero means no timeout:
+//
+//	ctx := tinycert.WithCallTimeout(ctx, 2*time.Minute)
+//	der, err := cert.GetPKCS12DERCtx(ctx, certId)
+//
+// Unlike a context deadline it applies to every retry afresh.
+func WithCallTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, timeout)
+}
+
+// client returns the HTTP client for a call to api: the session's, or a copy
+// of it with the call's own timeout.
+func (s *Session) client(ctx context.Context, api string) *http.Client {
+	timeout, ok := ctx.Value(callTimeoutKey{}).(time.Duration)
+	if !ok {
+		if timeout, ok = s.endpointTimeouts[api]; !ok {
+			return s.clt
+		}
+	}
+	clt := *s.clt
+	clt.Timeout = timeout
+	return &clt
+}
+
+// WithTransport replaces the session's connection handling, e.g. with an
+// instrumented or pre-configured *http.Transport. WithProxy and WithRootCAs
+// only work with an *http.Transport.
+func (s *Session) WithTransport(rt http.RoundTripper) *Session {
+	s.clt.Transport = rt
+	return s
+}
+
+// WithProxy sends API calls through the HTTP, HTTPS or SOCKS5 proxy at
+// proxyURL instead of the one named by HTTPS_PROXY and friends. An empty
+// proxyURL disables proxying. An invalid URL makes every call fail with
+// ErrInvalidProxy.
+func (s *Session) WithProxy(proxyURL string) *Session {
+	t := s.httpTransport()
+	if t == nil {
+		s.configErr = fmt.Errorf("%w: the session's transport is not an *http.Transport", ErrInvalidProxy)
+		return s
+	}
+	if proxyURL == "" {
+		t.Proxy = nil
+		return s
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+		s.configErr = fmt.Errorf("%w: %q", ErrInvalidProxy, proxyURL)
+		return s
+	}
+	t.Proxy = http.ProxyURL(u)
+	return s
+}
+
+// WithRootCAs verifies the server's certificate against pool instead of the
+// system roots, e.g. behind a TLS-intercepting middlebox.
+func (s *Session) WithRootCAs(pool *x509.CertPool) *Session {
+	t := s.httpTransport()
+	if t == nil {
+		s.configErr = errors.New("tinycert: root CAs need the session's transport to be an *http.Transport")
+		return s
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.RootCAs = pool
+	return s
+}
+
+// WithMaxIdleConnsPerHost keeps up to n idle connections to the API open for
+// reuse, for heavy users running more concurrent calls than the default of
+// 32. Calls beyond the idle connections open, and then close, connections of
+// their own.
+func (s *Session) WithMaxIdleConnsPerHost(n int) *Session {
+	t := s.httpTransport()
+	if t == nil {
+		s.configErr = errors.New("tinycert: idle connections need the session's transport to be an *http.Transport")
+		return s
+	}
+	t.MaxIdleConnsPerHost = n
+	if t.MaxIdleConns != 0 && t.MaxIdleConns < n {
+		t.MaxIdleConns = n
+	}
+	return s
+}
+
+// WithMaxConnsPerHost limits the connections to the API, idle or in use, to
+// n; calls beyond it wait for a connection. Zero means no limit.
+func (s *Session) WithMaxConnsPerHost(n int) *Session {
+	t := s.httpTransport()
+	if t == nil {
+		s.configErr = errors.New("tinycert: connection limits need the session's transport to be an *http.Transport")
+		return s
+	}
+	t.MaxConnsPerHost = n
+	return s
+}
+
+// WithHTTP2 enables or disables HTTP/2, which is on by default. Over HTTP/2
+// concurrent calls share a single connection; disable it for proxies or
+// middleboxes that mishandle it. Set it before the session's first call.
+func (s *Session) WithHTTP2(enabled bool) *Session {
+	t := s.httpTransport()
+	if t == nil {
+		s.configErr = errors.New("tinycert: HTTP/2 needs the session's transport to be an *http.Transport")
+		return s
+	}
+	t.ForceAttemptHTTP2 = enabled
+	if enabled {
+		t.TLSNextProto = nil
+	} else {
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return s
+}
+
+// httpTransport returns the session's *http.Transport, or nil if a different
+// RoundTripper is in use.
+func (s *Session) httpTransport() *http.Transport {
+	if s.clt.Transport == nil {
+		s.clt.Transport = newTransport()
+	}
+	t, _ := s.clt.Transport.(*http.Transport)
+	return t
+}
@@ -0,0 +1,307 @@
+package tinycert_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/srohatgi/tinycert"
+)
+
+func TestSession_WithProxy(t *testing.T) {
+	fs := newFakeServer(t)
+
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	sess := fs.session().WithProxy(proxy.URL)
+	if err := sess.Connect(); err != nil {
+		t.Fatal("unable to connect through proxy", err)
+	}
+	if proxied.Load() != 1 {
+		t.Errorf("proxy saw %d requests, want 1", proxied.Load())
+	}
+
+	for _, bad := range []string{"ftp://proxy", "proxy:3128", "://"} {
+		if err := fs.session().WithProxy(bad).Connect(); !errors.Is(err, tinycert.ErrInvalidProxy) {
+			t.Errorf("WithProxy(%q): expected ErrInvalidProxy, got %v", bad, err)
+		}
+	}
+}
+
+func TestSession_WithRootCAs(t *testing.T) {
+	fs := newFakeServer(t)
+	tlsServer := httptest.NewTLSServer(fs.Config.Handler)
+	defer tlsServer.Close()
+
+	if err := fs.session().WithBaseURL(tlsServer.URL + "/api").Connect(); err == nil {
+		t.Fatal("expected an untrusted certificate to be rejected")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+	if err := fs.session().WithBaseURL(tlsServer.URL + "/api").WithRootCAs(pool).Connect(); err != nil {
+		t.Fatal("unable to connect with custom root CAs", err)
+	}
+}
+
+func TestSession_WithTimeout(t *testing.T) {
+	fs := newFakeServer(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fs.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	sess := fs.session().WithBaseURL(slow.URL + "/api").WithTimeout(50 * time.Millisecond)
+	start := time.Now()
+	if err := sess.Connect(); err == nil {
+		t.Fatal("expected the call to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("call returned after %s, expected ~50ms", elapsed)
+	}
+
+	if err := sess.WithTimeout(time.Second).Connect(); err != nil {
+		t.Fatal("unable to connect with a longer timeout", err)
+	}
+}
+
+func TestSession_WithTransport(t *testing.T) {
+	fs := newFakeServer(t)
+
+	var calls atomic.Int32
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	sess := fs.session().WithTransport(rt)
+	if err := sess.Connect(); err != nil {
+		t.Fatal("unable to connect with custom transport", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("transport saw %d requests, want 1", calls.Load())
+	}
+
+	if err := fs.session().WithTransport(rt).WithProxy("http://proxy:3128").Connect(); !errors.Is(err, tinycert.ErrInvalidProxy) {
+		t.Error("expected WithProxy to reject a custom transport, got", err)
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSession_CallTimeouts(t *testing.T) {
+	fs := newFakeServer(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/ca/list") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fs.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	sess := fs.session().WithBaseURL(slow.URL + "/api").WithRetryPolicy(tinycert.RetryPolicy{}).WithTimeout(50 * time.Millisecond)
+	if err := sess.Connect(); err != nil {
+		t.Fatal("connect is fast and should not time out", err)
+	}
+	ca := tinycert.NewCA(sess)
+	if _, err := ca.List(); err == nil {
+		t.Fatal("expected ca/list to time out")
+	}
+
+	if _, err := ca.ListCtx(tinycert.WithCallTimeout(context.Background(), time.Second)); err != nil {
+		t.Error("call timeout did not override the session timeout:", err)
+	}
+
+	sess.WithEndpointTimeout("ca/list", time.Second)
+	if _, err := ca.List(); err != nil {
+		t.Error("endpoint timeout did not override the session timeout:", err)
+	}
+	start := time.Now()
+	if _, err := ca.ListCtx(tinycert.WithCallTimeout(context.Background(), 50*time.Millisecond)); err == nil {
+		t.Error("call timeout did not override the endpoint timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("call returned after %s, expected ~50ms", elapsed)
+	}
+}
+
+// http2Server serves fs over TLS with HTTP/2 enabled, after latency,
+// recording the protocol of each request, and returns a session trusting it.
+func http2Server(t testing.TB, fs *fakeServer, latency time.Duration) (sess *tinycert.Session, protos func() []string) {
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Proto)
+		mu.Unlock()
+		time.Sleep(latency)
+		fs.Config.Handler.ServeHTTP(w, r)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	sess = fs.session().WithBaseURL(srv.URL + "/api").WithRootCAs(pool)
+	return sess, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestSession_WithHTTP2(t *testing.T) {
+	fs := newFakeServer(t)
+
+	sess, protos := http2Server(t, fs, 0)
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if got := protos(); len(got) != 1 || got[0] != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2 by default, got %v", got)
+	}
+
+	sess, protos = http2Server(t, fs, 0)
+	if err := sess.WithHTTP2(false).Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if got := protos(); len(got) != 1 || got[0] != "HTTP/1.1" {
+		t.Errorf("expected HTTP/1.1 with HTTP/2 disabled, got %v", got)
+	}
+
+	rt := roundTripperFunc(http.DefaultTransport.RoundTrip)
+	if err := fs.session().WithTransport(rt).WithHTTP2(false).Connect(); err == nil {
+		t.Error("expected WithHTTP2 to reject a custom transport")
+	}
+	if err := fs.session().WithTransport(rt).WithMaxIdleConnsPerHost(64).Connect(); err == nil {
+		t.Error("expected WithMaxIdleConnsPerHost to reject a custom transport")
+	}
+}
+
+func TestSession_WithMaxConnsPerHost(t *testing.T) {
+	fs := newFakeServer(t)
+
+	var active, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		fs.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	sess := fs.session().WithBaseURL(srv.URL + "/api").WithMaxConnsPerHost(2)
+	if err := sess.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ca := tinycert.NewCA(sess)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ca.List(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d concurrent requests, want at most 2", got)
+	}
+}
+
+// benchLatency stands in for the round trip to the API, without which the
+// benchmarks measure little but the fake server's lock.
+const benchLatency = time.Millisecond
+
+// BenchmarkTransport compares the throughput of ca/list calls made one at a
+// time, concurrently over HTTP/1.1 with Go's default of 2 idle connections
+// and with the session's default, and concurrently over HTTP/2:
+//
+//	go test -run '^$' -bench Transport
+func BenchmarkTransport(b *testing.B) {
+	plain := func(b *testing.B) *tinycert.Session {
+		fs := newFakeServer(b)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(benchLatency)
+			fs.Config.Handler.ServeHTTP(w, r)
+		}))
+		b.Cleanup(srv.Close)
+		return fs.session().WithBaseURL(srv.URL + "/api")
+	}
+	list := func(b *testing.B, sess *tinycert.Session) func() {
+		if err := sess.Connect(); err != nil {
+			b.Fatal(err)
+		}
+		ca := tinycert.NewCA(sess)
+		return func() {
+			if _, err := ca.List(); err != nil {
+				b.Error(err)
+			}
+		}
+	}
+	parallel := func(b *testing.B, call func()) {
+		b.SetParallelism(32)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				call()
+			}
+		})
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		call := list(b, plain(b))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			call()
+		}
+	})
+	b.Run("pooled-idle-2", func(b *testing.B) {
+		parallel(b, list(b, plain(b).WithMaxIdleConnsPerHost(2)))
+	})
+	b.Run("pooled", func(b *testing.B) {
+		parallel(b, list(b, plain(b)))
+	})
+	b.Run("pooled-tls", func(b *testing.B) {
+		sess, _ := http2Server(b, newFakeServer(b), benchLatency)
+		parallel(b, list(b, sess.WithHTTP2(false)))
+	})
+	b.Run("http2", func(b *testing.B) {
+		sess, _ := http2Server(b, newFakeServer(b), benchLatency)
+		parallel(b, list(b, sess))
+	})
+}
@@ -0,0 +1,114 @@
+// Package trust installs TinyCert CA certificates into the local operating
+// system's trust store, so that browsers and command line tools on a
+// developer machine accept certificates the CA issues.
+//
+// Each platform is driven through its own tooling: the security command on
+// macOS (a front end to the Security framework's trust settings), the
+// distribution's anchor directory and update-ca-certificates or update-ca-trust
+// on Linux, and certutil on Windows. All of them change the system wide store
+// and therefore need administrator or root privileges.
+package trust
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrInvalidPEM  = errors.New("trust: invalid certificate pem")
+	ErrUnsupported = errors.New("trust: unsupported platform")
+)
+
+// InstallCA adds the first certificate of the PEM data to the system trust
+// store as a trusted root. Installing the same certificate twice is harmless.
+func InstallCA(pemData []byte) error {
+	cert, err := parse(pemData)
+	if err != nil {
+		return err
+	}
+	return install(cert)
+}
+
+// Remove takes the first certificate of the PEM data back out of the system
+// trust store.
+func Remove(pemData []byte) error {
+	cert, err := parse(pemData)
+	if err != nil {
+		return err
+	}
+	return remove(cert)
+}
+
+func parse(pemData []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		if block, pemData = pem.Decode(pemData); block == nil {
+			return nil, ErrInvalidPEM
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPEM, err)
+		}
+		return cert, nil
+	}
+}
+
+// fileName names the installed copy of cert after its fingerprint, so Remove
+// can find it again from the same PEM.
+func fileName(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return "tinycert-" + hex.EncodeToString(sum[:8])
+}
+
+func sha1Fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func encode(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// withTempFile writes cert to a temporary PEM file for tools that only take a
+// path, and removes it once fn returns.
+func withTempFile(cert *x509.Certificate, fn func(path string) error) error {
+	f, err := os.CreateTemp("", fileName(cert)+"-*.pem")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(encode(cert))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return fn(f.Name())
+}
+
+// run executes a platform tool; it is a variable so tests can stand in for
+// the real commands.
+var run = func(name string, args ...string) error {
+	var out bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("trust: %s: %v: %s", name, err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
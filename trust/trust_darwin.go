@@ -0,0 +1,21 @@
+package trust
+
+import "crypto/x509"
+
+const systemKeychain = "/Library/Keychains/System.keychain"
+
+func install(cert *x509.Certificate) error {
+	return withTempFile(cert, func(path string) error {
+		return run("security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", systemKeychain, path)
+	})
+}
+
+func remove(cert *x509.Certificate) error {
+	err := withTempFile(cert, func(path string) error {
+		return run("security", "remove-trusted-cert", "-d", path)
+	})
+	if err != nil {
+		return err
+	}
+	return run("security", "delete-certificate", "-Z", sha1Fingerprint(cert), systemKeychain)
+}
@@ -0,0 +1,64 @@
+package trust
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// anchorStore is a distribution's directory of extra trust anchors and the
+// command that rebuilds the system bundle from it.
+type anchorStore struct {
+	dir     string
+	ext     string
+	command []string
+}
+
+var anchorStores = []anchorStore{
+	// Debian, Ubuntu, Alpine.
+	{dir: "/usr/local/share/ca-certificates", ext: ".crt", command: []string{"update-ca-certificates"}},
+	// Fedora, RHEL, CentOS.
+	{dir: "/etc/pki/ca-trust/source/anchors", ext: ".pem", command: []string{"update-ca-trust", "extract"}},
+	// Arch.
+	{dir: "/etc/ca-certificates/trust-source/anchors", ext: ".crt", command: []string{"trust", "extract-compat"}},
+	// openSUSE.
+	{dir: "/usr/share/pki/trust/anchors", ext: ".pem", command: []string{"update-ca-certificates"}},
+}
+
+func findStore() (anchorStore, error) {
+	for _, store := range anchorStores {
+		if info, err := os.Stat(store.dir); err == nil && info.IsDir() {
+			return store, nil
+		}
+	}
+	return anchorStore{}, fmt.Errorf("%w: no known CA anchor directory found", ErrUnsupported)
+}
+
+func install(cert *x509.Certificate) error {
+	store, err := findStore()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(store.dir, fileName(cert)+store.ext)
+	if err = os.WriteFile(path, encode(cert), 0644); err != nil {
+		return err
+	}
+	return run(store.command[0], store.command[1:]...)
+}
+
+func remove(cert *x509.Certificate) error {
+	store, err := findStore()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(store.dir, fileName(cert)+store.ext)
+	if err = os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return run(store.command[0], store.command[1:]...)
+}
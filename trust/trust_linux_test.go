@@ -0,0 +1,85 @@
+package trust
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func selfSigned(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "acme root"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestInstallRemove_Linux(t *testing.T) {
+	dir := t.TempDir()
+	var commands [][]string
+	oldStores, oldRun := anchorStores, run
+	t.Cleanup(func() { anchorStores, run = oldStores, oldRun })
+	anchorStores = []anchorStore{
+		{dir: filepath.Join(dir, "missing"), ext: ".pem", command: []string{"never"}},
+		{dir: dir, ext: ".crt", command: []string{"update-ca-certificates"}},
+	}
+	run = func(name string, args ...string) error {
+		commands = append(commands, append([]string{name}, args...))
+		return nil
+	}
+
+	caPEM := selfSigned(t)
+	if err := InstallCA(caPEM); err != nil {
+		t.Fatal("InstallCA()", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "tinycert-*.crt"))
+	if len(files) != 1 {
+		t.Fatalf("installed files = %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); string(data) != string(caPEM) {
+		t.Error("installed file does not hold the CA")
+	}
+
+	if err := Remove(caPEM); err != nil {
+		t.Fatal("Remove()", err)
+	}
+	if _, err := os.Stat(files[0]); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Remove() left the anchor in place")
+	}
+	// A second Remove finds nothing to do and does not rebuild the bundle.
+	if err := Remove(caPEM); err != nil {
+		t.Error("Remove() of a missing anchor", err)
+	}
+
+	want := [][]string{{"update-ca-certificates"}, {"update-ca-certificates"}}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("commands = %v, want %v", commands, want)
+	}
+
+	if err := InstallCA([]byte("not pem")); !errors.Is(err, ErrInvalidPEM) {
+		t.Errorf("InstallCA(garbage) = %v, want ErrInvalidPEM", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package trust
+
+import "crypto/x509"
+
+func install(cert *x509.Certificate) error {
+	return ErrUnsupported
+}
+
+func remove(cert *x509.Certificate) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,13 @@
+package trust
+
+import "crypto/x509"
+
+func install(cert *x509.Certificate) error {
+	return withTempFile(cert, func(path string) error {
+		return run("certutil", "-addstore", "-f", "Root", path)
+	})
+}
+
+func remove(cert *x509.Certificate) error {
+	return run("certutil", "-delstore", "Root", sha1Fingerprint(cert))
+}